@@ -0,0 +1,496 @@
+package binlog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+// The default database of the event must not be used, e.g. CREATE DATABASE and DROP DATABASE.
+const logEventSuppressUseFlag = 0x8
+
+// Flags of rows events.
+const (
+	rowsEventNoForeignKeyChecks  = 0x2
+	rowsEventRelaxedUniqueChecks = 0x4
+)
+
+// Options in the flags2 status variable of query events.
+const (
+	optionAutoIsNull          = 1 << 14
+	optionNoForeignKeyChecks  = 1 << 26
+	optionRelaxedUniqueChecks = 1 << 27
+)
+
+// Status variable codes of query events.
+const (
+	statusFlags2Code = iota
+	statusSqlModeCode
+	statusCatalogCode
+	statusAutoIncrementCode
+	statusCharsetCode
+	statusTimeZoneCode
+	statusCatalogNzCode
+	statusLcTimeNamesCode
+	statusCharsetDatabaseCode
+	statusTableMapForUpdateCode
+	statusMasterDataWrittenCode
+	statusInvokerCode
+	statusUpdatedDbNamesCode
+	statusMicrosecondsCode
+	statusCommitTsCode
+	statusCommitTs2Code
+	statusExplicitDefaultsForTimestampCode
+	statusDdlLoggedWithXidCode
+	statusDefaultCollationForUtf8mb4Code
+	statusSqlRequirePrimaryKeyCode
+	statusDefaultTableEncryptionCode
+)
+
+// The updated databases are not logged if there are more than 16 of them.
+const overMaxDbsInEventMts = 254
+
+// Row values are decoded in UTC and restored with the +00:00 time zone.
+const rowsSqlMode, rowsTimeZone = "'NO_AUTO_VALUE_ON_ZERO'", "'+00:00'"
+
+type queryExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+type sessionVariable struct {
+	name  string
+	value string
+}
+
+// Turn binlog events into SQL statements and execute them on a single connection, the same as mysqlbinlog piped to mysql.
+// In dry-run mode the statements are written to the output instead.
+type eventApplier struct {
+	db            queryExecer
+	output        io.Writer
+	database      string
+	session       map[string]string
+	tables        map[string]*tableInfo
+	inTransaction bool
+	excludeGTIDs  *mysql.MysqlGTIDSet
+	skipping      bool
+	filter        *eventFilter
+}
+
+func newEventApplier(db queryExecer, output io.Writer) *eventApplier {
+	return &eventApplier{
+		db:      db,
+		output:  output,
+		session: make(map[string]string),
+		tables:  make(map[string]*tableInfo),
+	}
+}
+
+func (a *eventApplier) apply(e *replication.BinlogEvent) error {
+	// The events of an excluded transaction are skipped until the next transaction.
+	if _, ok := e.Event.(*replication.GTIDEvent); !ok && a.skipping {
+		return nil
+	}
+
+	switch event := e.Event.(type) {
+	case *replication.GTIDEvent:
+		gtid, err := eventGTID(e)
+		if err != nil {
+			return err
+		}
+
+		a.skipping = gtid != nil && a.excludeGTIDs != nil && a.excludeGTIDs.Contain(gtid)
+	case *replication.QueryEvent:
+		return a.applyQuery(e.Header, event)
+	case *replication.XIDEvent:
+		a.inTransaction = false
+		return a.exec("COMMIT")
+	case *replication.RowsEvent:
+		return a.applyRows(e.Header.EventType, event)
+	case *replication.IntVarEvent:
+		switch event.Type {
+		case replication.LAST_INSERT_ID:
+			return a.exec(fmt.Sprintf("SET LAST_INSERT_ID=%d", event.Value))
+		case replication.INSERT_ID:
+			return a.exec(fmt.Sprintf("SET INSERT_ID=%d", event.Value))
+		}
+	case *replication.TransactionPayloadEvent:
+		for _, inner := range event.Events {
+			if err := a.apply(inner); err != nil {
+				return err
+			}
+		}
+	case *replication.BeginLoadQueryEvent, *replication.ExecuteLoadQueryEvent:
+		return fmt.Errorf("LOAD DATA statements are not supported")
+	case *replication.GenericEvent:
+		switch e.Header.EventType {
+		case replication.RAND_EVENT:
+			if len(event.Data) < 16 {
+				return fmt.Errorf("invalid rand event")
+			}
+
+			return a.exec(fmt.Sprintf("SET @@RAND_SEED1=%d, @@RAND_SEED2=%d", binary.LittleEndian.Uint64(event.Data), binary.LittleEndian.Uint64(event.Data[8:])))
+		case replication.USER_VAR_EVENT:
+			return fmt.Errorf("user variables of statement-based binlogs are not supported")
+		}
+	}
+
+	// The other events, e.g. format description, table map (decoded by the parser) and GTID events, have nothing to apply.
+	return nil
+}
+
+// Close the connection state, the incomplete transaction at the stop position is rolled back.
+func (a *eventApplier) close() error {
+	if !a.inTransaction {
+		return nil
+	}
+
+	slog.Warn("the last transaction is incomplete at the stop position, rolling it back")
+	a.inTransaction = false
+
+	return a.exec("ROLLBACK")
+}
+
+func (a *eventApplier) applyQuery(header *replication.EventHeader, event *replication.QueryEvent) error {
+	query := string(event.Query)
+
+	transactionKeyword := true
+	switch strings.ToUpper(strings.TrimSpace(query)) {
+	case "BEGIN", "XA START":
+		a.inTransaction = true
+	case "COMMIT", "ROLLBACK":
+		a.inTransaction = false
+	default:
+		transactionKeyword = false
+
+		// The table structures may be changed.
+		clear(a.tables)
+	}
+
+	// The transactions are kept, the same as mysqlbinlog --database, but the filtered database is not used
+	// as it may not exist in the target server.
+	matched := a.filter.matchDatabase(string(event.Schema))
+	if !transactionKeyword && !matched {
+		return nil
+	}
+
+	// The tables of a statement are unknown, so the statements, e.g. ALTER TABLE and INSERT ... SELECT, are skipped
+	// with the table filters, otherwise they may change the excluded tables.
+	if !transactionKeyword && a.filter.hasTableFilter() {
+		slog.Warn("the statement is skipped by the table filters", slog.String("statement", sqlutil.Truncate(query)))
+		return nil
+	}
+
+	schema := a.filter.rewrite(string(event.Schema))
+
+	if header.Flags&logEventSuppressUseFlag != 0 {
+		a.database = ""
+	} else if matched && len(schema) > 0 && schema != a.database {
+		if err := a.exec("USE " + sqlutil.QuoteIdentifier(schema)); err != nil {
+			return err
+		}
+
+		a.database = schema
+	}
+
+	variables, err := queryVariables(header, event.StatusVars)
+	if err != nil {
+		return err
+	}
+
+	if err := a.setSession(variables); err != nil {
+		return err
+	}
+
+	return a.exec(query)
+}
+
+func (a *eventApplier) applyRows(eventType replication.EventType, event *replication.RowsEvent) error {
+	if event.Table == nil {
+		return fmt.Errorf("rows event of table id %d has no table map event", event.TableID)
+	}
+
+	if !a.filter.matchTable(string(event.Table.Schema), string(event.Table.Table)) {
+		return nil
+	}
+
+	schema := a.filter.rewrite(string(event.Table.Schema))
+
+	foreignKeyChecks, uniqueChecks := "1", "1"
+	if event.Flags&rowsEventNoForeignKeyChecks != 0 {
+		foreignKeyChecks = "0"
+	}
+
+	if event.Flags&rowsEventRelaxedUniqueChecks != 0 {
+		uniqueChecks = "0"
+	}
+
+	if err := a.setSession([]sessionVariable{
+		{"foreign_key_checks", foreignKeyChecks},
+		{"unique_checks", uniqueChecks},
+		{"sql_mode", rowsSqlMode},
+		{"time_zone", rowsTimeZone},
+	}); err != nil {
+		return err
+	}
+
+	table, err := a.getTable(schema, event.Table)
+	if err != nil {
+		return err
+	}
+
+	name := sqlutil.QuoteIdentifier(schema) + "." + sqlutil.QuoteIdentifier(string(event.Table.Table))
+
+	statements, err := rowsStatements(event, eventType, table, name)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		if err := a.exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get the table columns from the table map event if binlog_row_metadata=FULL, otherwise from the table of the target database.
+func (a *eventApplier) getTable(schema string, event *replication.TableMapEvent) (*tableInfo, error) {
+	if len(event.ColumnName) == int(event.ColumnCount) {
+		table := &tableInfo{
+			columns:  event.ColumnNameString(),
+			unsigned: event.UnsignedMap(),
+		}
+
+		for _, column := range event.PrimaryKey {
+			table.primaryKey = append(table.primaryKey, int(column))
+		}
+
+		return table, nil
+	}
+
+	key := schema + "." + string(event.Table)
+	if table, ok := a.tables[key]; ok {
+		return table, nil
+	}
+
+	rows, err := a.db.QueryContext(
+		context.Background(),
+		"SELECT COLUMN_NAME, COLUMN_TYPE, COLUMN_KEY FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION",
+		schema, string(event.Table),
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of table %s, error: %v", key, err)
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", slog.Any("error", err))
+		}
+	}()
+
+	table := &tableInfo{unsigned: make(map[int]bool)}
+	for i := 0; rows.Next(); i++ {
+		var name, columnType, columnKey string
+		if err := rows.Scan(&name, &columnType, &columnKey); err != nil {
+			return nil, fmt.Errorf("failed to scan columns of table %s, error: %v", key, err)
+		}
+
+		table.columns = append(table.columns, name)
+		table.unsigned[i] = strings.Contains(strings.ToLower(columnType), "unsigned")
+
+		if columnKey == "PRI" {
+			table.primaryKey = append(table.primaryKey, i)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get columns of table %s, error: %v", key, err)
+	}
+
+	if len(table.columns) == 0 {
+		return nil, fmt.Errorf("table %s is not found, the columns of the rows events are unknown without binlog_row_metadata=FULL", key)
+	}
+
+	a.tables[key] = table
+
+	return table, nil
+}
+
+// Set the session variables that are changed.
+func (a *eventApplier) setSession(variables []sessionVariable) error {
+	var assignments []string
+	for _, variable := range variables {
+		if value, ok := a.session[variable.name]; ok && value == variable.value {
+			continue
+		}
+
+		a.session[variable.name] = variable.value
+		assignments = append(assignments, fmt.Sprintf("@@session.%s=%s", variable.name, variable.value))
+	}
+
+	if len(assignments) == 0 {
+		return nil
+	}
+
+	return a.exec("SET " + strings.Join(assignments, ", "))
+}
+
+func (a *eventApplier) exec(query string) error {
+	if a.output != nil {
+		_, err := fmt.Fprintf(a.output, "%s\n/*!*/;\n", query)
+		return err
+	}
+
+	if _, err := a.db.ExecContext(context.Background(), query); err != nil {
+		return fmt.Errorf("failed to execute statement: %s, error: %v", sqlutil.Truncate(query), err)
+	}
+
+	return nil
+}
+
+// Get the session variables of the query event from its status variables, the same as the SET statements of mysqlbinlog.
+func queryVariables(header *replication.EventHeader, status []byte) ([]sessionVariable, error) {
+	var variables []sessionVariable
+	var microseconds uint32
+
+	invalid := func() ([]sessionVariable, error) {
+		return nil, fmt.Errorf("invalid status variables of query event at position %d", header.LogPos)
+	}
+
+	for pos := 0; pos < len(status); {
+		code := status[pos]
+		pos++
+
+		// The length of the value of the status variable.
+		var size int
+		switch code {
+		case statusFlags2Code, statusMasterDataWrittenCode:
+			size = 4
+		case statusSqlModeCode, statusTableMapForUpdateCode, statusDdlLoggedWithXidCode:
+			size = 8
+		case statusCatalogCode:
+			if pos >= len(status) {
+				return invalid()
+			}
+
+			size = int(status[pos]) + 2
+		case statusAutoIncrementCode:
+			size = 4
+		case statusCharsetCode:
+			size = 6
+		case statusTimeZoneCode, statusCatalogNzCode:
+			if pos >= len(status) {
+				return invalid()
+			}
+
+			size = int(status[pos]) + 1
+		case statusLcTimeNamesCode, statusCharsetDatabaseCode, statusDefaultCollationForUtf8mb4Code:
+			size = 2
+		case statusInvokerCode:
+			if pos >= len(status) {
+				return invalid()
+			}
+
+			userSize := int(status[pos]) + 1
+			if pos+userSize >= len(status) {
+				return invalid()
+			}
+
+			size = userSize + int(status[pos+userSize]) + 1
+		case statusUpdatedDbNamesCode:
+			if pos >= len(status) {
+				return invalid()
+			}
+
+			size = 1
+			if count := int(status[pos]); count != overMaxDbsInEventMts {
+				for range count {
+					end := pos + size
+					for end < len(status) && status[end] != 0 {
+						end++
+					}
+
+					size = end - pos + 1
+				}
+			}
+		case statusMicrosecondsCode:
+			size = 3
+		case statusExplicitDefaultsForTimestampCode, statusSqlRequirePrimaryKeyCode, statusDefaultTableEncryptionCode:
+			size = 1
+		default:
+			// The other status variables are not required to apply the query, and their lengths are unknown.
+			pos = len(status)
+			continue
+		}
+
+		if pos+size > len(status) {
+			return invalid()
+		}
+
+		value := status[pos : pos+size]
+		pos += size
+
+		switch code {
+		case statusFlags2Code:
+			flags := binary.LittleEndian.Uint32(value)
+			variables = append(variables,
+				sessionVariable{"foreign_key_checks", boolValue(flags&optionNoForeignKeyChecks == 0)},
+				sessionVariable{"sql_auto_is_null", boolValue(flags&optionAutoIsNull != 0)},
+				sessionVariable{"unique_checks", boolValue(flags&optionRelaxedUniqueChecks == 0)},
+			)
+		case statusSqlModeCode:
+			variables = append(variables, sessionVariable{"sql_mode", strconv.FormatUint(binary.LittleEndian.Uint64(value), 10)})
+		case statusAutoIncrementCode:
+			variables = append(variables,
+				sessionVariable{"auto_increment_increment", strconv.Itoa(int(binary.LittleEndian.Uint16(value)))},
+				sessionVariable{"auto_increment_offset", strconv.Itoa(int(binary.LittleEndian.Uint16(value[2:])))},
+			)
+		case statusCharsetCode:
+			variables = append(variables,
+				sessionVariable{"character_set_client", strconv.Itoa(int(binary.LittleEndian.Uint16(value)))},
+				sessionVariable{"collation_connection", strconv.Itoa(int(binary.LittleEndian.Uint16(value[2:])))},
+				sessionVariable{"collation_server", strconv.Itoa(int(binary.LittleEndian.Uint16(value[4:])))},
+			)
+		case statusTimeZoneCode:
+			variables = append(variables, sessionVariable{"time_zone", "'" + strings.ReplaceAll(string(value[1:]), "'", "''") + "'"})
+		case statusLcTimeNamesCode:
+			variables = append(variables, sessionVariable{"lc_time_names", strconv.Itoa(int(binary.LittleEndian.Uint16(value)))})
+		case statusCharsetDatabaseCode:
+			if id := binary.LittleEndian.Uint16(value); id != 0 {
+				variables = append(variables, sessionVariable{"collation_database", strconv.Itoa(int(id))})
+			}
+		case statusDefaultCollationForUtf8mb4Code:
+			variables = append(variables, sessionVariable{"default_collation_for_utf8mb4", strconv.Itoa(int(binary.LittleEndian.Uint16(value)))})
+		case statusMicrosecondsCode:
+			microseconds = uint32(value[0]) | uint32(value[1])<<8 | uint32(value[2])<<16
+		}
+	}
+
+	timestamp := strconv.FormatUint(uint64(header.Timestamp), 10)
+	if microseconds > 0 {
+		timestamp = fmt.Sprintf("%d.%06d", header.Timestamp, microseconds)
+	}
+
+	return append(variables, sessionVariable{"timestamp", timestamp}), nil
+}
+
+func boolValue(value bool) string {
+	if value {
+		return "1"
+	}
+
+	return "0"
+}
@@ -0,0 +1,146 @@
+package binlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyEventsDryRun(t *testing.T) {
+	assert := assert.New(t)
+
+	currentDir, err := os.Getwd()
+	assert.NoError(err)
+
+	binlogsDir := filepath.Join(currentDir, "..", "testutils", "mysqlrestore", "binlogs")
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(err)
+	defer db.Close()
+
+	// The test binlogs are written with binlog_row_metadata=MINIMAL, so the columns are read from the database.
+	rows := sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE", "COLUMN_KEY"})
+	for i := range 51 {
+		key := ""
+		if i < 2 {
+			key = "PRI"
+		}
+
+		rows.AddRow(fmt.Sprintf("c%d", i+1), "varchar(255)", key)
+	}
+
+	mock.ExpectQuery("SELECT COLUMN_NAME, COLUMN_TYPE, COLUMN_KEY FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION").
+		WithArgs("mysql", "user").
+		WillReturnRows(rows)
+
+	var output bytes.Buffer
+	restorer := NewBinlogRestorer(binlogsDir, "mysql-bin.000003", 0, WithNative(true), WithDryRun(true))
+
+	stopPos := 1857
+	plan := newBinlogRestorePlan(0)
+	plan.binlogs = []string{filepath.Join(binlogsDir, "mysql-bin.000003")}
+	plan.stopPosition = &stopPos
+
+	err = restorer.applyEvents(plan, newEventApplier(db, &output))
+	assert.NoError(err)
+	assert.NoError(mock.ExpectationsWereMet())
+
+	expected := "SET @@session.foreign_key_checks=1, @@session.sql_auto_is_null=0, @@session.unique_checks=1, @@session.sql_mode=1168113696, @@session.character_set_client=8, @@session.collation_connection=8, @@session.collation_server=224, @@session.default_collation_for_utf8mb4=255, @@session.timestamp=1748912333\n/*!*/;\n" +
+		"BEGIN\n/*!*/;\n" +
+		"SET @@session.sql_mode='NO_AUTO_VALUE_ON_ZERO', @@session.time_zone='+00:00'\n/*!*/;\n" +
+		"DELETE FROM `mysql`.`user` WHERE `c1`=_binary'%' AND `c2`=_binary'root' LIMIT 1\n/*!*/;\n" +
+		"COMMIT\n/*!*/;\n" +
+		"SET @@session.sql_mode=1168113696, @@session.time_zone='UTC'\n/*!*/;\n" +
+		"FLUSH PRIVILEGES\n/*!*/;\n"
+
+	assert.True(strings.HasPrefix(output.String(), expected), output.String())
+
+	// The default database of CREATE DATABASE is not used.
+	assert.True(strings.HasSuffix(output.String(), "SET @@session.timestamp=1748912438\n/*!*/;\nCREATE DATABASE IF NOT EXISTS `trendshift`\n/*!*/;\n"), output.String())
+	assert.NotContains(output.String(), "USE `trendshift`")
+}
+
+func TestApplyNativelyDryRunWithoutDatabase(t *testing.T) {
+	assert := assert.New(t)
+
+	currentDir, err := os.Getwd()
+	assert.NoError(err)
+
+	binlogsDir := filepath.Join(currentDir, "..", "testutils", "mysqlrestore", "binlogs")
+
+	// Nothing listens on the port, the dry run must not connect to the database for the query events.
+	restorer := NewBinlogRestorer(binlogsDir, "mysql-bin.000003", 0, WithNative(true), WithDryRun(true), WithDatabaseDSN("root@tcp(127.0.0.1:1)/"))
+
+	plan := newBinlogRestorePlan(1857)
+	plan.binlogs = []string{filepath.Join(binlogsDir, "mysql-bin.000003")}
+
+	var output bytes.Buffer
+	assert.NoError(restorer.applyNatively(plan, &output))
+	assert.True(strings.HasSuffix(output.String(), "FLUSH PRIVILEGES\n/*!*/;\nDELIMITER ;\n"), output.String())
+}
+
+func TestApplyEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(err)
+	defer db.Close()
+
+	applier := newEventApplier(db, nil)
+	header := &replication.EventHeader{Timestamp: 1748912333}
+
+	tableMap := &replication.TableMapEvent{
+		Schema:      []byte("shop"),
+		Table:       []byte("users"),
+		ColumnCount: 2,
+		ColumnType:  []byte{mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_VARCHAR},
+		ColumnName:  [][]byte{[]byte("id"), []byte("name")},
+		PrimaryKey:  []uint64{0},
+	}
+
+	events := []*replication.BinlogEvent{
+		{Header: header, Event: &replication.QueryEvent{Schema: []byte("shop"), Query: []byte("BEGIN")}},
+		{Header: &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2}, Event: &replication.RowsEvent{
+			Table: tableMap, ColumnCount: 2, Flags: rowsEventNoForeignKeyChecks, Rows: [][]any{{int32(1), "john"}},
+		}},
+		{Header: &replication.EventHeader{EventType: replication.DELETE_ROWS_EVENTv2}, Event: &replication.RowsEvent{
+			Table: tableMap, ColumnCount: 2, Flags: rowsEventNoForeignKeyChecks, Rows: [][]any{{int32(2), "jane"}},
+		}},
+		{Header: header, Event: &replication.XIDEvent{}},
+		{Header: header, Event: &replication.QueryEvent{Schema: []byte("shop"), Query: []byte("BEGIN")}},
+	}
+
+	mock.ExpectExec("USE `shop`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET @@session.timestamp=1748912333").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("BEGIN").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET @@session.foreign_key_checks=0, @@session.unique_checks=1, @@session.sql_mode='NO_AUTO_VALUE_ON_ZERO', @@session.time_zone='+00:00'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `shop`.`users` (`id`,`name`) VALUES (1,_binary'john')").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM `shop`.`users` WHERE `id`=2 LIMIT 1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("COMMIT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("BEGIN").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	for _, event := range events {
+		assert.NoError(applier.apply(event))
+	}
+
+	// The last transaction is incomplete.
+	assert.NoError(applier.close())
+	assert.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectExec("SET INSERT_ID=10").WillReturnError(errors.New("connection lost"))
+	err = applier.apply(&replication.BinlogEvent{Header: header, Event: &replication.IntVarEvent{Type: replication.INSERT_ID, Value: 10}})
+	assert.EqualError(err, "failed to execute statement: SET INSERT_ID=10, error: connection lost")
+
+	err = applier.apply(&replication.BinlogEvent{Header: header, Event: &replication.BeginLoadQueryEvent{}})
+	assert.EqualError(err, "LOAD DATA statements are not supported")
+}
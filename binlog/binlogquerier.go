@@ -118,13 +118,7 @@ func (b *binlogQuerier) queryBinlogStatus() (string, uint64, error) {
 		return "", 0, fmt.Errorf("fail to query MySQL version, error: %v", err)
 	}
 
-	var showBinlogStatusQuery string
-	// use ShowMasterStatusQuery for all MySQL < 8.2
-	if version.major < 8 || (version.major == 8 && version.minor < 2) {
-		showBinlogStatusQuery = ShowMasterStatusQuery
-	} else {
-		showBinlogStatusQuery = ShowBinlogStatusQuery
-	}
+	showBinlogStatusQuery := version.binlogStatusQuery()
 
 	rows, err := b.db.Query(showBinlogStatusQuery)
 	if err != nil {
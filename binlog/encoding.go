@@ -0,0 +1,441 @@
+package binlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+
+	EncryptedSuffix = ".enc" // The suffix of the encrypted binlog files
+
+	encryptionKeySize = 32        // AES-256
+	encryptChunkSize  = 64 * 1024 // The plaintext size of an encrypted chunk
+)
+
+var (
+	Compressions = []string{CompressionGzip, CompressionZstd}
+
+	compressionSuffixes = map[string]string{CompressionGzip: ".gz", CompressionZstd: ".zst"}
+
+	// The header of the encrypted files, followed by the random nonce prefix of the file.
+	encryptMagic = []byte("ONEDUMP1")
+)
+
+// Decode a base64 encoded 256-bit key, e.g. generated by: openssl rand -base64 32
+func ParseEncryptionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key must be base64 encoded, error: %v", err)
+	}
+
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", encryptionKeySize, len(key))
+	}
+
+	return key, nil
+}
+
+// How the binlog files are transformed before they are saved to the destinations.
+type fileEncoding struct {
+	compression string
+	key         []byte // encrypt the files if it is not empty
+}
+
+func (e fileEncoding) isPlain() bool {
+	return e.compression == "" && len(e.key) == 0
+}
+
+// Get the suffix of the remote file names, e.g. mysql-bin.000001.zst.enc
+func (e fileEncoding) suffix() string {
+	suffix := compressionSuffixes[e.compression]
+
+	if len(e.key) > 0 {
+		suffix += EncryptedSuffix
+	}
+
+	return suffix
+}
+
+// Compress and encrypt the content written to the writer, closing it does not close the destination.
+func (e fileEncoding) encode(dst io.Writer) (io.WriteCloser, error) {
+	var closers []io.Closer
+
+	w := dst
+	if len(e.key) > 0 {
+		encryptor, err := newEncryptWriter(w, e.key)
+		if err != nil {
+			return nil, err
+		}
+
+		closers = append(closers, encryptor)
+		w = encryptor
+	}
+
+	switch e.compression {
+	case CompressionGzip:
+		compressor := gzip.NewWriter(w)
+		closers = append(closers, compressor)
+		w = compressor
+	case CompressionZstd:
+		compressor, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create zstd writer, error: %v", err)
+		}
+
+		closers = append(closers, compressor)
+		w = compressor
+	case "":
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s, support %v", e.compression, Compressions)
+	}
+
+	return &encodeWriter{Writer: w, closers: closers}, nil
+}
+
+// Get a reader of the encoded content.
+func (e fileEncoding) reader(src io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		w, err := e.encode(pw)
+		if err == nil {
+			_, err = io.Copy(w, src)
+			err = errors.Join(err, w.Close())
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
+
+type encodeWriter struct {
+	io.Writer
+	closers []io.Closer
+}
+
+// Close the compressor first, so its remaining content is encrypted.
+func (w *encodeWriter) Close() error {
+	var err error
+	for i := len(w.closers) - 1; i >= 0; i-- {
+		err = errors.Join(err, w.closers[i].Close())
+	}
+
+	return err
+}
+
+// Encrypt the content in chunks with AES-256-GCM, so large files are encrypted without being loaded into memory.
+// Each chunk is prefixed with a flag byte that marks the last chunk and the ciphertext length, the flag is authenticated,
+// so a truncated file fails to be decrypted.
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create cipher, error: %v", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create GCM cipher, error: %v", err)
+	}
+
+	return aead, nil
+}
+
+func newEncryptWriter(w io.Writer, key []byte) (*encryptWriter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The nonce of a chunk is the random prefix of the file and the chunk counter.
+	prefix := make([]byte, aead.NonceSize()-4)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("fail to generate nonce, error: %v", err)
+	}
+
+	if _, err := w.Write(append(bytes.Clone(encryptMagic), prefix...)); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, encryptChunkSize)}, nil
+}
+
+func chunkNonce(prefix []byte, counter uint32) []byte {
+	return binary.BigEndian.AppendUint32(bytes.Clone(prefix), counter)
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), encryptChunkSize-len(e.buf))
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+		written += n
+
+		if len(e.buf) == encryptChunkSize {
+			if err := e.writeChunk(false); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+func (e *encryptWriter) writeChunk(last bool) error {
+	flag := []byte{0}
+	if last {
+		flag[0] = 1
+	}
+
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.counter), e.buf, flag)
+	e.counter++
+	e.buf = e.buf[:0]
+
+	header := binary.BigEndian.AppendUint32(flag, uint32(len(sealed)))
+	if _, err := e.w.Write(append(header, sealed...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Write the last chunk.
+func (e *encryptWriter) Close() error {
+	return e.writeChunk(true)
+}
+
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	last    bool
+}
+
+func newDecryptReader(r io.Reader, key []byte) (*decryptReader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(encryptMagic)+aead.NonceSize()-4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("fail to read encryption header, error: %v", err)
+	}
+
+	if !bytes.Equal(header[:len(encryptMagic)], encryptMagic) {
+		return nil, errors.New("the file is not encrypted by onedump")
+	}
+
+	return &decryptReader{r: r, aead: aead, prefix: header[len(encryptMagic):]}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.last {
+			return 0, io.EOF
+		}
+
+		if err := d.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+
+	return n, nil
+}
+
+func (d *decryptReader) readChunk() error {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("encrypted file is truncated")
+		}
+
+		return fmt.Errorf("fail to read encrypted chunk, error: %v", err)
+	}
+
+	sealed := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return fmt.Errorf("fail to read encrypted chunk, error: %v", err)
+	}
+
+	plain, err := d.aead.Open(nil, chunkNonce(d.prefix, d.counter), sealed, header[:1])
+	if err != nil {
+		return errors.New("fail to decrypt the file, the encryption key is wrong or the file is corrupted")
+	}
+
+	d.counter++
+	d.buf = plain
+	d.last = header[0] == 1
+
+	return nil
+}
+
+// Decompress and decrypt the binlog files of the directory in place, so they can be read by mysqlbinlog.
+// Only the files listed in the manifest synced alongside the binlog files are decoded, the other files are left untouched.
+// The encoded files are removed after they are decoded, e.g. mysql-bin.000001.zst.enc is replaced by mysql-bin.000001.
+func DecodeBinlogs(dir string, key []byte) error {
+	decoded, err := decodeBinlogs(dir, dir, key)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range decoded {
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("fail to remove encoded binlog file: %s, error: %v", file, err)
+		}
+	}
+
+	return nil
+}
+
+// Decode the encoded binlog files of the directory that are listed in its manifest into the dst directory,
+// it returns the encoded files that are decoded. The decoded files are checked against the size and sha256 of the manifest.
+func decodeBinlogs(dir, dst string, key []byte) ([]string, error) {
+	// The manifest is empty if it is not downloaded, so nothing is decoded.
+	manifest, err := loadManifest(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []string
+	for _, entry := range manifest.entries() {
+		if entry.Compression == "" && !entry.Encrypted {
+			continue
+		}
+
+		file := filepath.Join(dir, filepath.Base(entry.remoteName()))
+		if _, err := os.Stat(file); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("fail to stat binlog file: %s, error: %v", file, err)
+		}
+
+		encoding := fileEncoding{compression: entry.Compression}
+		if entry.Encrypted {
+			if len(key) == 0 {
+				return nil, fmt.Errorf("binlog file %s is encrypted, the encryption key is required", filepath.Base(file))
+			}
+
+			encoding.key = key
+		}
+
+		name := filepath.Base(entry.Name)
+		if err := decodeFile(file, filepath.Join(dst, name), encoding, entry); err != nil {
+			return nil, fmt.Errorf("fail to decode binlog file: %s, error: %v", file, err)
+		}
+
+		decoded = append(decoded, file)
+		slog.Debug("binlog file decoded", slog.String("file", name))
+	}
+
+	return decoded, nil
+}
+
+// Decode the file and check it against the size and sha256 of its manifest entry,
+// as the uncompressed and zstd files are not checked for truncation or corruption.
+func decodeFile(src, dst string, encoding fileEncoding, entry *ManifestEntry) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := in.Close(); err != nil {
+			slog.Error("fail to close file.", slog.Any("file", in.Name()), slog.Any("error", err))
+		}
+	}()
+
+	var r io.Reader = in
+	if len(encoding.key) > 0 {
+		if r, err = newDecryptReader(r, encoding.key); err != nil {
+			return err
+		}
+	}
+
+	switch encoding.compression {
+	case CompressionGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("fail to create gzip reader, error: %v", err)
+		}
+
+		defer gz.Close()
+		r = gz
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("fail to create zstd reader, error: %v", err)
+		}
+
+		defer zr.Close()
+		r = zr
+	}
+
+	// Write to a temporary file, so a failed decoding does not leave a partial binlog file.
+	// The file may be decrypted, so it is only readable by the owner.
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hasher), r)
+	if err == nil {
+		err = checkDecodedFile(entry, size, hex.EncodeToString(hasher.Sum(nil)))
+	}
+
+	if err = errors.Join(err, out.Close()); err != nil {
+		return errors.Join(err, os.Remove(tmp))
+	}
+
+	return os.Rename(tmp, dst)
+}
+
+// The checksum is not checked if the manifest does not have it.
+func checkDecodedFile(entry *ManifestEntry, size int64, sum string) error {
+	if size != entry.Size {
+		return fmt.Errorf("the decoded size %d does not match the size %d of the manifest, the file is truncated or corrupted", size, entry.Size)
+	}
+
+	if entry.SHA256 != "" && sum != entry.SHA256 {
+		return errors.New("the decoded sha256 does not match the manifest, the file is corrupted")
+	}
+
+	return nil
+}
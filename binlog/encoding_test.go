@@ -0,0 +1,270 @@
+package binlog
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liweiyi88/onedump/filesync"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEncryptionKey(t *testing.T) []byte {
+	key := make([]byte, encryptionKeySize)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+
+	return key
+}
+
+func encodeContent(t *testing.T, encoding fileEncoding, content []byte) []byte {
+	var buf bytes.Buffer
+
+	w, err := encoding.encode(&buf)
+	assert.NoError(t, err)
+
+	_, err = w.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+// Save the manifest of the encoded files, as binlog sync does alongside the binlog files.
+func saveEncodedManifest(t *testing.T, dir string, content []byte, encodings map[string]fileEncoding) {
+	sum := sha256.Sum256(content)
+
+	manifest := &BinlogManifest{}
+	for remoteName, encoding := range encodings {
+		manifest.set(&ManifestEntry{
+			Name:        strings.TrimSuffix(remoteName, encoding.suffix()),
+			Size:        int64(len(content)),
+			SHA256:      hex.EncodeToString(sum[:]),
+			RemoteName:  remoteName,
+			Compression: encoding.compression,
+			Encrypted:   len(encoding.key) > 0,
+		})
+	}
+
+	assert.NoError(t, manifest.save(filepath.Join(dir, ManifestFile)))
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	assert := assert.New(t)
+
+	key := newEncryptionKey(t)
+	parsed, err := ParseEncryptionKey(base64.StdEncoding.EncodeToString(key) + "\n")
+	assert.NoError(err)
+	assert.Equal(key, parsed)
+
+	_, err = ParseEncryptionKey("not base64!")
+	assert.ErrorContains(err, "base64")
+
+	_, err = ParseEncryptionKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.ErrorContains(err, "must be 32 bytes")
+}
+
+func TestFileEncodingSuffix(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("", fileEncoding{}.suffix())
+	assert.Equal(".gz", fileEncoding{compression: CompressionGzip}.suffix())
+	assert.Equal(".zst.enc", fileEncoding{compression: CompressionZstd, key: []byte("key")}.suffix())
+	assert.Equal(".enc", fileEncoding{key: []byte("key")}.suffix())
+}
+
+func TestDecodeBinlogs(t *testing.T) {
+	content := bytes.Repeat([]byte("binlog events "), 10000) // larger than an encrypted chunk
+	key := newEncryptionKey(t)
+
+	t.Run("it should decode the compressed and encrypted files in place", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+
+		encodings := map[string]fileEncoding{
+			"binlog.000001.gz":      {compression: CompressionGzip},
+			"binlog.000002.zst":     {compression: CompressionZstd},
+			"binlog.000003.enc":     {key: key},
+			"binlog.000004.gz.enc":  {compression: CompressionGzip, key: key},
+			"binlog.000005.zst.enc": {compression: CompressionZstd, key: key},
+		}
+
+		for name, encoding := range encodings {
+			assert.NoError(os.WriteFile(filepath.Join(dir, name), encodeContent(t, encoding, content), 0644))
+		}
+
+		saveEncodedManifest(t, dir, content, encodings)
+
+		assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000006"), content, 0644))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "dump.sql.gz"), []byte("not a binlog"), 0644))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "app.20250101.gz"), []byte("not a binlog"), 0644))
+
+		assert.NoError(DecodeBinlogs(dir, key))
+
+		binlogs, err := listSortedBinlogs(dir)
+		assert.NoError(err)
+		assert.Len(binlogs, 6)
+
+		for _, binlog := range binlogs {
+			decoded, err := os.ReadFile(binlog)
+			assert.NoError(err)
+			assert.True(bytes.Equal(content, decoded), binlog)
+		}
+
+		for name := range encodings {
+			assert.NoFileExists(filepath.Join(dir, name))
+		}
+
+		assert.FileExists(filepath.Join(dir, "dump.sql.gz"))
+		assert.FileExists(filepath.Join(dir, "app.20250101.gz"))
+		assert.NoFileExists(filepath.Join(dir, "app.20250101"))
+	})
+
+	t.Run("it should not decode the files without the manifest", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+
+		encoded := encodeContent(t, fileEncoding{compression: CompressionGzip}, content)
+		assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000001.gz"), encoded, 0644))
+
+		assert.NoError(DecodeBinlogs(dir, key))
+		assert.FileExists(filepath.Join(dir, "binlog.000001.gz"))
+		assert.NoFileExists(filepath.Join(dir, "binlog.000001"))
+	})
+
+	t.Run("it should skip the files that are not downloaded", func(t *testing.T) {
+		dir := t.TempDir()
+		saveEncodedManifest(t, dir, content, map[string]fileEncoding{"binlog.000001.gz": {compression: CompressionGzip}})
+
+		assert.NoError(t, DecodeBinlogs(dir, key))
+	})
+
+	t.Run("it should decode the files into another directory and leave the encoded files untouched", func(t *testing.T) {
+		assert := assert.New(t)
+		dir, dst := t.TempDir(), t.TempDir()
+
+		assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000001.zst.enc"), encodeContent(t, fileEncoding{compression: CompressionZstd, key: key}, content), 0644))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000002"), content, 0644))
+		saveEncodedManifest(t, dir, content, map[string]fileEncoding{"binlog.000001.zst.enc": {compression: CompressionZstd, key: key}})
+
+		decoded, err := decodeBinlogs(dir, dst, key)
+		assert.NoError(err)
+		assert.Equal([]string{filepath.Join(dir, "binlog.000001.zst.enc")}, decoded)
+		assert.FileExists(filepath.Join(dir, "binlog.000001.zst.enc"))
+		assert.NoFileExists(filepath.Join(dir, "binlog.000001"))
+
+		// The decrypted file is only readable by the owner.
+		info, err := os.Stat(filepath.Join(dst, "binlog.000001"))
+		assert.NoError(err)
+		assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+		binlogs, err := listSortedBinlogs(dir, dst)
+		assert.NoError(err)
+		assert.Equal([]string{filepath.Join(dst, "binlog.000001"), filepath.Join(dir, "binlog.000002")}, binlogs)
+	})
+
+	t.Run("it should fail to decode the files that do not match the manifest", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+
+		// The zstd file of a truncated download is decoded without errors.
+		encoded := encodeContent(t, fileEncoding{compression: CompressionZstd}, content[:len(content)-100])
+		assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000001.zst"), encoded, 0644))
+		saveEncodedManifest(t, dir, content, map[string]fileEncoding{"binlog.000001.zst": {compression: CompressionZstd}})
+
+		assert.ErrorContains(DecodeBinlogs(dir, key), "does not match the size")
+		assert.FileExists(filepath.Join(dir, "binlog.000001.zst"))
+		assert.NoFileExists(filepath.Join(dir, "binlog.000001"))
+		assert.NoFileExists(filepath.Join(dir, "binlog.000001.tmp"))
+
+		corrupted := bytes.Clone(content)
+		corrupted[0] = 'B'
+		assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000001.zst"), encodeContent(t, fileEncoding{compression: CompressionZstd}, corrupted), 0644))
+
+		assert.ErrorContains(DecodeBinlogs(dir, key), "sha256 does not match")
+		assert.NoFileExists(filepath.Join(dir, "binlog.000001"))
+	})
+
+	t.Run("it should require the key of the encrypted files", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "binlog.000001.enc"), encodeContent(t, fileEncoding{key: key}, content), 0644))
+		saveEncodedManifest(t, dir, content, map[string]fileEncoding{"binlog.000001.enc": {key: key}})
+
+		assert.ErrorContains(t, DecodeBinlogs(dir, nil), "the encryption key is required")
+	})
+
+	t.Run("it should fail to decrypt with a wrong key", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "binlog.000001.enc"), encodeContent(t, fileEncoding{key: key}, content), 0644))
+		saveEncodedManifest(t, dir, content, map[string]fileEncoding{"binlog.000001.enc": {key: key}})
+
+		assert.ErrorContains(t, DecodeBinlogs(dir, newEncryptionKey(t)), "the encryption key is wrong")
+		assert.FileExists(t, filepath.Join(dir, "binlog.000001.enc"))
+		assert.NoFileExists(t, filepath.Join(dir, "binlog.000001"))
+	})
+
+	t.Run("it should fail to decrypt a truncated file", func(t *testing.T) {
+		dir := t.TempDir()
+		encoded := encodeContent(t, fileEncoding{key: key}, content)
+
+		// Drop the last chunk.
+		truncated := encoded[:len(encoded)-(len(content)%encryptChunkSize+5+16)]
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "binlog.000001.enc"), truncated, 0644))
+		saveEncodedManifest(t, dir, content, map[string]fileEncoding{"binlog.000001.enc": {key: key}})
+
+		assert.ErrorContains(t, DecodeBinlogs(dir, key), "truncated")
+	})
+}
+
+func TestBinlogSyncerEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("binlog events "), 1000)
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000001"), content, 0644))
+
+	key := newEncryptionKey(t)
+	destination := &memoryStorage{files: make(map[string][]byte)}
+	binlogInfo := &BinlogInfo{currentBinlogFile: "binlog.000002", binlogDir: dir, binlogPrefix: "binlog"}
+
+	syncer := NewBinlogSyncer("binlogs", false, "", filesync.NewFileSync(false, ""), binlogInfo, WithCompression(CompressionZstd), WithEncryptionKey(key))
+	assert.NoError(syncer.Sync(destination))
+
+	encoded, ok := destination.get("binlogs/binlog.000001.zst.enc")
+	assert.True(ok)
+	assert.Less(len(encoded), len(content))
+
+	entry := syncer.manifest.get("binlog.000001")
+	assert.Equal("binlog.000001.zst.enc", entry.RemoteName)
+	assert.Equal(CompressionZstd, entry.Compression)
+	assert.True(entry.Encrypted)
+	assert.Equal(int64(len(content)), entry.Size)
+
+	restoreDir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(restoreDir, "binlog.000001.zst.enc"), encoded, 0644))
+
+	manifest, ok := destination.get("binlogs/" + ManifestFile)
+	assert.True(ok)
+	assert.NoError(os.WriteFile(filepath.Join(restoreDir, ManifestFile), manifest, 0644))
+	assert.NoError(DecodeBinlogs(restoreDir, key))
+
+	decoded, err := os.ReadFile(filepath.Join(restoreDir, "binlog.000001"))
+	assert.NoError(err)
+	assert.True(bytes.Equal(content, decoded))
+
+	// The file is uploaded again when the encoding changes.
+	clear(destination.files)
+	syncer = NewBinlogSyncer("binlogs", false, "", filesync.NewFileSync(false, ""), binlogInfo, WithCompression(CompressionGzip))
+	assert.NoError(syncer.Sync(destination))
+
+	_, ok = destination.get("binlogs/binlog.000001.gz")
+	assert.True(ok)
+	assert.Equal("binlog.000001.gz", syncer.manifest.get("binlog.000001").RemoteName)
+	assert.False(syncer.manifest.get("binlog.000001").Encrypted)
+}
@@ -0,0 +1,105 @@
+package binlog
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/liweiyi88/onedump/config"
+)
+
+// Select the events of a database and its tables, and rewrite the database names, e.g. restore orders into orders_recovered.
+// The database filter matches the source database names before they are rewritten.
+type eventFilter struct {
+	database   string
+	tables     config.TableFilter
+	rewriteDBs map[string]string
+}
+
+// Create the event filter, it returns nil if there is nothing to filter or rewrite.
+func newEventFilter(database string, tables config.TableFilter, rewriteDBs []string) (*eventFilter, error) {
+	database = strings.TrimSpace(database)
+
+	if database == "" && len(tables.Include) == 0 && len(tables.Exclude) == 0 && len(rewriteDBs) == 0 {
+		return nil, nil
+	}
+
+	for _, patterns := range [][]string{tables.Include, tables.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid table pattern: %q, error: %v", pattern, err)
+			}
+		}
+	}
+
+	filter := &eventFilter{
+		database:   database,
+		tables:     config.TableFilter{Include: tables.Include, Exclude: tables.Exclude},
+		rewriteDBs: make(map[string]string),
+	}
+
+	for _, rewrite := range rewriteDBs {
+		from, to, found := strings.Cut(rewrite, "->")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+
+		if !found || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid value of --rewrite-db option: %s, it must be in the format of from->to", rewrite)
+		}
+
+		if _, ok := filter.rewriteDBs[from]; ok {
+			return nil, fmt.Errorf("invalid value of --rewrite-db option: %s, database %s is rewritten more than once", rewrite, from)
+		}
+
+		filter.rewriteDBs[from] = to
+	}
+
+	return filter, nil
+}
+
+func (f *eventFilter) hasTableFilter() bool {
+	return f != nil && (len(f.tables.Include) > 0 || len(f.tables.Exclude) > 0)
+}
+
+// Check if the statements executed in the default database should be applied.
+// The same as mysqlbinlog, the statements without a default database are skipped if the database filter is set.
+func (f *eventFilter) matchDatabase(database string) bool {
+	return f == nil || f.database == "" || f.database == database
+}
+
+// Check if the row changes of the table should be applied.
+func (f *eventFilter) matchTable(database, table string) bool {
+	return f == nil || (f.matchDatabase(database) && f.tables.Match(table))
+}
+
+// Get the database name in the target server.
+func (f *eventFilter) rewrite(database string) string {
+	if f == nil {
+		return database
+	}
+
+	if to, ok := f.rewriteDBs[database]; ok {
+		return to
+	}
+
+	return database
+}
+
+// mysqlbinlog applies --rewrite-db before --database, so it filters the rewritten database name.
+func (f *eventFilter) mysqlbinlogArgs() []string {
+	if f == nil {
+		return nil
+	}
+
+	var args []string
+	if f.database != "" {
+		args = append(args, "--database="+f.rewrite(f.database))
+	}
+
+	for _, from := range slices.Sorted(maps.Keys(f.rewriteDBs)) {
+		args = append(args, fmt.Sprintf("--rewrite-db=%s->%s", from, f.rewriteDBs[from]))
+	}
+
+	return args
+}
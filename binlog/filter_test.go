@@ -0,0 +1,112 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/liweiyi88/onedump/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEventFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	filter, err := newEventFilter(" ", config.TableFilter{}, nil)
+	assert.NoError(err)
+	assert.Nil(filter)
+
+	// All methods work without a filter.
+	assert.True(filter.matchDatabase(""))
+	assert.True(filter.matchTable("orders", "items"))
+	assert.Equal("orders", filter.rewrite("orders"))
+	assert.False(filter.hasTableFilter())
+	assert.Nil(filter.mysqlbinlogArgs())
+
+	_, err = newEventFilter("", config.TableFilter{}, []string{"orders"})
+	assert.EqualError(err, "invalid value of --rewrite-db option: orders, it must be in the format of from->to")
+
+	_, err = newEventFilter("", config.TableFilter{}, []string{"orders->a", "orders->b"})
+	assert.EqualError(err, "invalid value of --rewrite-db option: orders->b, database orders is rewritten more than once")
+
+	_, err = newEventFilter("", config.TableFilter{Include: []string{"[a"}}, nil)
+	assert.ErrorContains(err, `invalid table pattern: "[a"`)
+}
+
+func TestEventFilterMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	filter, err := newEventFilter("orders", config.TableFilter{Exclude: []string{"audit_*"}}, []string{"orders -> orders_recovered", "crm->crm_recovered"})
+	assert.NoError(err)
+
+	assert.True(filter.hasTableFilter())
+	assert.True(filter.matchDatabase("orders"))
+	assert.False(filter.matchDatabase("crm"))
+	assert.False(filter.matchDatabase(""))
+
+	assert.True(filter.matchTable("orders", "items"))
+	assert.False(filter.matchTable("orders", "audit_logs"))
+	assert.False(filter.matchTable("crm", "items"))
+
+	assert.Equal("orders_recovered", filter.rewrite("orders"))
+	assert.Equal("mysql", filter.rewrite("mysql"))
+
+	assert.Equal([]string{"--database=orders_recovered", "--rewrite-db=crm->crm_recovered", "--rewrite-db=orders->orders_recovered"}, filter.mysqlbinlogArgs())
+}
+
+func TestApplyFilteredEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(err)
+	defer db.Close()
+
+	applier := newEventApplier(db, nil)
+	applier.filter, err = newEventFilter("orders", config.TableFilter{Exclude: []string{"audit_logs"}}, []string{"orders->orders_recovered"})
+	assert.NoError(err)
+
+	header := &replication.EventHeader{Timestamp: 1748912333}
+	tableMap := func(schema, table string) *replication.TableMapEvent {
+		return &replication.TableMapEvent{
+			Schema:      []byte(schema),
+			Table:       []byte(table),
+			ColumnCount: 1,
+			ColumnType:  []byte{mysql.MYSQL_TYPE_LONG},
+			ColumnName:  [][]byte{[]byte("id")},
+			PrimaryKey:  []uint64{0},
+		}
+	}
+
+	writeRows := func(schema, table string) *replication.BinlogEvent {
+		return &replication.BinlogEvent{Header: &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2}, Event: &replication.RowsEvent{
+			Table: tableMap(schema, table), ColumnCount: 1, Rows: [][]any{{int32(1)}},
+		}}
+	}
+
+	events := []*replication.BinlogEvent{
+		{Header: header, Event: &replication.QueryEvent{Schema: []byte("crm"), Query: []byte("CREATE TABLE contacts (id INT)")}},
+		{Header: header, Event: &replication.QueryEvent{Schema: []byte("orders"), Query: []byte("CREATE TABLE items (id INT)")}},
+		{Header: header, Event: &replication.QueryEvent{Schema: []byte("orders"), Query: []byte("ALTER TABLE audit_logs ADD COLUMN note TEXT")}},
+		{Header: header, Event: &replication.QueryEvent{Schema: []byte("orders"), Query: []byte("TRUNCATE audit_logs")}},
+		{Header: header, Event: &replication.QueryEvent{Schema: []byte("crm"), Query: []byte("BEGIN")}},
+		writeRows("crm", "contacts"),
+		writeRows("orders", "audit_logs"),
+		writeRows("orders", "items"),
+		{Header: header, Event: &replication.XIDEvent{}},
+	}
+
+	// The statements are skipped with the table filters, only the transactions and the row events are applied.
+	mock.ExpectExec("SET @@session.timestamp=1748912333").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("BEGIN").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET @@session.foreign_key_checks=1, @@session.unique_checks=1, @@session.sql_mode='NO_AUTO_VALUE_ON_ZERO', @@session.time_zone='+00:00'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `orders_recovered`.`items` (`id`) VALUES (1)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("COMMIT").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	for _, event := range events {
+		assert.NoError(applier.apply(event))
+	}
+
+	assert.NoError(applier.close())
+	assert.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,279 @@
+package binlog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/liweiyi88/onedump/config"
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+// The boundaries and filters of the row changes to undo.
+// The start boundaries are inclusive and the stop boundaries are exclusive, the same as the restore command.
+type FlashbackOptions struct {
+	StartBinlog   string // the first binlog file by default
+	StartPosition int
+	StopBinlog    string // the last binlog file by default
+	StopPosition  int    // the end of the stop binlog file by default
+	StartDateTime time.Time
+	StopDateTime  time.Time
+	Database      string
+	Tables        config.TableFilter
+}
+
+// Generate the inverse statements of the row changes in the binlogs, e.g. to undo an UPDATE or DELETE without WHERE.
+type Flashback struct {
+	binlogDir string
+	options   FlashbackOptions
+	filter    *eventFilter
+}
+
+func NewFlashback(binlogDir string, options FlashbackOptions) (*Flashback, error) {
+	if options.StopPosition > 0 && options.StopBinlog == "" {
+		return nil, errors.New("stop binlog is required if stop position is set")
+	}
+
+	if !options.StartDateTime.IsZero() && !options.StopDateTime.IsZero() && !options.StartDateTime.Before(options.StopDateTime) {
+		return nil, errors.New("start datetime must be before stop datetime")
+	}
+
+	filter, err := newEventFilter(options.Database, options.Tables, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Flashback{
+		binlogDir: binlogDir,
+		options:   options,
+		filter:    filter,
+	}, nil
+}
+
+// Undo the row changes in a transaction, or write the statements to the output for review if it is not nil.
+func (f *Flashback) Run(db *sql.DB, output io.Writer) error {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("fail to connect to database, error: %v", err)
+	}
+
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("fail to close database connection", slog.Any("error", err))
+		}
+	}()
+
+	statements, err := f.generate(newEventApplier(conn, nil))
+	if err != nil {
+		return err
+	}
+
+	if len(statements) == 0 {
+		slog.Info("no row changes are found between the boundaries, nothing to undo")
+		return nil
+	}
+
+	// Row values are decoded in UTC.
+	statements = slices.Concat(
+		[]string{fmt.Sprintf("SET @@session.time_zone=%s, @@session.sql_mode=%s", rowsTimeZone, rowsSqlMode), "BEGIN"},
+		statements,
+		[]string{"COMMIT"},
+	)
+
+	if output != nil {
+		for _, statement := range statements {
+			if _, err := fmt.Fprintf(output, "%s;\n", statement); err != nil {
+				return fmt.Errorf("fail to write flashback statements, error: %v", err)
+			}
+		}
+
+		return nil
+	}
+
+	for _, statement := range statements {
+		if _, err := conn.ExecContext(context.Background(), statement); err != nil {
+			if _, rollbackErr := conn.ExecContext(context.Background(), "ROLLBACK"); rollbackErr != nil {
+				slog.Error("fail to rollback flashback statements", slog.Any("error", rollbackErr))
+			}
+
+			return fmt.Errorf("failed to execute statement: %s, error: %v", sqlutil.Truncate(statement), err)
+		}
+	}
+
+	slog.Info("flashback completed", slog.Int("statements", len(statements)-3))
+
+	return nil
+}
+
+// Get the inverse statements of the row changes within the boundaries in reverse order.
+func (f *Flashback) generate(applier *eventApplier) ([]string, error) {
+	binlogs, err := f.getBinlogs()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := replication.NewBinlogParser()
+	parser.SetTimestampStringLocation(time.UTC)
+
+	var statements []string
+	for i, binlog := range binlogs {
+		// Parse from the beginning, so the table map events before the start position are known.
+		err := parser.ParseFile(binlog, 0, func(e *replication.BinlogEvent) error {
+			start := int(e.Header.LogPos) - int(e.Header.EventSize)
+
+			if i == 0 && start < f.options.StartPosition {
+				return nil
+			}
+
+			if i == len(binlogs)-1 && f.options.StopPosition > 0 && start >= f.options.StopPosition {
+				return errStopPositionReached
+			}
+
+			if e.Header.Timestamp > 0 {
+				eventTime := time.Unix(int64(e.Header.Timestamp), 0)
+
+				if eventTime.Before(f.options.StartDateTime) {
+					return nil
+				}
+
+				if !f.options.StopDateTime.IsZero() && !eventTime.Before(f.options.StopDateTime) {
+					return errStopPositionReached
+				}
+			}
+
+			inverses, err := f.inverse(applier, e)
+			if err != nil {
+				return fmt.Errorf("fail to undo event at %s:%d, error: %v", filepath.Base(binlog), start, err)
+			}
+
+			statements = append(statements, inverses...)
+			return nil
+		})
+
+		if errors.Is(err, errStopPositionReached) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse binlog file: %s: %v", binlog, err)
+		}
+	}
+
+	slices.Reverse(statements)
+
+	return statements, nil
+}
+
+func (f *Flashback) inverse(applier *eventApplier, e *replication.BinlogEvent) ([]string, error) {
+	switch event := e.Event.(type) {
+	case *replication.TransactionPayloadEvent:
+		var statements []string
+		for _, inner := range event.Events {
+			inverses, err := f.inverse(applier, inner)
+			if err != nil {
+				return nil, err
+			}
+
+			statements = append(statements, inverses...)
+		}
+
+		return statements, nil
+	case *replication.RowsEvent:
+		if event.Table == nil {
+			return nil, fmt.Errorf("rows event of table id %d has no table map event", event.TableID)
+		}
+
+		schema, tableName := string(event.Table.Schema), string(event.Table.Table)
+		if !f.filter.matchTable(schema, tableName) {
+			return nil, nil
+		}
+
+		table, err := applier.getTable(schema, event.Table)
+		if err != nil {
+			return nil, err
+		}
+
+		return inverseRowsStatements(event, e.Header.EventType, table, sqlutil.QuoteIdentifier(schema)+"."+sqlutil.QuoteIdentifier(tableName))
+	}
+
+	return nil, nil
+}
+
+// Get the statements that undo a rows event: DELETE for inserted rows, INSERT for deleted rows,
+// and UPDATE with the before and after images swapped for updated rows.
+func inverseRowsStatements(event *replication.RowsEvent, eventType replication.EventType, table *tableInfo, name string) ([]string, error) {
+	if eventType == replication.PARTIAL_UPDATE_ROWS_EVENT {
+		return nil, fmt.Errorf("partial JSON updates of table %s cannot be undone, set binlog_row_value_options to empty", name)
+	}
+
+	// The full before and after images are required to restore the rows.
+	for _, skipped := range event.SkippedColumns {
+		if len(skipped) > 0 {
+			return nil, fmt.Errorf("rows event of table %s does not have full row images, flashback requires binlog_row_image=FULL", name)
+		}
+	}
+
+	inverse := *event
+
+	var inverseType replication.EventType
+	switch eventType {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		inverseType = replication.DELETE_ROWS_EVENTv2
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+		inverseType = replication.WRITE_ROWS_EVENTv2
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		if len(event.Rows)%2 != 0 {
+			return nil, fmt.Errorf("update rows event of table %s has an odd number of row images", name)
+		}
+
+		inverseType = replication.UPDATE_ROWS_EVENTv2
+		inverse.Rows = make([][]any, 0, len(event.Rows))
+		for i := 0; i < len(event.Rows); i += 2 {
+			inverse.Rows = append(inverse.Rows, event.Rows[i+1], event.Rows[i])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rows event type: %s", eventType)
+	}
+
+	return rowsStatements(&inverse, inverseType, table, name)
+}
+
+// Get the binlogs between the start and stop binlogs.
+func (f *Flashback) getBinlogs() ([]string, error) {
+	binlogs, err := listSortedBinlogs(f.binlogDir)
+	if err != nil {
+		return nil, err
+	}
+
+	startIndex, stopIndex := 0, len(binlogs)-1
+	for i, binlog := range binlogs {
+		name := filepath.Base(binlog)
+
+		if name == f.options.StartBinlog {
+			startIndex = i
+		}
+
+		if name == f.options.StopBinlog {
+			stopIndex = i
+		}
+	}
+
+	for _, name := range []string{f.options.StartBinlog, f.options.StopBinlog} {
+		if name != "" && !slices.ContainsFunc(binlogs, func(binlog string) bool { return filepath.Base(binlog) == name }) {
+			return nil, fmt.Errorf("binlog %s not found", name)
+		}
+	}
+
+	if startIndex > stopIndex {
+		return nil, fmt.Errorf("start binlog %s is after stop binlog %s", f.options.StartBinlog, f.options.StopBinlog)
+	}
+
+	return binlogs[startIndex : stopIndex+1], nil
+}
@@ -0,0 +1,160 @@
+package binlog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/liweiyi88/onedump/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInverseRowsStatements(t *testing.T) {
+	assert := assert.New(t)
+
+	table := &tableInfo{columns: []string{"id", "name"}, primaryKey: []int{0}}
+	tableMap := &replication.TableMapEvent{ColumnType: []byte{mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_VARCHAR}}
+	event := func(rows ...[]any) *replication.RowsEvent {
+		return &replication.RowsEvent{Table: tableMap, ColumnCount: 2, Rows: rows, SkippedColumns: make([][]int, len(rows))}
+	}
+
+	statements, err := inverseRowsStatements(event([]any{int32(1), "john"}, []any{int32(2), "jane"}), replication.WRITE_ROWS_EVENTv2, table, "`users`")
+	assert.NoError(err)
+	assert.Equal([]string{"DELETE FROM `users` WHERE `id`=1 LIMIT 1", "DELETE FROM `users` WHERE `id`=2 LIMIT 1"}, statements)
+
+	statements, err = inverseRowsStatements(event([]any{int32(1), "john"}, []any{int32(2), "jane"}), replication.DELETE_ROWS_EVENTv2, table, "`users`")
+	assert.NoError(err)
+	assert.Equal([]string{"INSERT INTO `users` (`id`,`name`) VALUES (1,_binary'john'),(2,_binary'jane')"}, statements)
+
+	statements, err = inverseRowsStatements(event([]any{int32(1), "john"}, []any{int32(3), "jack"}), replication.UPDATE_ROWS_EVENTv2, table, "`users`")
+	assert.NoError(err)
+	assert.Equal([]string{"UPDATE `users` SET `id`=1, `name`=_binary'john' WHERE `id`=3 LIMIT 1"}, statements)
+
+	minimal := event([]any{int32(1), nil}, []any{nil, "jack"})
+	minimal.SkippedColumns = [][]int{{1}, {0}}
+	_, err = inverseRowsStatements(minimal, replication.UPDATE_ROWS_EVENTv2, table, "`users`")
+	assert.EqualError(err, "rows event of table `users` does not have full row images, flashback requires binlog_row_image=FULL")
+
+	_, err = inverseRowsStatements(event(), replication.PARTIAL_UPDATE_ROWS_EVENT, table, "`users`")
+	assert.ErrorContains(err, "cannot be undone")
+}
+
+func TestNewFlashback(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewFlashback("", FlashbackOptions{StopPosition: 100})
+	assert.EqualError(err, "stop binlog is required if stop position is set")
+
+	_, err = NewFlashback("", FlashbackOptions{StartDateTime: time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC), StopDateTime: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)})
+	assert.EqualError(err, "start datetime must be before stop datetime")
+
+	_, err = NewFlashback("", FlashbackOptions{Tables: config.TableFilter{Include: []string{"[a"}}})
+	assert.ErrorContains(err, "invalid table pattern")
+}
+
+func TestFlashbackRun(t *testing.T) {
+	assert := assert.New(t)
+
+	currentDir, err := os.Getwd()
+	assert.NoError(err)
+
+	binlogsDir := filepath.Join(currentDir, "..", "testutils", "mysqlrestore", "binlogs")
+
+	expectColumns := func(mock sqlmock.Sqlmock) {
+		rows := sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE", "COLUMN_KEY"})
+		for i := range 51 {
+			key := ""
+			if i < 2 {
+				key = "PRI"
+			}
+
+			rows.AddRow(fmt.Sprintf("c%d", i+1), "varchar(255)", key)
+		}
+
+		mock.ExpectQuery("SELECT COLUMN_NAME, COLUMN_TYPE, COLUMN_KEY FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION").
+			WithArgs("mysql", "user").
+			WillReturnRows(rows)
+	}
+
+	var insert string
+
+	t.Run("it should write the inverse statements to the output", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(err)
+		defer db.Close()
+
+		expectColumns(mock)
+
+		flashback, err := NewFlashback(binlogsDir, FlashbackOptions{StartBinlog: "mysql-bin.000003", Database: "mysql"})
+		assert.NoError(err)
+
+		var output bytes.Buffer
+		assert.NoError(flashback.Run(db, &output))
+		assert.NoError(mock.ExpectationsWereMet())
+
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		assert.Len(lines, 4)
+		assert.Equal("SET @@session.time_zone='+00:00', @@session.sql_mode='NO_AUTO_VALUE_ON_ZERO';", lines[0])
+		assert.Equal("BEGIN;", lines[1])
+		assert.True(strings.HasPrefix(lines[2], "INSERT INTO `mysql`.`user` (`c1`,`c2`,"), lines[2])
+		assert.Equal("COMMIT;", lines[3])
+
+		insert = strings.TrimSuffix(lines[2], ";")
+	})
+
+	t.Run("it should skip the row changes outside of the boundaries", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(err)
+		defer db.Close()
+
+		// The row changes of mysql.user start at position 237.
+		flashback, err := NewFlashback(binlogsDir, FlashbackOptions{StartBinlog: "mysql-bin.000003", StopBinlog: "mysql-bin.000003", StopPosition: 237})
+		assert.NoError(err)
+
+		var output bytes.Buffer
+		assert.NoError(flashback.Run(db, &output))
+		assert.Empty(output.String())
+		assert.NoError(mock.ExpectationsWereMet())
+
+		flashback, err = NewFlashback(binlogsDir, FlashbackOptions{StartBinlog: "mysql-bin.000003", Tables: config.TableFilter{Exclude: []string{"user"}}})
+		assert.NoError(err)
+		assert.NoError(flashback.Run(db, &output))
+		assert.Empty(output.String())
+	})
+
+	t.Run("it should apply the inverse statements in a transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(err)
+		defer db.Close()
+
+		expectColumns(mock)
+		mock.ExpectExec("SET @@session.time_zone='+00:00', @@session.sql_mode='NO_AUTO_VALUE_ON_ZERO'").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("BEGIN").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(insert).WillReturnError(fmt.Errorf("duplicate entry"))
+		mock.ExpectExec("ROLLBACK").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		flashback, err := NewFlashback(binlogsDir, FlashbackOptions{StartBinlog: "mysql-bin.000003"})
+		assert.NoError(err)
+
+		err = flashback.Run(db, nil)
+		assert.ErrorContains(err, "duplicate entry")
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return an error if the binlog is not found", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		assert.NoError(err)
+		defer db.Close()
+
+		flashback, err := NewFlashback(binlogsDir, FlashbackOptions{StartBinlog: "mysql-bin.000009"})
+		assert.NoError(err)
+		assert.EqualError(flashback.Run(db, nil), "binlog mysql-bin.000009 not found")
+	})
+}
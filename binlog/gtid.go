@@ -0,0 +1,147 @@
+package binlog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+var (
+	ErrStopGTIDNotFound = errors.New("the GTID of --stop-before-gtid option is not found in the binlog files")
+
+	// A fake error to stop parsing events.
+	errPreviousGTIDsFound = errors.New("previous GTIDs found")
+	errStopGTIDFound      = errors.New("stop GTID found")
+
+	gtidPurgedRegex  = regexp.MustCompile(`(?i)GTID_PURGED\s*=\s*(?:/\*!\d+\s*'\+'\s*\*/\s*)?'([^']*)('?)`)
+	dumpContentRegex = regexp.MustCompile(`(?i)^(CREATE\s+TABLE|INSERT\s+INTO)\b`)
+)
+
+// Parse a GTID set option, it returns nil if the option is empty.
+func parseGTIDSet(option, value string) (*mysql.MysqlGTIDSet, error) {
+	value = strings.Join(strings.Fields(value), "")
+	if value == "" {
+		return nil, nil
+	}
+
+	set, err := mysql.ParseMysqlGTIDSet(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of --%s option: %s, error: %v", option, value, err)
+	}
+
+	return set.(*mysql.MysqlGTIDSet), nil
+}
+
+// Parse the --stop-before-gtid option, it must be a single GTID, e.g. 3E11FA47-71CA-11E1-9E33-C80AA9429562:23.
+func parseGTID(value string) (*mysql.MysqlGTIDSet, error) {
+	set, err := parseGTIDSet("stop-before-gtid", value)
+	if err != nil || set == nil {
+		return set, err
+	}
+
+	for _, uuidSet := range set.Sets {
+		if len(set.Sets) != 1 || len(uuidSet.Intervals) != 1 || uuidSet.Intervals[0].Stop-uuidSet.Intervals[0].Start != 1 {
+			return nil, fmt.Errorf("invalid value of --stop-before-gtid option: %s, it must be a single GTID, e.g. 3E11FA47-71CA-11E1-9E33-C80AA9429562:23", value)
+		}
+	}
+
+	return set, nil
+}
+
+// Get the GTID of a GTID event, anonymous GTID events of servers without gtid_mode have no GTID.
+func eventGTID(e *replication.BinlogEvent) (mysql.GTIDSet, error) {
+	event, ok := e.Event.(*replication.GTIDEvent)
+	if !ok || e.Header.EventType != replication.GTID_EVENT {
+		return nil, nil
+	}
+
+	gtid, err := event.GTIDNext()
+	if err != nil {
+		return nil, fmt.Errorf("invalid GTID event at position %d, error: %v", e.Header.LogPos, err)
+	}
+
+	return gtid, nil
+}
+
+// Get the GTIDs executed before the binlog file from its PREVIOUS_GTIDS event.
+func readPreviousGTIDs(binlog string) (*mysql.MysqlGTIDSet, error) {
+	previous := &mysql.MysqlGTIDSet{Sets: make(map[string]*mysql.UUIDSet)}
+
+	parser := replication.NewBinlogParser()
+	err := parser.ParseFile(binlog, 0, func(e *replication.BinlogEvent) error {
+		if e.Header.EventType == replication.FORMAT_DESCRIPTION_EVENT {
+			return nil
+		}
+
+		if event, ok := e.Event.(*replication.PreviousGTIDsEvent); ok {
+			if strings.TrimSpace(event.GTIDSets) == "" {
+				return errPreviousGTIDsFound
+			}
+
+			set, err := mysql.ParseMysqlGTIDSet(event.GTIDSets)
+			if err != nil {
+				return fmt.Errorf("invalid previous GTIDs: %s, error: %v", event.GTIDSets, err)
+			}
+
+			previous = set.(*mysql.MysqlGTIDSet)
+		}
+
+		// The PREVIOUS_GTIDS event follows the FORMAT_DESCRIPTION event.
+		return errPreviousGTIDsFound
+	})
+
+	if err != nil && !errors.Is(err, errPreviousGTIDsFound) {
+		return nil, fmt.Errorf("fail to parse binlog file: %s: %v", binlog, err)
+	}
+
+	return previous, nil
+}
+
+// Extracts the GTID_PURGED value from the header of a database dump file, it returns an empty string if the dump has no GTID state.
+// It supports dumps created by mysqldump with --set-gtid-purged and by the native mysql dumper.
+func ParseGTIDPurged(reader io.Reader) (string, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// The GTID state is in the header, before any table.
+		if dumpContentRegex.MatchString(line) {
+			return "", nil
+		}
+
+		matches := gtidPurgedRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		// mysqldump writes multiple GTID sets on separate lines.
+		var sb strings.Builder
+		sb.WriteString(matches[1])
+
+		for closed := matches[2] != ""; !closed; {
+			if !scanner.Scan() {
+				return "", errors.New("fail to parse GTID_PURGED, the value is not closed")
+			}
+
+			value, _, found := strings.Cut(scanner.Text(), "'")
+			sb.WriteString(value)
+			closed = found
+		}
+
+		return strings.Join(strings.Fields(sb.String()), ""), nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("fail to scan while extracting GTID_PURGED, error: %v", err)
+	}
+
+	return "", nil
+}
@@ -0,0 +1,112 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+)
+
+const testServerUUID = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+
+func TestParseGTIDPurged(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name     string
+		dump     string
+		expected string
+	}{
+		{
+			name:     "native dumper",
+			dump:     "-- CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='mysql-bin.000003', SOURCE_LOG_POS=157;\n-- SET @@GLOBAL.GTID_PURGED='" + testServerUUID + ":1-5';\nCREATE TABLE `users` (\n",
+			expected: testServerUUID + ":1-5",
+		},
+		{
+			name:     "mysqldump with multiple GTID sets",
+			dump:     "SET @@GLOBAL.GTID_PURGED=/*!80000 '+'*/ '" + testServerUUID + ":1-5,\n8a94f357-aab4-11df-86ab-c80aa9429563:1-3';\n",
+			expected: testServerUUID + ":1-5,8a94f357-aab4-11df-86ab-c80aa9429563:1-3",
+		},
+		{
+			name:     "GTID state after the tables is ignored",
+			dump:     "INSERT INTO `users` VALUES (1);\nSET @@GLOBAL.GTID_PURGED='" + testServerUUID + ":1-5';\n",
+			expected: "",
+		},
+		{
+			name:     "no GTID state",
+			dump:     "-- CHANGE MASTER TO MASTER_LOG_FILE='mysql-bin.000003', MASTER_LOG_POS=157;\n",
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gtidPurged, err := ParseGTIDPurged(strings.NewReader(test.dump))
+			assert.NoError(err)
+			assert.Equal(test.expected, gtidPurged)
+		})
+	}
+
+	_, err := ParseGTIDPurged(strings.NewReader("SET @@GLOBAL.GTID_PURGED='" + testServerUUID + ":1-5,\n"))
+	assert.EqualError(err, "fail to parse GTID_PURGED, the value is not closed")
+}
+
+func TestParseGTID(t *testing.T) {
+	assert := assert.New(t)
+
+	gtid, err := parseGTID(testServerUUID + ":23")
+	assert.NoError(err)
+	assert.Equal(testServerUUID+":23", gtid.String())
+
+	gtid, err = parseGTID("")
+	assert.NoError(err)
+	assert.Nil(gtid)
+
+	_, err = parseGTID(testServerUUID + ":1-23")
+	assert.ErrorContains(err, "it must be a single GTID")
+
+	_, err = parseGTIDSet("exclude-gtids", "invalid")
+	assert.ErrorContains(err, "invalid value of --exclude-gtids option: invalid")
+}
+
+func TestApplyExcludedGTIDs(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(err)
+	defer db.Close()
+
+	var output bytes.Buffer
+	applier := newEventApplier(db, &output)
+	applier.excludeGTIDs, err = parseGTIDSet("exclude-gtids", testServerUUID+":2")
+	assert.NoError(err)
+
+	sid, err := hex.DecodeString(strings.ReplaceAll(testServerUUID, "-", ""))
+	assert.NoError(err)
+
+	header := &replication.EventHeader{Timestamp: 1748912333}
+	gtidEvent := func(gno int64) *replication.BinlogEvent {
+		return &replication.BinlogEvent{Header: &replication.EventHeader{EventType: replication.GTID_EVENT}, Event: &replication.GTIDEvent{SID: sid, GNO: gno}}
+	}
+
+	events := []*replication.BinlogEvent{
+		gtidEvent(1),
+		{Header: header, Event: &replication.QueryEvent{Query: []byte("CREATE TABLE t1 (id INT)")}},
+		gtidEvent(2),
+		{Header: header, Event: &replication.QueryEvent{Query: []byte("DROP TABLE t1")}},
+		gtidEvent(3),
+		{Header: header, Event: &replication.QueryEvent{Query: []byte("CREATE TABLE t2 (id INT)")}},
+	}
+
+	for _, event := range events {
+		assert.NoError(applier.apply(event))
+	}
+
+	assert.Contains(output.String(), "CREATE TABLE t1 (id INT)")
+	assert.Contains(output.String(), "CREATE TABLE t2 (id INT)")
+	assert.NotContains(output.String(), "DROP TABLE t1")
+}
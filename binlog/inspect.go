@@ -0,0 +1,154 @@
+package binlog
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// The rows changed in a table.
+type TableRows struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Deleted  int `json:"deleted"`
+}
+
+// The summary of a binlog file, it tells which time window and transactions the file covers before a restore.
+// The event times are in UTC, the same as the value of the --stop-datetime option.
+type BinlogReport struct {
+	File           string                `json:"file"`
+	FirstEventTime time.Time             `json:"first_event_time"`
+	LastEventTime  time.Time             `json:"last_event_time"`
+	FirstPosition  int                   `json:"first_position"` // the start position of the first event
+	LastPosition   int                   `json:"last_position"`  // the start position of the last event
+	EndPosition    int                   `json:"end_position"`   // the end position of the last event
+	PreviousGTIDs  string                `json:"previous_gtids"` // the GTIDs executed before the file
+	GTIDs          string                `json:"gtids"`          // the GTIDs of the transactions in the file
+	Events         map[string]int        `json:"events"`         // event counts by type
+	Rows           map[string]*TableRows `json:"rows"`           // rows changed by table, e.g. orders.items
+}
+
+// Inspect the binlog files of the directory.
+func InspectBinlogs(dir string) ([]*BinlogReport, error) {
+	binlogs, err := listSortedBinlogs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*BinlogReport, 0, len(binlogs))
+	for _, binlog := range binlogs {
+		report, err := InspectBinlog(binlog)
+		if err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, report)
+	}
+
+	if len(reports) == 0 {
+		return nil, ErrBinlogsNotFound
+	}
+
+	return reports, nil
+}
+
+// Inspect a binlog file.
+func InspectBinlog(binlog string) (*BinlogReport, error) {
+	report := &BinlogReport{
+		File:   filepath.Base(binlog),
+		Events: make(map[string]int),
+		Rows:   make(map[string]*TableRows),
+	}
+
+	gtids := &mysql.MysqlGTIDSet{Sets: make(map[string]*mysql.UUIDSet)}
+
+	parser := replication.NewBinlogParser()
+	parser.SetTimestampStringLocation(time.UTC)
+
+	first := true
+	err := parser.ParseFile(binlog, 0, func(e *replication.BinlogEvent) error {
+		start := int(e.Header.LogPos) - int(e.Header.EventSize)
+
+		if first {
+			report.FirstPosition = start
+			first = false
+		}
+
+		report.LastPosition = start
+		report.EndPosition = int(e.Header.LogPos)
+
+		// The events generated by the server, e.g. the fake rotate event, have no timestamp.
+		if e.Header.Timestamp > 0 {
+			eventTime := time.Unix(int64(e.Header.Timestamp), 0).UTC()
+
+			if report.FirstEventTime.IsZero() {
+				report.FirstEventTime = eventTime
+			}
+
+			report.LastEventTime = eventTime
+		}
+
+		switch event := e.Event.(type) {
+		case *replication.PreviousGTIDsEvent:
+			report.PreviousGTIDs = event.GTIDSets
+		case *replication.GTIDEvent:
+			gtid, err := eventGTID(e)
+			if err != nil {
+				return err
+			}
+
+			if gtid != nil {
+				if err := gtids.Update(gtid.String()); err != nil {
+					return fmt.Errorf("invalid GTID %s, error: %v", gtid.String(), err)
+				}
+			}
+		}
+
+		report.countEvent(e)
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse binlog file: %s: %v", binlog, err)
+	}
+
+	report.GTIDs = gtids.String()
+
+	return report, nil
+}
+
+// Count the event and the rows it changed, the events of a compressed transaction are counted as well.
+func (r *BinlogReport) countEvent(e *replication.BinlogEvent) {
+	r.Events[e.Header.EventType.String()]++
+
+	switch event := e.Event.(type) {
+	case *replication.TransactionPayloadEvent:
+		for _, inner := range event.Events {
+			r.countEvent(inner)
+		}
+	case *replication.RowsEvent:
+		if event.Table == nil {
+			return
+		}
+
+		name := string(event.Table.Schema) + "." + string(event.Table.Table)
+		rows, ok := r.Rows[name]
+		if !ok {
+			rows = &TableRows{}
+			r.Rows[name] = rows
+		}
+
+		switch e.Header.EventType {
+		case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+			rows.Inserted += len(event.Rows)
+		case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2, replication.PARTIAL_UPDATE_ROWS_EVENT:
+			// The before and after images of an updated row.
+			rows.Updated += len(event.Rows) / 2
+		case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+			rows.Deleted += len(event.Rows)
+		}
+	}
+}
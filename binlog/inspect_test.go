@@ -0,0 +1,64 @@
+package binlog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectBinlog(t *testing.T) {
+	assert := assert.New(t)
+
+	currentDir, err := os.Getwd()
+	assert.NoError(err)
+
+	report, err := InspectBinlog(filepath.Join(currentDir, "..", "testutils", "mysqlrestore", "binlogs", "mysql-bin.000003"))
+	assert.NoError(err)
+
+	expected := &BinlogReport{
+		File:           "mysql-bin.000003",
+		FirstEventTime: time.Date(2025, 6, 3, 0, 58, 52, 0, time.UTC),
+		LastEventTime:  time.Date(2025, 6, 3, 1, 0, 43, 0, time.UTC),
+		FirstPosition:  4,
+		LastPosition:   2522,
+		EndPosition:    2609,
+		Events: map[string]int{
+			"AnonymousGTIDEvent":     9,
+			"DeleteRowsEventV2":      1,
+			"FormatDescriptionEvent": 1,
+			"PreviousGTIDsEvent":     1,
+			"QueryEvent":             9,
+			"TableMapEvent":          1,
+			"XIDEvent":               1,
+		},
+		Rows: map[string]*TableRows{"mysql.user": {Deleted: 1}},
+	}
+
+	assert.Equal(expected, report)
+}
+
+func TestInspectBinlogs(t *testing.T) {
+	assert := assert.New(t)
+
+	currentDir, err := os.Getwd()
+	assert.NoError(err)
+
+	binlogsDir := filepath.Join(currentDir, "..", "testutils", "mysqlrestore", "binlogs")
+
+	reports, err := InspectBinlogs(binlogsDir)
+	assert.NoError(err)
+	assert.Len(reports, 3)
+	assert.Equal("mysql-bin.000001", reports[0].File)
+	assert.Equal(118015, reports[1].Rows["mysql.time_zone_transition"].Inserted)
+	assert.Equal("mysql-bin.000003", reports[2].File)
+
+	// The files that are not binlogs are skipped.
+	tempDir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(tempDir, "checksum.onedump"), []byte("checksum"), 0644))
+
+	_, err = InspectBinlogs(tempDir)
+	assert.ErrorIs(err, ErrBinlogsNotFound)
+}
@@ -0,0 +1,182 @@
+package binlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ManifestFile = "onedump-binlog-manifest.json" // The default manifest filename, it is saved locally and in the destinations
+
+// The uploaded version of a binlog file.
+type ManifestEntry struct {
+	Name        string     `json:"name"`
+	Size        int64      `json:"size"`   // the size of the binlog file before it is compressed or encrypted
+	SHA256      string     `json:"sha256"` // the checksum of the binlog file before it is compressed or encrypted
+	Active      bool       `json:"active"` // the file was still written by MySQL when it was uploaded, so it is incomplete
+	RemoteName  string     `json:"remote_name,omitempty"`
+	Compression string     `json:"compression,omitempty"`
+	Encrypted   bool       `json:"encrypted,omitempty"`
+	UploadedAt  time.Time  `json:"uploaded_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // the file was deleted from the destinations by the retention
+}
+
+// Get the file name in the destinations, e.g. mysql-bin.000001.zst.enc
+func (e *ManifestEntry) remoteName() string {
+	if e.RemoteName != "" {
+		return e.RemoteName
+	}
+
+	return e.Name
+}
+
+// The manifest tells which version of each binlog file is in the destinations.
+type BinlogManifest struct {
+	mu        sync.Mutex
+	UpdatedAt time.Time        `json:"updated_at"`
+	Files     []*ManifestEntry `json:"files"`
+}
+
+// Load the manifest, it is empty if the file does not exist.
+func loadManifest(manifestFile string) (*BinlogManifest, error) {
+	manifest := &BinlogManifest{}
+
+	content, err := os.ReadFile(manifestFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return manifest, nil
+		}
+
+		return nil, fmt.Errorf("fail to read manifest file: %s, error: %v", manifestFile, err)
+	}
+
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("fail to decode manifest file: %s, error: %v", manifestFile, err)
+	}
+
+	return manifest, nil
+}
+
+func (m *BinlogManifest) get(name string) *ManifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, entry := range m.Files {
+		if entry.Name == name {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// Add or replace the entry of the binlog file.
+func (m *BinlogManifest) set(entry *ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.UpdatedAt = entry.UploadedAt
+
+	index := slices.IndexFunc(m.Files, func(e *ManifestEntry) bool { return e.Name == entry.Name })
+	if index >= 0 {
+		m.Files[index] = entry
+		return
+	}
+
+	m.Files = append(m.Files, entry)
+	slices.SortFunc(m.Files, func(a, b *ManifestEntry) int { return strings.Compare(a.Name, b.Name) })
+}
+
+// Get a snapshot of the entries.
+func (m *BinlogManifest) entries() []*ManifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.Files)
+}
+
+// Mark the file as deleted from the destinations, the entry is kept so the file is not uploaded again.
+func (m *BinlogManifest) markDeleted(name string, deletedAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, entry := range m.Files {
+		if entry.Name == name {
+			entry.DeletedAt = &deletedAt
+			m.UpdatedAt = deletedAt
+		}
+	}
+}
+
+func (m *BinlogManifest) remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Files = slices.DeleteFunc(m.Files, func(e *ManifestEntry) bool { return e.Name == name })
+}
+
+func (m *BinlogManifest) encode() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	encoded, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("fail to encode manifest to json, error: %v", err)
+	}
+
+	return encoded, nil
+}
+
+// Save the manifest to a temporary file and rename it, so it is never partially written.
+func (m *BinlogManifest) save(manifestFile string) error {
+	encoded, err := m.encode()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(manifestFile), 0o755); err != nil {
+		return fmt.Errorf("fail to create manifest directory, error: %v", err)
+	}
+
+	tmp := manifestFile + ".tmp"
+	if err := os.WriteFile(tmp, encoded, 0o644); err != nil {
+		return fmt.Errorf("fail to write manifest file: %s, error: %v", tmp, err)
+	}
+
+	if err := os.Rename(tmp, manifestFile); err != nil {
+		return fmt.Errorf("fail to save manifest file: %s, error: %v", manifestFile, err)
+	}
+
+	return nil
+}
+
+// Compute the sha256 of the first size bytes of the file.
+func computeSHA256(source BinlogSource, filename string, size int64) (string, error) {
+	file, err := source.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("fail to open file: %s, error %v", filename, err)
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			slog.Error("fail to close file.", slog.Any("file", filename), slog.Any("error", err))
+		}
+	}()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, io.LimitReader(file, size)); err != nil {
+		return "", fmt.Errorf("fail to compute sha256 of file: %s, error: %v", filename, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,107 @@
+package binlog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liweiyi88/onedump/filesync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinlogSyncerManifest(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000001"), []byte("closed"), 0644))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000002"), []byte("active+partial"), 0644))
+
+	destination := &memoryStorage{files: make(map[string][]byte)}
+	binlogInfo := &BinlogInfo{currentBinlogFile: "binlog.000002", position: 6, binlogDir: dir, binlogPrefix: "binlog"}
+
+	sync := func(opts ...BinlogSyncerOption) *BinlogManifest {
+		clear(destination.files)
+
+		syncer := NewBinlogSyncer("binlogs", false, "", filesync.NewFileSync(false, ""), binlogInfo, opts...)
+		assert.NoError(syncer.Sync(destination))
+
+		return syncer.manifest
+	}
+
+	t.Run("it should only upload the complete events of the active file", func(t *testing.T) {
+		manifest := sync()
+
+		assert.Equal("closed", string(destination.files["binlogs/binlog.000001"]))
+		assert.Equal("active", string(destination.files["binlogs/binlog.000002"]))
+
+		assert.Len(manifest.Files, 2)
+		assert.False(manifest.get("binlog.000001").Active)
+		assert.Equal(int64(6), manifest.get("binlog.000001").Size)
+		assert.True(manifest.get("binlog.000002").Active)
+		assert.Equal(int64(6), manifest.get("binlog.000002").Size)
+
+		sum, err := computeSHA256(localSource{}, filepath.Join(dir, "binlog.000002"), 6)
+		assert.NoError(err)
+		assert.Equal(sum, manifest.get("binlog.000002").SHA256)
+
+		// The manifest is saved locally and in the destination.
+		local, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+		assert.NoError(err)
+		assert.JSONEq(string(local), string(destination.files["binlogs/"+ManifestFile]))
+	})
+
+	t.Run("it should not upload the files that have not changed", func(t *testing.T) {
+		sync()
+		assert.Empty(destination.files)
+	})
+
+	t.Run("it should upload the active file again if it has grown", func(t *testing.T) {
+		binlogInfo.position = 14
+
+		manifest := sync()
+		assert.Equal("active+partial", string(destination.files["binlogs/binlog.000002"]))
+		assert.NotContains(destination.files, "binlogs/binlog.000001")
+		assert.Equal(int64(14), manifest.get("binlog.000002").Size)
+		assert.True(manifest.get("binlog.000002").Active)
+	})
+
+	t.Run("it should mark the closed file without uploading it again if it has not changed", func(t *testing.T) {
+		binlogInfo.currentBinlogFile, binlogInfo.position = "binlog.000003", 4
+		assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000003"), []byte("next"), 0644))
+
+		manifest := sync(WithSkipActive(true))
+		assert.NotContains(destination.files, "binlogs/binlog.000002")
+		assert.NotContains(destination.files, "binlogs/binlog.000003")
+		assert.False(manifest.get("binlog.000002").Active)
+		assert.Nil(manifest.get("binlog.000003"))
+
+		var saved BinlogManifest
+		assert.NoError(json.Unmarshal(destination.files["binlogs/"+ManifestFile], &saved))
+		assert.Len(saved.Files, 2)
+		assert.False(saved.Files[1].Active)
+	})
+
+	t.Run("it should upload the closed file again if it has grown", func(t *testing.T) {
+		assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000002"), []byte("active+partial+rotate"), 0644))
+
+		manifest := sync(WithSkipActive(true))
+		assert.Equal("active+partial+rotate", string(destination.files["binlogs/binlog.000002"]))
+		assert.Equal(int64(21), manifest.get("binlog.000002").Size)
+		assert.False(manifest.get("binlog.000002").Active)
+	})
+}
+
+func TestLoadManifest(t *testing.T) {
+	assert := assert.New(t)
+
+	manifest, err := loadManifest(filepath.Join(t.TempDir(), ManifestFile))
+	assert.NoError(err)
+	assert.Empty(manifest.Files)
+
+	invalid := filepath.Join(t.TempDir(), ManifestFile)
+	assert.NoError(os.WriteFile(invalid, []byte("{"), 0644))
+
+	_, err = loadManifest(invalid)
+	assert.ErrorContains(err, "fail to decode manifest file")
+}
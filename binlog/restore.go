@@ -2,10 +2,13 @@ package binlog
 
 import (
 	"bufio"
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net"
 	"os"
 	"os/exec"
@@ -17,8 +20,10 @@ import (
 	"strings"
 	"time"
 
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
 	"github.com/go-sql-driver/mysql"
+	"github.com/liweiyi88/onedump/config"
 	"github.com/liweiyi88/onedump/fileutil"
 )
 
@@ -33,12 +38,17 @@ var (
 	ErrEventBeforeStopDatetime = errors.New("event is before stop datetime")
 	ErrStopPositionNotFound    = errors.New("stop position not found")
 	ErrBinlogsNotFound         = errors.New("no binlog files were found in the directory")
+
+	// A fake error to stop applying events natively.
+	errStopPositionReached = errors.New("stop position reached")
 )
 
 type binlogRestorePlan struct {
 	startPosition int
 	stopPosition  *int
 	binlogs       []string
+	excludeGTIDs  *gomysql.MysqlGTIDSet
+	filter        *eventFilter
 }
 
 func newBinlogRestorePlan(startPosition int) *binlogRestorePlan {
@@ -54,9 +64,18 @@ type BinlogRestorer struct {
 	dsn             string
 	mysqlPath       string
 	mysqlbinlogPath string
+	native          bool
 	startBinlog     string
 	startPosition   int
 	stopDateTime    time.Time
+	startGTIDSet    string
+	excludeGTIDs    string
+	stopBeforeGTID  string
+	database        string
+	tables          config.TableFilter
+	rewriteDBs      []string
+	encryptionKey   []byte
+	decodedDir      string // the temp directory of the binlog files decoded from binlogDir
 }
 
 func NewBinlogRestorer(binlogDir string, startBinlog string, startPosition int, opts ...binlogRestoreOption) *BinlogRestorer {
@@ -95,6 +114,13 @@ func WithDryRun(dryRun bool) binlogRestoreOption {
 	}
 }
 
+// Apply the binlog events with the built-in decoder through database/sql instead of mysqlbinlog and mysql.
+func WithNative(native bool) binlogRestoreOption {
+	return func(binlogRestorer *BinlogRestorer) {
+		binlogRestorer.native = native
+	}
+}
+
 func WithStopDateTime(stopDateTime string) binlogRestoreOption {
 	return func(binlogRestorer *BinlogRestorer) {
 		if strings.TrimSpace(stopDateTime) != "" {
@@ -108,6 +134,56 @@ func WithStopDateTime(stopDateTime string) binlogRestoreOption {
 	}
 }
 
+// Skip the transactions of the GTID set, e.g. the gtid_purged of the dump file.
+// If the start binlog is empty, the restore starts from the binlog that follows the GTID set.
+func WithStartGTIDSet(startGTIDSet string) binlogRestoreOption {
+	return func(binlogRestorer *BinlogRestorer) {
+		binlogRestorer.startGTIDSet = startGTIDSet
+	}
+}
+
+// Skip the transactions of the GTID set, e.g. a transaction that dropped a table by mistake.
+func WithExcludeGTIDs(excludeGTIDs string) binlogRestoreOption {
+	return func(binlogRestorer *BinlogRestorer) {
+		binlogRestorer.excludeGTIDs = excludeGTIDs
+	}
+}
+
+// Stop the restore right before the transaction of the GTID.
+func WithStopBeforeGTID(stopBeforeGTID string) binlogRestoreOption {
+	return func(binlogRestorer *BinlogRestorer) {
+		binlogRestorer.stopBeforeGTID = stopBeforeGTID
+	}
+}
+
+// Only restore the events of the database.
+func WithDatabase(database string) binlogRestoreOption {
+	return func(binlogRestorer *BinlogRestorer) {
+		binlogRestorer.database = database
+	}
+}
+
+// Only restore the row changes of the tables, it requires the native mode.
+func WithTables(tables config.TableFilter) binlogRestoreOption {
+	return func(binlogRestorer *BinlogRestorer) {
+		binlogRestorer.tables = tables
+	}
+}
+
+// Restore the events of a database into another database, each rewrite is in the format of from->to.
+func WithRewriteDBs(rewriteDBs []string) binlogRestoreOption {
+	return func(binlogRestorer *BinlogRestorer) {
+		binlogRestorer.rewriteDBs = rewriteDBs
+	}
+}
+
+// Decrypt the binlog files that are encrypted by binlog sync.
+func WithDecryptionKey(key []byte) binlogRestoreOption {
+	return func(binlogRestorer *BinlogRestorer) {
+		binlogRestorer.encryptionKey = key
+	}
+}
+
 func WithDatabaseDSN(dsn string) binlogRestoreOption {
 	return func(binlogRestorer *BinlogRestorer) {
 		binlogRestorer.dsn = dsn
@@ -145,22 +221,61 @@ func extractBinlogNumber(filename string) int64 {
 	return num
 }
 
-func (b *BinlogRestorer) getSortedBinlogs() ([]string, error) {
-	binlogs, err := fileutil.ListFiles(b.binlogDir, "", ".index")
-	if err != nil {
-		return nil, fmt.Errorf("fail to list binlog files from %s, error: %v", b.binlogDir, err)
+// List the binlog files of the directories in the order of their sequence numbers.
+// A binlog file of a later directory replaces the one with the same name of the earlier directories.
+func listSortedBinlogs(dirs ...string) ([]string, error) {
+	files := make(map[string]string)
+
+	for _, dir := range dirs {
+		binlogs, err := fileutil.ListFiles(dir, "", ".index")
+		if err != nil {
+			return nil, fmt.Errorf("fail to list binlog files from %s, error: %v", dir, err)
+		}
+
+		for _, binlog := range binlogs {
+			files[filepath.Base(binlog)] = binlog
+		}
 	}
 
+	binlogs := sortBinlogs(slices.Collect(maps.Values(files)))
 	if len(binlogs) == 0 {
 		return nil, ErrBinlogsNotFound
 	}
 
+	return binlogs, nil
+}
+
+// Sort the binlog files in the order of their sequence numbers.
+// The other files, e.g. the manifest and the checksum file of binlog sync, are skipped.
+func sortBinlogs(files []string) []string {
+	binlogs := slices.DeleteFunc(slices.Clone(files), func(binlog string) bool {
+		return extractBinlogNumber(filepath.Base(binlog)) == 0
+	})
+
 	sort.Slice(binlogs, func(i, j int) bool {
 		a := extractBinlogNumber(binlogs[i])
 		b := extractBinlogNumber(binlogs[j])
 		return a < b
 	})
 
+	return binlogs
+}
+
+func (b *BinlogRestorer) getSortedBinlogs() ([]string, error) {
+	dirs := []string{b.binlogDir}
+	if b.decodedDir != "" {
+		dirs = append(dirs, b.decodedDir)
+	}
+
+	binlogs, err := listSortedBinlogs(dirs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.startBinlog == "" {
+		return b.getBinlogsAfterGTIDSet(binlogs)
+	}
+
 	startIndex := -1
 
 	for i, name := range binlogs {
@@ -177,7 +292,54 @@ func (b *BinlogRestorer) getSortedBinlogs() ([]string, error) {
 	return binlogs[startIndex:], nil
 }
 
+// Get the binlogs from the last one whose previous GTIDs are all in the start GTID set,
+// the transactions before it have been restored by the dump file.
+func (b *BinlogRestorer) getBinlogsAfterGTIDSet(binlogs []string) ([]string, error) {
+	startGTIDSet, err := parseGTIDSet("start-gtid-set", b.startGTIDSet)
+	if err != nil {
+		return nil, err
+	}
+
+	if startGTIDSet == nil {
+		return nil, errors.New("either start binlog or start GTID set is required")
+	}
+
+	for i := len(binlogs) - 1; i >= 0; i-- {
+		previous, err := readPreviousGTIDs(binlogs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if startGTIDSet.Contain(previous) {
+			slog.Debug("found start binlog by GTID set", slog.Any("binlog file", binlogs[i]), slog.Any("previous GTIDs", previous.String()))
+			return binlogs[i:], nil
+		}
+	}
+
+	return nil, fmt.Errorf("the binlogs after GTID set %s are not found, they may have been purged", startGTIDSet.String())
+}
+
 func (b *BinlogRestorer) createBinlogRestorePlan() (*binlogRestorePlan, error) {
+	startGTIDSet, err := parseGTIDSet("start-gtid-set", b.startGTIDSet)
+	if err != nil {
+		return nil, err
+	}
+
+	excludeGTIDs, err := parseGTIDSet("exclude-gtids", b.excludeGTIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	stopBeforeGTID, err := parseGTID(b.stopBeforeGTID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := newEventFilter(b.database, b.tables, b.rewriteDBs)
+	if err != nil {
+		return nil, err
+	}
+
 	binlogs, err := b.getSortedBinlogs()
 	if err != nil {
 		return nil, err
@@ -185,12 +347,52 @@ func (b *BinlogRestorer) createBinlogRestorePlan() (*binlogRestorePlan, error) {
 
 	parser := replication.NewBinlogParser()
 	plan := newBinlogRestorePlan(b.startPosition)
+	plan.filter = filter
+
+	// Without a start binlog, the restore starts from the beginning of the binlog that follows the start GTID set.
+	if b.startBinlog == "" {
+		plan.startPosition = 4
+	}
+
+	// The transactions of the start GTID set have been restored by the dump file, so they are excluded as well.
+	for _, set := range []*gomysql.MysqlGTIDSet{startGTIDSet, excludeGTIDs} {
+		if set == nil {
+			continue
+		}
+
+		if plan.excludeGTIDs == nil {
+			plan.excludeGTIDs = set.Clone().(*gomysql.MysqlGTIDSet)
+			continue
+		}
+
+		if err := plan.excludeGTIDs.Add(*set); err != nil {
+			return nil, fmt.Errorf("fail to merge GTID sets, error: %v", err)
+		}
+	}
 
 	for _, binlog := range binlogs {
 		plan.binlogs = append(plan.binlogs, binlog)
 
-		if !b.stopDateTime.IsZero() {
+		if !b.stopDateTime.IsZero() || stopBeforeGTID != nil {
 			err := parser.ParseFile(binlog, 0, func(e *replication.BinlogEvent) error {
+				if stopBeforeGTID != nil {
+					gtid, err := eventGTID(e)
+					if err != nil {
+						return err
+					}
+
+					// The stop position is exclusive, so the transaction of the GTID is not restored.
+					if gtid != nil && stopBeforeGTID.Contain(gtid) {
+						stopPos := int(e.Header.LogPos - e.Header.EventSize)
+						plan.stopPosition = &stopPos
+						return errStopGTIDFound
+					}
+				}
+
+				if b.stopDateTime.IsZero() {
+					return nil
+				}
+
 				eventTime := time.Unix(int64(e.Header.Timestamp), 0)
 				pos := e.Header.LogPos
 
@@ -213,6 +415,10 @@ func (b *BinlogRestorer) createBinlogRestorePlan() (*binlogRestorePlan, error) {
 			})
 
 			if err != nil {
+				if errors.Is(err, errStopGTIDFound) {
+					return plan, nil
+				}
+
 				if !errors.Is(err, ErrEventBeforeStopDatetime) {
 					return nil, fmt.Errorf("fail to parse binlog file: %s: %v", binlog, err)
 				}
@@ -228,6 +434,11 @@ func (b *BinlogRestorer) createBinlogRestorePlan() (*binlogRestorePlan, error) {
 		}
 	}
 
+	// The transaction to stop before must be in the binlogs, otherwise all transactions would be restored.
+	if stopBeforeGTID != nil {
+		return nil, ErrStopGTIDNotFound
+	}
+
 	// We should find a stop position if we pass a valid value of --stop-datetime option
 	// If we can't find the position, it means the value of stop datetime is before all events from all binlog files.
 	if plan.stopPosition == nil && !b.stopDateTime.IsZero() {
@@ -238,6 +449,25 @@ func (b *BinlogRestorer) createBinlogRestorePlan() (*binlogRestorePlan, error) {
 }
 
 func (b *BinlogRestorer) createRestoreCommandArgs(plan *binlogRestorePlan) []string {
+	args := b.createBinlogPositionArgs(plan)
+
+	var options []string
+	if plan.excludeGTIDs != nil && !plan.excludeGTIDs.IsEmpty() {
+		options = append(options, "--exclude-gtids="+plan.excludeGTIDs.String())
+	}
+
+	options = append(options, plan.filter.mysqlbinlogArgs()...)
+
+	if len(options) > 0 {
+		for i := range args {
+			args[i] = args[i] + " " + strings.Join(options, " ")
+		}
+	}
+
+	return args
+}
+
+func (b *BinlogRestorer) createBinlogPositionArgs(plan *binlogRestorePlan) []string {
 	args := make([]string, 0)
 
 	if len(plan.binlogs) == 0 {
@@ -289,17 +519,70 @@ func (b *BinlogRestorer) createRestoreCommandArgs(plan *binlogRestorePlan) []str
 
 // Use mysqlbinlog to restore data -> if --dry-run just output the content, otherwise pipe it with mysql
 func (b *BinlogRestorer) Restore() error {
-	if err := b.ensureMySQLCommandPaths(); err != nil {
+	if !b.native {
+		if err := b.ensureMySQLCommandPaths(); err != nil {
+			return err
+		}
+	}
+
+	// The binlog files may be compressed and encrypted by binlog sync,
+	// they are decoded into a temp directory, so the files of the binlog directory are left untouched.
+	decodedDir, err := os.MkdirTemp(fileutil.WorkDir(), ".onedump-binlogs-*")
+	if err != nil {
+		return fmt.Errorf("fail to create temp directory for decoded binlog files, error: %v", err)
+	}
+
+	defer func() {
+		b.decodedDir = ""
+
+		if err := os.RemoveAll(decodedDir); err != nil {
+			slog.Error("fail to remove decoded binlog files", slog.Any("error", err), slog.String("dir", decodedDir))
+		}
+	}()
+
+	if _, err := decodeBinlogs(b.binlogDir, decodedDir, b.encryptionKey); err != nil {
 		return err
 	}
 
+	b.decodedDir = decodedDir
+
 	plan, err := b.createBinlogRestorePlan()
 	if err != nil {
 		return fmt.Errorf("fail to create binlog restore plan, error: %v", err)
 	}
 
+	// mysqlbinlog can only filter databases.
+	if !b.native && plan.filter.hasTableFilter() {
+		return errors.New("the table filters require the native mode, use --native option")
+	}
+
+	if b.native {
+		return b.applyNatively(plan, os.Stdout)
+	}
+
 	cmdArgs := b.createRestoreCommandArgs(plan)
 
+	var mysqlArgs []string
+	if !b.dryRun {
+		cfg, err := mysql.ParseDSN(b.dsn)
+		if err != nil {
+			return fmt.Errorf("fail to parse database dsn: %s, error: %v", b.dsn, err)
+		}
+
+		credentialFile, err := createCredentialFile(cfg)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := os.Remove(credentialFile); err != nil {
+				slog.Error("fail to remove mysql credentials file", slog.Any("error", err))
+			}
+		}()
+
+		mysqlArgs = mysqlCommandArgs(credentialFile, cfg.DBName)
+	}
+
 	for _, argsString := range cmdArgs {
 		args := strings.Fields(argsString)
 		mysqlBinlogCmd := exec.Command(b.mysqlbinlogPath, args...)
@@ -319,25 +602,7 @@ func (b *BinlogRestorer) Restore() error {
 				return fmt.Errorf("fail to get restore command std out pipe, error: %v", err)
 			}
 
-			cfg, err := mysql.ParseDSN(b.dsn)
-			if err != nil {
-				return fmt.Errorf("fail to parse database dsn: %s, error: %v", b.dsn, err)
-			}
-
-			host, port, err := net.SplitHostPort(cfg.Addr)
-			if err != nil {
-				host = cfg.Addr
-				port = "3306"
-			}
-
-			mysqlCmd := exec.Command(
-				b.mysqlPath,
-				"-u", cfg.User,
-				fmt.Sprintf("--password=%s", cfg.Passwd),
-				"-h", host,
-				"-P", port,
-				fmt.Sprintf("--database=%s", cfg.DBName),
-			)
+			mysqlCmd := exec.Command(b.mysqlPath, mysqlArgs...)
 			mysqlCmd.Stdin = mysqlBinlogCmdOut
 			mysqlCmd.Stdout = os.Stdout
 			mysqlCmd.Stderr = os.Stderr
@@ -363,6 +628,149 @@ func (b *BinlogRestorer) Restore() error {
 	return nil
 }
 
+// Store the credentials in a temp file for the mysql command.
+// It avoids exposing the password as user can view the whole command via ps aux.
+func createCredentialFile(cfg *mysql.Config) (string, error) {
+	host, port, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		host = cfg.Addr
+		port = "3306"
+	}
+
+	// Values are quoted in option files, so the password can contain any character.
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	contents := fmt.Sprintf("[client]\nuser = \"%s\"\npassword = \"%s\"\nhost = \"%s\"\nport = %s\n",
+		quote.Replace(cfg.User), quote.Replace(cfg.Passwd), quote.Replace(host), port)
+
+	// The temp file is only readable by the current user.
+	file, err := os.CreateTemp(fileutil.WorkDir(), ".mysqlpass")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp mysql credentials file: %w", err)
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			slog.Error("fail to close temp file for storing mysql credentials", slog.Any("error", err))
+		}
+	}()
+
+	if _, err := file.WriteString(contents); err != nil {
+		return "", errors.Join(fmt.Errorf("failed to write credentials to temp file: %w", err), os.Remove(file.Name()))
+	}
+
+	return file.Name(), nil
+}
+
+// The --defaults-extra-file option must be the first argument of the mysql command.
+func mysqlCommandArgs(credentialFile, database string) []string {
+	return []string{
+		"--defaults-extra-file=" + credentialFile,
+		fmt.Sprintf("--database=%s", database),
+	}
+}
+
+// Decode the events of the plan and execute them on the database -> if --dry-run just output the SQL statements.
+func (b *BinlogRestorer) applyNatively(plan *binlogRestorePlan, output io.Writer) error {
+	if len(plan.binlogs) == 0 {
+		slog.Debug("no binlog file is included in restore plan, skip")
+		return nil
+	}
+
+	db, err := sql.Open("mysql", b.dsn)
+	if err != nil {
+		return fmt.Errorf("fail to open database, error: %v", err)
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			slog.Error("fail to close database", slog.Any("error", err))
+		}
+	}()
+
+	// The dry run only queries the table columns if the binlogs do not have them, sql.Open does not connect until then.
+	var target queryExecer = db
+	var statementOutput io.Writer
+
+	if b.dryRun {
+		statementOutput = output
+		if _, err := fmt.Fprintln(output, "DELIMITER /*!*/;"); err != nil {
+			return err
+		}
+	} else {
+		// Session variables and temporary tables must be kept between events, so all events are applied on a single connection.
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			return fmt.Errorf("fail to connect to database, error: %v", err)
+		}
+
+		defer func() {
+			if err := conn.Close(); err != nil {
+				slog.Error("fail to close database connection", slog.Any("error", err))
+			}
+		}()
+
+		target = conn
+	}
+
+	applier := newEventApplier(target, statementOutput)
+	applier.excludeGTIDs = plan.excludeGTIDs
+	applier.filter = plan.filter
+
+	if err := b.applyEvents(plan, applier); err != nil {
+		return err
+	}
+
+	if b.dryRun {
+		if _, err := fmt.Fprintln(output, "DELIMITER ;"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Apply the events of the binlogs in the plan from the start position to the stop position.
+func (b *BinlogRestorer) applyEvents(plan *binlogRestorePlan, applier *eventApplier) error {
+	parser := replication.NewBinlogParser()
+	parser.SetTimestampStringLocation(time.UTC)
+
+	for i, binlog := range plan.binlogs {
+		offset := int64(4)
+		if i == 0 && plan.startPosition > 4 {
+			offset = int64(plan.startPosition)
+		}
+
+		// The stop position is exclusive and only applies to the last binlog, the same as mysqlbinlog.
+		var stopPosition *int
+		if i == len(plan.binlogs)-1 {
+			stopPosition = plan.stopPosition
+		}
+
+		err := parser.ParseFile(binlog, offset, func(e *replication.BinlogEvent) error {
+			if e.Header.EventType == replication.FORMAT_DESCRIPTION_EVENT {
+				return nil
+			}
+
+			start := int(e.Header.LogPos) - int(e.Header.EventSize)
+			if stopPosition != nil && start >= *stopPosition {
+				return errStopPositionReached
+			}
+
+			if err := applier.apply(e); err != nil {
+				return fmt.Errorf("fail to apply event at %s:%d, error: %v", filepath.Base(binlog), start, err)
+			}
+
+			return nil
+		})
+
+		if err != nil && !errors.Is(err, errStopPositionReached) {
+			return err
+		}
+	}
+
+	return applier.close()
+}
+
 // Extracts the binlog file and position from a database dump file.
 // The dump file must be created using mysqldump with the --master-data=2 option.
 func ParseBinlogFilePosition(reader io.Reader) (string, int, error) {
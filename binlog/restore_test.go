@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/liweiyi88/onedump/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -229,6 +231,32 @@ func TestGetRestoreCommands(t *testing.T) {
 		assert.Equal("mysqlbin.000012", commands[2])
 		assert.Equal("mysqlbin.000013 --stop-position=140", commands[3])
 	})
+
+	t.Run("it should exclude the GTIDs in every command", func(t *testing.T) {
+		plan := newBinlogRestorePlan(123)
+		plan.binlogs = []string{"mysqlbin.00001", "mysqlbin.00002"}
+		excludeGTIDs, err := parseGTIDSet("exclude-gtids", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5")
+		assert.NoError(err)
+		plan.excludeGTIDs = excludeGTIDs
+
+		commands := restorer.createRestoreCommandArgs(plan)
+		assert.Len(commands, 2)
+		assert.Equal("mysqlbin.00001 --start-position=123 --exclude-gtids=3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5", commands[0])
+		assert.Equal("mysqlbin.00002 --exclude-gtids=3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5", commands[1])
+	})
+
+	t.Run("it should filter and rewrite the database in every command", func(t *testing.T) {
+		plan := newBinlogRestorePlan(123)
+		plan.binlogs = []string{"mysqlbin.00001", "mysqlbin.00002"}
+		filter, err := newEventFilter("orders", config.TableFilter{}, []string{"orders->orders_recovered"})
+		assert.NoError(err)
+		plan.filter = filter
+
+		commands := restorer.createRestoreCommandArgs(plan)
+		assert.Len(commands, 2)
+		assert.Equal("mysqlbin.00001 --start-position=123 --database=orders_recovered --rewrite-db=orders->orders_recovered", commands[0])
+		assert.Equal("mysqlbin.00002 --database=orders_recovered --rewrite-db=orders->orders_recovered", commands[1])
+	})
 }
 
 func TestWithOptions(t *testing.T) {
@@ -260,6 +288,30 @@ func TestWithOptions(t *testing.T) {
 	assert.Equal(t, expected, restorer)
 }
 
+func TestCreateCredentialFile(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg, err := mysql.ParseDSN(`root:pa"ss\word@tcp(db.example.com:3307)/onedump`)
+	assert.NoError(err)
+
+	credentialFile, err := createCredentialFile(cfg)
+	assert.NoError(err)
+	defer os.Remove(credentialFile)
+
+	content, err := os.ReadFile(credentialFile)
+	assert.NoError(err)
+	assert.Equal("[client]\nuser = \"root\"\npassword = \"pa\\\"ss\\\\word\"\nhost = \"db.example.com\"\nport = 3307\n", string(content))
+
+	info, err := os.Stat(credentialFile)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+	// The password is not in the arguments of the mysql command.
+	args := mysqlCommandArgs(credentialFile, cfg.DBName)
+	assert.Equal([]string{"--defaults-extra-file=" + credentialFile, "--database=onedump"}, args)
+	assert.NotContains(strings.Join(args, " "), cfg.Passwd)
+}
+
 func TestCreateBinlogRestorePlan(t *testing.T) {
 	assert := assert.New(t)
 
@@ -356,6 +408,47 @@ func TestCreateBinlogRestorePlan(t *testing.T) {
 
 		assert.Equal(expected, plan)
 	})
+
+	t.Run("it should merge the start GTID set and the excluded GTIDs", func(t *testing.T) {
+		restorer := NewBinlogRestorer(
+			binlogsDir,
+			"mysql-bin.000003",
+			4,
+			WithStartGTIDSet("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"),
+			WithExcludeGTIDs("3e11fa47-71ca-11e1-9e33-c80aa9429562:7, 8a94f357-aab4-11df-86ab-c80aa9429563:2"),
+		)
+
+		plan, err := restorer.createBinlogRestorePlan()
+		assert.NoError(err)
+		assert.Equal("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:7,8a94f357-aab4-11df-86ab-c80aa9429563:2", plan.excludeGTIDs.String())
+	})
+
+	t.Run("it should start from the binlog after the start GTID set if start binlog is empty", func(t *testing.T) {
+		// The test binlogs are written without gtid_mode, so all of them have empty previous GTIDs.
+		restorer := NewBinlogRestorer(binlogsDir, "", 4, WithStartGTIDSet("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"))
+
+		plan, err := restorer.createBinlogRestorePlan()
+		assert.NoError(err)
+		assert.Equal([]string{filepath.Join(binlogsDir, "mysql-bin.000003")}, plan.binlogs)
+	})
+
+	t.Run("it should return an error if the stop GTID is not found", func(t *testing.T) {
+		restorer := NewBinlogRestorer(binlogsDir, "mysql-bin.000002", 0, WithStopBeforeGTID("3e11fa47-71ca-11e1-9e33-c80aa9429562:23"))
+
+		_, err := restorer.createBinlogRestorePlan()
+		assert.ErrorIs(err, ErrStopGTIDNotFound)
+	})
+
+	t.Run("it should return an error if the GTID options are invalid", func(t *testing.T) {
+		restorer := NewBinlogRestorer(binlogsDir, "mysql-bin.000002", 0, WithStopBeforeGTID("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-3"))
+
+		_, err := restorer.createBinlogRestorePlan()
+		assert.ErrorContains(err, "invalid value of --stop-before-gtid option")
+
+		restorer = NewBinlogRestorer(binlogsDir, "", 0)
+		_, err = restorer.createBinlogRestorePlan()
+		assert.EqualError(err, "either start binlog or start GTID set is required")
+	})
 }
 
 func TestEnsureMySQLCommandPaths(t *testing.T) {
@@ -405,6 +498,21 @@ func TestRestore(t *testing.T) {
 		assert.NoError(err)
 	})
 
+	t.Run("it should require the native mode for table filters", func(t *testing.T) {
+		restorer := NewBinlogRestorer(
+			binlogsDir,
+			"mysql-bin.000003",
+			0,
+			WithDryRun(true),
+			WithMySQLPath(mysqlPath),
+			WithMySQLBinlogPath(mysqlbinlogPath),
+			WithTables(config.TableFilter{Include: []string{"orders"}}),
+		)
+
+		err := restorer.Restore()
+		assert.EqualError(err, "the table filters require the native mode, use --native option")
+	})
+
 	t.Run("it should restore", func(t *testing.T) {
 		if runtime.GOOS == "windows" {
 			// The piping on Window is different from Linux, on CI server the Antivirus/Windows Defender will also slow down the program
@@ -0,0 +1,275 @@
+package binlog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/liweiyi88/onedump/storage"
+)
+
+const PurgeBinaryLogsQuery = "PURGE BINARY LOGS TO '%s';"
+
+// Clean up the binlog files that are safely archived, on the MySQL server and in the destinations.
+type BinlogRetention struct {
+	syncer     *BinlogSyncer
+	db         *sql.DB       // it is only required to purge the binary logs on the server
+	purge      bool          // purge the binary logs on the server that are uploaded and verified by checksum
+	maxAge     time.Duration // delete the archived binlog files that were uploaded before the age
+	dumpBinlog string        // delete the archived binlog files before the binlog file of the oldest retained dump
+	dryRun     bool          // only report the binlog files that would be purged and deleted
+}
+
+type BinlogRetentionOption func(r *BinlogRetention)
+
+func WithPurge(purge bool) BinlogRetentionOption {
+	return func(r *BinlogRetention) {
+		r.purge = purge
+	}
+}
+
+func WithMaxAge(maxAge time.Duration) BinlogRetentionOption {
+	return func(r *BinlogRetention) {
+		r.maxAge = maxAge
+	}
+}
+
+// The binlog file of the oldest retained full dump, the archived binlog files before it are not required by point-in-time recovery.
+func WithDumpBinlog(dumpBinlog string) BinlogRetentionOption {
+	return func(r *BinlogRetention) {
+		r.dumpBinlog = dumpBinlog
+	}
+}
+
+func WithRetentionDryRun(dryRun bool) BinlogRetentionOption {
+	return func(r *BinlogRetention) {
+		r.dryRun = dryRun
+	}
+}
+
+func NewBinlogRetention(syncer *BinlogSyncer, db *sql.DB, opts ...BinlogRetentionOption) *BinlogRetention {
+	retention := &BinlogRetention{
+		syncer: syncer,
+		db:     db,
+	}
+
+	for _, opt := range opts {
+		opt(retention)
+	}
+
+	return retention
+}
+
+// The binlog files that are purged and deleted, or would be in the dry run.
+type RetentionReport struct {
+	DryRun  bool
+	PurgeTo string   // the binary logs before the file are purged on the server
+	Purged  []string // the binary logs that are purged on the server
+	Deleted []string // the archived binlog files that are deleted from the destinations
+}
+
+// Check if any retention is configured.
+func (r *BinlogRetention) Enabled() bool {
+	return r.purge || r.maxAge > 0 || r.dumpBinlog != ""
+}
+
+// Purge the binary logs on the server and delete the expired binlog files from the destinations.
+// It runs after the sync, so it relies on the manifest of the uploaded binlog files.
+func (r *BinlogRetention) Apply(storages ...storage.Storage) (*RetentionReport, error) {
+	if r.purge && r.db == nil {
+		return nil, errors.New("database is required to purge binary logs")
+	}
+
+	if r.syncer.manifest == nil {
+		manifest, err := loadManifest(r.syncer.getManifestFile())
+		if err != nil {
+			return nil, err
+		}
+
+		r.syncer.manifest = manifest
+	}
+
+	report := &RetentionReport{DryRun: r.dryRun}
+
+	if r.purge {
+		purgeTo, purged, err := r.planPurge()
+		if err != nil {
+			return nil, err
+		}
+
+		if purgeTo != "" && !r.dryRun {
+			if _, err := r.db.Exec(fmt.Sprintf(PurgeBinaryLogsQuery, purgeTo)); err != nil {
+				return nil, fmt.Errorf("fail to purge binary logs to %s, error: %v", purgeTo, err)
+			}
+		}
+
+		report.PurgeTo, report.Purged = purgeTo, purged
+	}
+
+	expired := r.expiredEntries(time.Now().UTC())
+	if r.dryRun {
+		for _, entry := range expired {
+			report.Deleted = append(report.Deleted, entry.remoteName())
+		}
+
+		return report, nil
+	}
+
+	var allErrors []error
+	updated := false
+
+	for _, entry := range expired {
+		if err := r.deleteArchived(entry, storages...); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("fail to delete archived binlog file: %s, error: %v", entry.remoteName(), err))
+			continue
+		}
+
+		r.syncer.manifest.markDeleted(entry.Name, time.Now().UTC())
+		report.Deleted = append(report.Deleted, entry.remoteName())
+		updated = true
+	}
+
+	// The entries are not required once the files are deleted from both the server and the destinations.
+	for _, entry := range r.syncer.manifest.entries() {
+		if entry.DeletedAt == nil {
+			continue
+		}
+
+		if _, err := r.syncer.getSource().Stat(path.Join(r.syncer.binlogDir, entry.Name)); errors.Is(err, os.ErrNotExist) {
+			r.syncer.manifest.remove(entry.Name)
+			updated = true
+		}
+	}
+
+	if updated {
+		if err := r.syncer.saveManifest(storages...); err != nil {
+			allErrors = append(allErrors, err)
+		}
+	}
+
+	return report, errors.Join(allErrors...)
+}
+
+// Get the binlog file to purge to, all the binlog files before it are uploaded and verified by checksum.
+// The active binlog file is never purged.
+func (r *BinlogRetention) planPurge() (string, []string, error) {
+	files, err := r.syncer.getSource().List(r.syncer.binlogDir, r.syncer.binlogPrefix+".*")
+	if err != nil {
+		return "", nil, fmt.Errorf("fail to list binlog files from %s, error: %v", r.syncer.binlogDir, err)
+	}
+
+	var purged []string
+	for _, binlog := range sortBinlogs(files) {
+		name := filepath.Base(binlog)
+
+		verified, err := r.isVerified(binlog)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if r.syncer.isActive(binlog) || !verified {
+			if len(purged) == 0 {
+				return "", nil, nil
+			}
+
+			return name, purged, nil
+		}
+
+		purged = append(purged, name)
+	}
+
+	// There is no later binlog file to purge to.
+	return "", nil, nil
+}
+
+// Check if the closed binlog file is uploaded and the local file matches the checksum of the upload.
+func (r *BinlogRetention) isVerified(filename string) (bool, error) {
+	if r.syncer.isActive(filename) {
+		return false, nil
+	}
+
+	entry := r.syncer.manifest.get(filepath.Base(filename))
+	if entry == nil || entry.Active {
+		return false, nil
+	}
+
+	info, err := r.syncer.getSource().Stat(filename)
+	if err != nil {
+		return false, fmt.Errorf("fail to get file stat: %s, error %v", filename, err)
+	}
+
+	if info.Size() != entry.Size {
+		return false, nil
+	}
+
+	sum, err := computeSHA256(r.syncer.getSource(), filename, entry.Size)
+	if err != nil {
+		return false, err
+	}
+
+	return sum == entry.SHA256, nil
+}
+
+// Get the archived binlog files that are expired by any of the configured rules.
+func (r *BinlogRetention) expiredEntries(now time.Time) []*ManifestEntry {
+	if r.maxAge <= 0 && r.dumpBinlog == "" {
+		return nil
+	}
+
+	var expired []*ManifestEntry
+	for _, entry := range r.syncer.manifest.entries() {
+		if entry.Active || entry.DeletedAt != nil || r.syncer.isActive(entry.Name) {
+			continue
+		}
+
+		// The index file is uploaded as well, it is not expired.
+		if extractBinlogNumber(entry.Name) == 0 {
+			continue
+		}
+
+		tooOld := r.maxAge > 0 && now.Sub(entry.UploadedAt) >= r.maxAge
+		beforeDump := r.dumpBinlog != "" && isBinlogBefore(entry.Name, r.dumpBinlog)
+
+		if tooOld || beforeDump {
+			expired = append(expired, entry)
+		}
+	}
+
+	return expired
+}
+
+// Check if the binlog file is before the other binlog file of the same sequence.
+func isBinlogBefore(name, other string) bool {
+	prefix := strings.TrimSuffix(name, filepath.Ext(name))
+	if prefix != strings.TrimSuffix(other, filepath.Ext(other)) {
+		return false
+	}
+
+	return extractBinlogNumber(name) < extractBinlogNumber(other)
+}
+
+func (r *BinlogRetention) deleteArchived(entry *ManifestEntry, storages ...storage.Storage) error {
+	pathGenerator := func(folder string) string {
+		return path.Join(folder, r.syncer.destinationPath, entry.remoteName())
+	}
+
+	var allErrors []error
+	for _, destination := range storages {
+		deleter, ok := destination.(storage.Deleter)
+		if !ok {
+			allErrors = append(allErrors, fmt.Errorf("destination %T does not support deleting files", destination))
+			continue
+		}
+
+		if err := deleter.Delete(pathGenerator); err != nil {
+			allErrors = append(allErrors, err)
+		}
+	}
+
+	return errors.Join(allErrors...)
+}
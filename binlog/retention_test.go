@@ -0,0 +1,194 @@
+package binlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/filesync"
+	"github.com/liweiyi88/onedump/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type saveOnlyStorage struct{}
+
+func (s *saveOnlyStorage) Save(reader io.Reader, pathGenerator storage.PathGeneratorFunc) error {
+	_, err := io.Copy(io.Discard, reader)
+	return err
+}
+
+func TestBinlogRetention(t *testing.T) {
+	setup := func(t *testing.T) (*BinlogSyncer, *memoryStorage) {
+		dir := t.TempDir()
+		for i, content := range []string{"first", "second", "third", "active"} {
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("binlog.%06d", i+1)), []byte(content), 0644))
+		}
+
+		destination := &memoryStorage{files: make(map[string][]byte)}
+		syncer := NewBinlogSyncer("binlogs", false, "", filesync.NewFileSync(false, ""), &BinlogInfo{
+			currentBinlogFile: "binlog.000004",
+			binlogDir:         dir,
+			binlogPrefix:      "binlog",
+		}, WithSkipActive(true))
+
+		assert.NoError(t, syncer.Sync(destination))
+		assert.Len(t, syncer.manifest.Files, 3)
+
+		return syncer, destination
+	}
+
+	// Pretend the files were uploaded a while ago.
+	age := func(syncer *BinlogSyncer, names ...string) {
+		for _, name := range names {
+			syncer.manifest.get(name).UploadedAt = time.Now().UTC().Add(-48 * time.Hour)
+		}
+	}
+
+	t.Run("it should purge the binary logs that are uploaded and verified", func(t *testing.T) {
+		assert := assert.New(t)
+		syncer, destination := setup(t)
+
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(err)
+		defer db.Close()
+
+		mock.ExpectExec("PURGE BINARY LOGS TO 'binlog.000004';").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		report, err := NewBinlogRetention(syncer, db, WithPurge(true)).Apply(destination)
+		assert.NoError(err)
+		assert.Equal("binlog.000004", report.PurgeTo)
+		assert.Equal([]string{"binlog.000001", "binlog.000002", "binlog.000003"}, report.Purged)
+		assert.Empty(report.Deleted)
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not purge the binary logs after a file that does not match the checksum", func(t *testing.T) {
+		assert := assert.New(t)
+		syncer, destination := setup(t)
+
+		assert.NoError(os.WriteFile(filepath.Join(syncer.binlogDir, "binlog.000002"), []byte("SECOND"), 0644))
+
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(err)
+		defer db.Close()
+
+		mock.ExpectExec("PURGE BINARY LOGS TO 'binlog.000002';").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		report, err := NewBinlogRetention(syncer, db, WithPurge(true)).Apply(destination)
+		assert.NoError(err)
+		assert.Equal([]string{"binlog.000001"}, report.Purged)
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should delete the archived files older than the max age", func(t *testing.T) {
+		assert := assert.New(t)
+		syncer, destination := setup(t)
+		age(syncer, "binlog.000001", "binlog.000002")
+
+		report, err := NewBinlogRetention(syncer, nil, WithMaxAge(24*time.Hour)).Apply(destination)
+		assert.NoError(err)
+		assert.Equal([]string{"binlog.000001", "binlog.000002"}, report.Deleted)
+
+		_, ok := destination.get("binlogs/binlog.000001")
+		assert.False(ok)
+		_, ok = destination.get("binlogs/binlog.000003")
+		assert.True(ok)
+
+		assert.NotNil(syncer.manifest.get("binlog.000001").DeletedAt)
+		assert.Nil(syncer.manifest.get("binlog.000003").DeletedAt)
+
+		// The deleted files are not uploaded again.
+		assert.NoError(syncer.Sync(destination))
+		_, ok = destination.get("binlogs/binlog.000001")
+		assert.False(ok)
+
+		// The entry is removed once the file is purged on the server.
+		assert.NoError(os.Remove(filepath.Join(syncer.binlogDir, "binlog.000001")))
+		_, err = NewBinlogRetention(syncer, nil, WithMaxAge(24*time.Hour)).Apply(destination)
+		assert.NoError(err)
+		assert.Nil(syncer.manifest.get("binlog.000001"))
+		assert.NotNil(syncer.manifest.get("binlog.000002"))
+	})
+
+	t.Run("it should delete the archived files before the binlog of the oldest dump", func(t *testing.T) {
+		assert := assert.New(t)
+		syncer, destination := setup(t)
+
+		report, err := NewBinlogRetention(syncer, nil, WithDumpBinlog("binlog.000002")).Apply(destination)
+		assert.NoError(err)
+		assert.Equal([]string{"binlog.000001"}, report.Deleted)
+	})
+
+	t.Run("it should delete the archived files expired by either the max age or the oldest dump", func(t *testing.T) {
+		assert := assert.New(t)
+		syncer, destination := setup(t)
+		age(syncer, "binlog.000003")
+
+		report, err := NewBinlogRetention(syncer, nil, WithMaxAge(24*time.Hour), WithDumpBinlog("binlog.000002")).Apply(destination)
+		assert.NoError(err)
+		assert.Equal([]string{"binlog.000001", "binlog.000003"}, report.Deleted)
+
+		_, ok := destination.get("binlogs/binlog.000002")
+		assert.True(ok)
+	})
+
+	t.Run("it should only report the files in the dry run", func(t *testing.T) {
+		assert := assert.New(t)
+		syncer, destination := setup(t)
+		age(syncer, "binlog.000001")
+
+		db, mock, err := sqlmock.New()
+		assert.NoError(err)
+		defer db.Close()
+
+		report, err := NewBinlogRetention(syncer, db, WithPurge(true), WithMaxAge(24*time.Hour), WithRetentionDryRun(true)).Apply(destination)
+		assert.NoError(err)
+		assert.True(report.DryRun)
+		assert.Equal("binlog.000004", report.PurgeTo)
+		assert.Equal([]string{"binlog.000001"}, report.Deleted)
+
+		_, ok := destination.get("binlogs/binlog.000001")
+		assert.True(ok)
+		assert.Nil(syncer.manifest.get("binlog.000001").DeletedAt)
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should record the remote name of the deleted files", func(t *testing.T) {
+		assert := assert.New(t)
+		syncer, _ := setup(t)
+		syncer.encoding = fileEncoding{compression: CompressionGzip}
+
+		destination := &memoryStorage{files: make(map[string][]byte)}
+		syncer.manifest = nil
+		assert.NoError(os.Remove(syncer.getManifestFile()))
+		assert.NoError(syncer.Sync(destination))
+		age(syncer, "binlog.000001")
+
+		report, err := NewBinlogRetention(syncer, nil, WithMaxAge(24*time.Hour)).Apply(destination)
+		assert.NoError(err)
+		assert.Equal([]string{"binlog.000001.gz"}, report.Deleted)
+
+		_, ok := destination.get("binlogs/binlog.000001.gz")
+		assert.False(ok)
+	})
+
+	t.Run("it should return an error if a destination does not support deleting files", func(t *testing.T) {
+		syncer, _ := setup(t)
+		age(syncer, "binlog.000001")
+
+		_, err := NewBinlogRetention(syncer, nil, WithMaxAge(24*time.Hour)).Apply(&saveOnlyStorage{})
+		assert.ErrorContains(t, err, "does not support deleting files")
+		assert.Nil(t, syncer.manifest.get("binlog.000001").DeletedAt)
+	})
+
+	t.Run("it should require a database to purge binary logs", func(t *testing.T) {
+		syncer, destination := setup(t)
+
+		_, err := NewBinlogRetention(syncer, nil, WithPurge(true)).Apply(destination)
+		assert.EqualError(t, err, "database is required to purge binary logs")
+	})
+}
@@ -0,0 +1,260 @@
+package binlog
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+// Column names, signedness and primary key of a table, they are required to turn row images into SQL.
+type tableInfo struct {
+	columns    []string
+	unsigned   map[int]bool
+	primaryKey []int
+}
+
+// Get the SQL statements of a rows event, an INSERT statement for all rows of a write event,
+// or an UPDATE/DELETE statement per row that matches the before image by the primary key if possible.
+func rowsStatements(event *replication.RowsEvent, eventType replication.EventType, table *tableInfo, name string) ([]string, error) {
+	if eventType == replication.PARTIAL_UPDATE_ROWS_EVENT {
+		return nil, fmt.Errorf("partial JSON updates of table %s are not supported, set binlog_row_value_options to empty", name)
+	}
+
+	if len(table.columns) != int(event.ColumnCount) {
+		return nil, fmt.Errorf("table %s has %d columns, but the rows event has %d columns", name, len(table.columns), event.ColumnCount)
+	}
+
+	switch eventType {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		statement, err := insertStatement(event, table, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{statement}, nil
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		if len(event.Rows)%2 != 0 {
+			return nil, fmt.Errorf("update rows event of table %s has an odd number of row images", name)
+		}
+
+		statements := make([]string, 0, len(event.Rows)/2)
+		for i := 0; i < len(event.Rows); i += 2 {
+			where, err := whereClause(event, table, i)
+			if err != nil {
+				return nil, err
+			}
+
+			var assignments []string
+			for _, column := range presentColumns(event, i+1) {
+				value, err := formatValue(event, table, i+1, column)
+				if err != nil {
+					return nil, err
+				}
+
+				assignments = append(assignments, sqlutil.QuoteIdentifier(table.columns[column])+"="+value)
+			}
+
+			statements = append(statements, fmt.Sprintf("UPDATE %s SET %s WHERE %s LIMIT 1", name, strings.Join(assignments, ", "), where))
+		}
+
+		return statements, nil
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+		statements := make([]string, 0, len(event.Rows))
+		for i := range event.Rows {
+			where, err := whereClause(event, table, i)
+			if err != nil {
+				return nil, err
+			}
+
+			statements = append(statements, fmt.Sprintf("DELETE FROM %s WHERE %s LIMIT 1", name, where))
+		}
+
+		return statements, nil
+	default:
+		return nil, fmt.Errorf("unsupported rows event type: %s", eventType)
+	}
+}
+
+func insertStatement(event *replication.RowsEvent, table *tableInfo, name string) (string, error) {
+	if len(event.Rows) == 0 {
+		return "", fmt.Errorf("write rows event of table %s has no rows", name)
+	}
+
+	// All rows of an event have the same columns.
+	columns := presentColumns(event, 0)
+
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, sqlutil.QuoteIdentifier(table.columns[column]))
+	}
+
+	rows := make([]string, 0, len(event.Rows))
+	for i := range event.Rows {
+		values := make([]string, 0, len(columns))
+		for _, column := range columns {
+			value, err := formatValue(event, table, i, column)
+			if err != nil {
+				return "", err
+			}
+
+			values = append(values, value)
+		}
+
+		rows = append(rows, "("+strings.Join(values, ",")+")")
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", name, strings.Join(names, ","), strings.Join(rows, ",")), nil
+}
+
+// Match the before image by the primary key, or by all columns of the image if the table has no primary key
+// or the image does not contain it.
+func whereClause(event *replication.RowsEvent, table *tableInfo, row int) (string, error) {
+	columns := presentColumns(event, row)
+
+	if len(table.primaryKey) > 0 {
+		present := true
+		for _, column := range table.primaryKey {
+			if !isPresent(event, row, column) {
+				present = false
+				break
+			}
+		}
+
+		if present {
+			columns = table.primaryKey
+		}
+	}
+
+	conditions := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if event.Rows[row][column] == nil {
+			conditions = append(conditions, sqlutil.QuoteIdentifier(table.columns[column])+" IS NULL")
+			continue
+		}
+
+		value, err := formatValue(event, table, row, column)
+		if err != nil {
+			return "", err
+		}
+
+		conditions = append(conditions, sqlutil.QuoteIdentifier(table.columns[column])+"="+value)
+	}
+
+	return strings.Join(conditions, " AND "), nil
+}
+
+// Get the columns in the row image, the columns that are not logged (e.g. binlog_row_image=MINIMAL) are skipped.
+func presentColumns(event *replication.RowsEvent, row int) []int {
+	columns := make([]int, 0, event.ColumnCount)
+	for column := range int(event.ColumnCount) {
+		if isPresent(event, row, column) {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns
+}
+
+func isPresent(event *replication.RowsEvent, row, column int) bool {
+	if row >= len(event.SkippedColumns) {
+		return true
+	}
+
+	for _, skipped := range event.SkippedColumns[row] {
+		if skipped == column {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Format a decoded value as a SQL literal. Strings are binary literals, so they are restored byte by byte
+// regardless of the connection character set.
+func formatValue(event *replication.RowsEvent, table *tableInfo, row, column int) (string, error) {
+	columnType := event.Table.ColumnType[column]
+	unsigned := table.unsigned[column]
+
+	switch v := event.Rows[row][column].(type) {
+	case nil:
+		return "NULL", nil
+	case int8:
+		if unsigned {
+			return strconv.FormatUint(uint64(uint8(v)), 10), nil
+		}
+
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		if unsigned {
+			return strconv.FormatUint(uint64(uint16(v)), 10), nil
+		}
+
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		if unsigned && columnType == mysql.MYSQL_TYPE_INT24 {
+			return strconv.FormatUint(uint64(uint32(v)&0xFFFFFF), 10), nil
+		}
+
+		if unsigned {
+			return strconv.FormatUint(uint64(uint32(v)), 10), nil
+		}
+
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		// ENUM and SET values are their indexes, they are the same as the values in a numeric context.
+		if unsigned || columnType == mysql.MYSQL_TYPE_BIT {
+			return strconv.FormatUint(uint64(v), 10), nil
+		}
+
+		return strconv.FormatInt(v, 10), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case string:
+		switch columnType {
+		case mysql.MYSQL_TYPE_NEWDECIMAL, mysql.MYSQL_TYPE_DECIMAL:
+			return v, nil
+		case mysql.MYSQL_TYPE_JSON:
+			return "CAST(" + stringLiteral("_utf8mb4", []byte(v)) + " AS JSON)", nil
+		case mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_VAR_STRING, mysql.MYSQL_TYPE_STRING:
+			return stringLiteral("_binary", []byte(v)), nil
+		default:
+			// Temporal values.
+			return "'" + v + "'", nil
+		}
+	case []byte:
+		if columnType == mysql.MYSQL_TYPE_JSON {
+			return "CAST(" + stringLiteral("_utf8mb4", v) + " AS JSON)", nil
+		}
+
+		return stringLiteral("_binary", v), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T of column %s", v, table.columns[column])
+	}
+}
+
+// Quote the printable ASCII strings for readability, the others are hex literals.
+func stringLiteral(introducer string, value []byte) string {
+	printable := true
+	for _, b := range value {
+		if b < 0x20 || b > 0x7e || b == '\\' {
+			printable = false
+			break
+		}
+	}
+
+	if printable {
+		return introducer + "'" + strings.ReplaceAll(string(value), "'", "''") + "'"
+	}
+
+	return introducer + " X'" + strings.ToUpper(hex.EncodeToString(value)) + "'"
+}
@@ -0,0 +1,137 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowsStatements(t *testing.T) {
+	assert := assert.New(t)
+
+	tableMap := &replication.TableMapEvent{
+		ColumnType: []byte{mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_BLOB, mysql.MYSQL_TYPE_TINY},
+	}
+
+	table := &tableInfo{
+		columns:    []string{"id", "name", "avatar", "age"},
+		unsigned:   map[int]bool{3: true},
+		primaryKey: []int{0},
+	}
+
+	name := "`shop`.`users`"
+
+	t.Run("it should insert all rows of a write rows event", func(t *testing.T) {
+		event := &replication.RowsEvent{
+			Table:       tableMap,
+			ColumnCount: 4,
+			Rows: [][]any{
+				{int32(1), "john", []byte{0x00, 0xff}, int8(-56)},
+				{int32(2), "it's", nil, int8(20)},
+			},
+		}
+
+		statements, err := rowsStatements(event, replication.WRITE_ROWS_EVENTv2, table, name)
+		assert.NoError(err)
+		assert.Equal([]string{
+			"INSERT INTO `shop`.`users` (`id`,`name`,`avatar`,`age`) VALUES (1,_binary'john',_binary X'00FF',200),(2,_binary'it''s',NULL,20)",
+		}, statements)
+	})
+
+	t.Run("it should update rows by primary key", func(t *testing.T) {
+		event := &replication.RowsEvent{
+			Table:       tableMap,
+			ColumnCount: 4,
+			Rows: [][]any{
+				{int32(1), "john", nil, int8(20)},
+				{int32(1), "jane", nil, int8(21)},
+			},
+		}
+
+		statements, err := rowsStatements(event, replication.UPDATE_ROWS_EVENTv2, table, name)
+		assert.NoError(err)
+		assert.Equal([]string{
+			"UPDATE `shop`.`users` SET `id`=1, `name`=_binary'jane', `avatar`=NULL, `age`=21 WHERE `id`=1 LIMIT 1",
+		}, statements)
+	})
+
+	t.Run("it should delete rows by all columns if the table has no primary key", func(t *testing.T) {
+		event := &replication.RowsEvent{
+			Table:       tableMap,
+			ColumnCount: 4,
+			Rows: [][]any{
+				{int32(1), "john", nil, int8(20)},
+			},
+		}
+
+		statements, err := rowsStatements(event, replication.DELETE_ROWS_EVENTv2, &tableInfo{columns: table.columns}, name)
+		assert.NoError(err)
+		assert.Equal([]string{
+			"DELETE FROM `shop`.`users` WHERE `id`=1 AND `name`=_binary'john' AND `avatar` IS NULL AND `age`=20 LIMIT 1",
+		}, statements)
+	})
+
+	t.Run("it should skip the columns that are not in a minimal row image", func(t *testing.T) {
+		event := &replication.RowsEvent{
+			Table:          tableMap,
+			ColumnCount:    4,
+			Rows:           [][]any{{int32(1), nil, nil, nil}, {nil, "jane", nil, nil}},
+			SkippedColumns: [][]int{{1, 2, 3}, {0, 2, 3}},
+		}
+
+		statements, err := rowsStatements(event, replication.UPDATE_ROWS_EVENTv2, table, name)
+		assert.NoError(err)
+		assert.Equal([]string{"UPDATE `shop`.`users` SET `name`=_binary'jane' WHERE `id`=1 LIMIT 1"}, statements)
+	})
+
+	t.Run("it should return error if the columns do not match the event", func(t *testing.T) {
+		event := &replication.RowsEvent{Table: tableMap, ColumnCount: 5, Rows: [][]any{{1, 2, 3, 4, 5}}}
+
+		_, err := rowsStatements(event, replication.WRITE_ROWS_EVENTv2, table, name)
+		assert.EqualError(err, "table `shop`.`users` has 4 columns, but the rows event has 5 columns")
+	})
+
+	t.Run("it should return error for partial JSON updates", func(t *testing.T) {
+		event := &replication.RowsEvent{Table: tableMap, ColumnCount: 4}
+
+		_, err := rowsStatements(event, replication.PARTIAL_UPDATE_ROWS_EVENT, table, name)
+		assert.EqualError(err, "partial JSON updates of table `shop`.`users` are not supported, set binlog_row_value_options to empty")
+	})
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		columnType byte
+		unsigned   bool
+		value      any
+		expected   string
+	}{
+		{mysql.MYSQL_TYPE_SHORT, true, int16(-1), "65535"},
+		{mysql.MYSQL_TYPE_INT24, true, int32(-1), "16777215"},
+		{mysql.MYSQL_TYPE_LONG, false, int32(-1), "-1"},
+		{mysql.MYSQL_TYPE_LONGLONG, true, int64(-1), "18446744073709551615"},
+		{mysql.MYSQL_TYPE_BIT, false, int64(-1), "18446744073709551615"},
+		{mysql.MYSQL_TYPE_YEAR, false, 2025, "2025"},
+		{mysql.MYSQL_TYPE_FLOAT, false, float32(0.1), "0.1"},
+		{mysql.MYSQL_TYPE_DOUBLE, false, 1e20, "1e+20"},
+		{mysql.MYSQL_TYPE_NEWDECIMAL, false, "-12.50", "-12.50"},
+		{mysql.MYSQL_TYPE_DATETIME2, false, "2025-06-03 00:58:49.123", "'2025-06-03 00:58:49.123'"},
+		{mysql.MYSQL_TYPE_STRING, false, "back\\slash", "_binary X'6261636B5C736C617368'"},
+		{mysql.MYSQL_TYPE_VARCHAR, false, "café", "_binary X'636166C3A9'"},
+		{mysql.MYSQL_TYPE_JSON, false, `{"a": "it's"}`, `CAST(_utf8mb4'{"a": "it''s"}' AS JSON)`},
+		{mysql.MYSQL_TYPE_GEOMETRY, false, []byte{0x01}, "_binary X'01'"},
+	}
+
+	for _, tt := range tests {
+		event := &replication.RowsEvent{
+			Table: &replication.TableMapEvent{ColumnType: []byte{tt.columnType}},
+			Rows:  [][]any{{tt.value}},
+		}
+
+		value, err := formatValue(event, &tableInfo{columns: []string{"c"}, unsigned: map[int]bool{0: tt.unsigned}}, 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, value)
+	}
+}
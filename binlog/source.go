@@ -0,0 +1,296 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/liweiyi88/onedump/dumper/dialer"
+	"github.com/liweiyi88/onedump/fileutil"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// The base delay before reconnecting to the remote host, it doubles after each attempt.
+const sftpSourceRetryDelay = 5 * time.Second
+
+// Where the binlog files are read from, the local disk or the database host.
+type BinlogSource interface {
+	List(dir, pattern string) ([]string, error)
+	Stat(filename string) (os.FileInfo, error)
+	Open(filename string) (io.ReadSeekCloser, error)
+}
+
+// Read the binlog files from the local disk, onedump runs on the database host.
+type localSource struct{}
+
+func (localSource) List(dir, pattern string) ([]string, error) {
+	return fileutil.ListFiles(dir, pattern, "")
+}
+
+func (localSource) Stat(filename string) (os.FileInfo, error) {
+	return os.Stat(filename)
+}
+
+func (localSource) Open(filename string) (io.ReadSeekCloser, error) {
+	return os.Open(filename)
+}
+
+// Read the binlog files from the database host over SFTP, so one backup host can archive the binlogs of many database servers.
+// The connection is shared by the transfers, it is reconnected if it fails and the transfers resume from their offsets.
+type SftpSource struct {
+	mu          sync.Mutex
+	ssh         *dialer.Ssh
+	conn        *ssh.Client
+	client      *sftp.Client
+	maxAttempts int // by default it is 0, infinite retries
+	retryDelay  time.Duration
+}
+
+func NewSftpSource(host, user, key string, maxAttempts int) *SftpSource {
+	return &SftpSource{
+		ssh:         dialer.NewSsh(host, key, user),
+		maxAttempts: maxAttempts,
+		retryDelay:  sftpSourceRetryDelay,
+	}
+}
+
+func (s *SftpSource) getClient() (*sftp.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	conn, err := s.ssh.CreateSshClient()
+	if err != nil {
+		return nil, fmt.Errorf("fail to create ssh connection, error: %v", err)
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("fail to create sftp client, error: %v", err), conn.Close())
+	}
+
+	s.conn, s.client = conn, client
+
+	return client, nil
+}
+
+// Drop the failed connection, the next operation reconnects.
+// The connection may have been replaced by another transfer already.
+func (s *SftpSource) reset(client *sftp.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != client {
+		return
+	}
+
+	s.closeConnection()
+}
+
+func (s *SftpSource) closeConnection() {
+	if s.client == nil {
+		return
+	}
+
+	if err := s.client.Close(); err != nil {
+		slog.Debug("[sftp] fail to close sftp client", slog.Any("error", err))
+	}
+
+	if err := s.conn.Close(); err != nil {
+		slog.Debug("[sftp] fail to close ssh connection", slog.Any("error", err))
+	}
+
+	s.conn, s.client = nil, nil
+}
+
+func (s *SftpSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeConnection()
+	return nil
+}
+
+// Run the operation and retry it with a new connection if it fails.
+// The missing files and the permission errors are not retried.
+func (s *SftpSource) retry(operation string, fn func(client *sftp.Client) error) error {
+	for attempt := 1; ; attempt++ {
+		client, err := s.getClient()
+		if err == nil {
+			err = fn(client)
+			if err == nil || errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+				return err
+			}
+
+			s.reset(client)
+		}
+
+		if s.maxAttempts > 0 && attempt >= s.maxAttempts {
+			return fmt.Errorf("[sftp] fail to %s after %d attempts, error: %v", operation, attempt, err)
+		}
+
+		delay := min(s.retryDelay*(1<<(attempt-1)), time.Minute)
+		slog.Warn("[sftp] retrying", slog.String("operation", operation), slog.Int("attempt", attempt), slog.Duration("delay", delay), slog.Any("error", err))
+		time.Sleep(delay)
+	}
+}
+
+func (s *SftpSource) List(dir, pattern string) ([]string, error) {
+	var files []string
+
+	err := s.retry("list "+dir, func(client *sftp.Client) error {
+		entries, err := client.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		files = files[:0]
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			if pattern != "" {
+				matched, err := filepath.Match(pattern, entry.Name())
+				if err != nil {
+					return fmt.Errorf("invalid pattern: %w", err)
+				}
+
+				if !matched {
+					continue
+				}
+			}
+
+			files = append(files, path.Join(dir, entry.Name()))
+		}
+
+		return nil
+	})
+
+	slices.Sort(files)
+	return files, err
+}
+
+func (s *SftpSource) Stat(filename string) (os.FileInfo, error) {
+	var info os.FileInfo
+
+	err := s.retry("stat "+filename, func(client *sftp.Client) error {
+		var err error
+		info, err = client.Stat(filename)
+		return err
+	})
+
+	return info, err
+}
+
+// Open the remote file, it is opened lazily by the first read.
+func (s *SftpSource) Open(filename string) (io.ReadSeekCloser, error) {
+	return &sftpSourceFile{source: s, filename: filename}, nil
+}
+
+// A remote file that resumes from its offset if the connection fails during a read.
+type sftpSourceFile struct {
+	source   *SftpSource
+	filename string
+	file     *sftp.File
+	client   *sftp.Client // the connection of the opened file
+	offset   int64
+}
+
+func (f *sftpSourceFile) Read(p []byte) (int, error) {
+	var n int
+	var readErr error
+
+	err := f.source.retry("read "+f.filename, func(client *sftp.Client) error {
+		// The connection has been replaced after another transfer failed.
+		if f.client != client {
+			f.closeFile()
+		}
+
+		if f.file == nil {
+			file, err := client.Open(f.filename)
+			if err != nil {
+				return err
+			}
+
+			if _, err := file.Seek(f.offset, io.SeekStart); err != nil {
+				return errors.Join(err, file.Close())
+			}
+
+			f.file, f.client = file, client
+		}
+
+		var err error
+		n, err = f.file.Read(p)
+		f.offset += int64(n)
+
+		if err == nil || errors.Is(err, io.EOF) {
+			readErr = err
+			return nil
+		}
+
+		f.closeFile()
+
+		// Return the content that has been read, the next read reopens the file.
+		if n > 0 {
+			return nil
+		}
+
+		return err
+	})
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, readErr
+}
+
+func (f *sftpSourceFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	default:
+		return 0, fmt.Errorf("[sftp] unsupported seek whence: %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errors.New("[sftp] negative seek offset")
+	}
+
+	f.offset = offset
+
+	// The file is reopened at the offset by the next read.
+	f.closeFile()
+
+	return offset, nil
+}
+
+func (f *sftpSourceFile) closeFile() {
+	if f.file == nil {
+		return
+	}
+
+	if err := f.file.Close(); err != nil {
+		slog.Debug("[sftp] fail to close remote file", slog.String("file", f.filename), slog.Any("error", err))
+	}
+
+	f.file, f.client = nil, nil
+}
+
+func (f *sftpSourceFile) Close() error {
+	f.closeFile()
+	return nil
+}
@@ -0,0 +1,244 @@
+package binlog
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liweiyi88/onedump/filesync"
+	"github.com/liweiyi88/onedump/testutils"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// An in-process SSH server with the sftp subsystem, it serves the local file system.
+type sftpTestServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    []net.Conn
+}
+
+func newSftpTestServer(t *testing.T) *sftpTestServer {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	assert.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := &sftpTestServer{listener: listener}
+	t.Cleanup(server.close)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			server.mu.Lock()
+			server.conns = append(server.conns, conn)
+			server.mu.Unlock()
+
+			go server.serve(conn, config)
+		}
+	}()
+
+	return server
+}
+
+func (s *sftpTestServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				_ = req.Reply(ok, nil)
+
+				if ok {
+					server, err := sftp.NewServer(channel)
+					if err != nil {
+						return
+					}
+
+					_ = server.Serve()
+					_ = server.Close()
+				}
+			}
+		}()
+	}
+}
+
+// Drop all the connections, e.g. the network fails during a transfer.
+func (s *sftpTestServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+
+	s.conns = nil
+}
+
+func (s *sftpTestServer) close() {
+	_ = s.listener.Close()
+	s.dropConnections()
+}
+
+func newTestSftpSource(t *testing.T, server *sftpTestServer, maxAttempts int) *SftpSource {
+	key, err := testutils.GenerateRSAPrivateKey()
+	assert.NoError(t, err)
+
+	source := NewSftpSource(server.listener.Addr().String(), "onedump", key, maxAttempts)
+	source.retryDelay = 10 * time.Millisecond
+	t.Cleanup(func() {
+		_ = source.Close()
+	})
+
+	return source
+}
+
+func TestSftpSource(t *testing.T) {
+	server := newSftpTestServer(t)
+	source := newTestSftpSource(t, server, 3)
+
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("binlog event "), 10000)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "binlog.000002"), content, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "binlog.000001"), []byte("first"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "binlog.index"), []byte("binlog.000001\nbinlog.000002\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.log"), []byte("other"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "binlog.dir"), 0755))
+
+	t.Run("it should list the binlog files", func(t *testing.T) {
+		files, err := source.List(dir, "binlog*")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "binlog.000001"),
+			filepath.Join(dir, "binlog.000002"),
+			filepath.Join(dir, "binlog.index"),
+		}, files)
+	})
+
+	t.Run("it should get the file stat", func(t *testing.T) {
+		info, err := source.Stat(filepath.Join(dir, "binlog.000002"))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)), info.Size())
+		assert.Equal(t, "binlog.000002", info.Name())
+	})
+
+	t.Run("it should not retry a missing file", func(t *testing.T) {
+		_, err := source.Stat(filepath.Join(dir, "binlog.000009"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("it should read the file from the offset", func(t *testing.T) {
+		file, err := source.Open(filepath.Join(dir, "binlog.000002"))
+		assert.NoError(t, err)
+		defer file.Close()
+
+		offset, err := file.Seek(100, io.SeekStart)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(100), offset)
+
+		actual, err := io.ReadAll(file)
+		assert.NoError(t, err)
+		assert.Equal(t, content[100:], actual)
+	})
+
+	t.Run("it should resume the transfer after the connection fails", func(t *testing.T) {
+		file, err := source.Open(filepath.Join(dir, "binlog.000002"))
+		assert.NoError(t, err)
+		defer file.Close()
+
+		buf := make([]byte, 1024)
+		n, err := io.ReadFull(file, buf)
+		assert.NoError(t, err)
+
+		server.dropConnections()
+
+		rest, err := io.ReadAll(file)
+		assert.NoError(t, err)
+		assert.Equal(t, content, append(buf[:n], rest...))
+	})
+}
+
+func TestSftpSourceMaxAttempts(t *testing.T) {
+	server := newSftpTestServer(t)
+	source := newTestSftpSource(t, server, 2)
+
+	server.close()
+
+	_, err := source.List(t.TempDir(), "binlog*")
+	assert.ErrorContains(t, err, "[sftp] fail to list")
+	assert.ErrorContains(t, err, "after 2 attempts")
+}
+
+func TestBinlogSyncerSyncFromSftpSource(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newSftpTestServer(t)
+	source := newTestSftpSource(t, server, 3)
+
+	dir := t.TempDir()
+	for i, content := range []string{"first", "second", "active"} {
+		assert.NoError(os.WriteFile(filepath.Join(dir, fmt.Sprintf("binlog.%06d", i+1)), []byte(content), 0644))
+	}
+
+	stateDir := t.TempDir()
+	destination := &memoryStorage{files: make(map[string][]byte)}
+	syncer := NewBinlogSyncer("binlogs", true, "", filesync.NewFileSync(false, ""), &BinlogInfo{
+		currentBinlogFile: "binlog.000003",
+		binlogDir:         dir,
+		binlogPrefix:      "binlog",
+	}, WithSource(source), WithStateDir(stateDir), WithSkipActive(true))
+
+	assert.NoError(syncer.Sync(destination))
+
+	content, ok := destination.get("binlogs/binlog.000002")
+	assert.True(ok)
+	assert.Equal("second", string(content))
+
+	_, ok = destination.get("binlogs/binlog.000003")
+	assert.False(ok)
+
+	// The state is saved on the backup host instead of the database host.
+	assert.FileExists(filepath.Join(stateDir, ManifestFile))
+	assert.FileExists(filepath.Join(stateDir, SyncResultFile))
+	assert.NoFileExists(filepath.Join(dir, ManifestFile))
+}
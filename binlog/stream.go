@@ -0,0 +1,441 @@
+package binlog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/go-sql-driver/mysql"
+	"github.com/liweiyi88/onedump/storage"
+)
+
+const (
+	StreamStateFile = "onedump-binlog-stream.json" // The default file that saves the stream position
+
+	ShowBinaryLogsQuery = "SHOW BINARY LOGS;"
+
+	// How often the received events are flushed to disk and the position is saved.
+	streamStateInterval = time.Second
+)
+
+// The replication stream, it is implemented by replication.BinlogStreamer.
+type eventSource interface {
+	GetEvent(ctx context.Context) (*replication.BinlogEvent, error)
+}
+
+// The position of the stream, the files that fail to be uploaded are retried after the next rotation or restart.
+type streamState struct {
+	File     string   `json:"file"`
+	Position uint32   `json:"position"`
+	Pending  []string `json:"pending"`
+}
+
+// Stream the binlog events from a remote server via the replication protocol, reassemble them into binlog files
+// in a local directory and save the closed files to the storages, so it does not need to run on the database host.
+type RemoteBinlogSyncer struct {
+	dir             string // the local directory of the reassembled binlog files
+	destinationPath string // storage folder
+	storages        []storage.Storage
+	state           *streamState
+	file            *os.File
+	savedAt         time.Time
+}
+
+func NewRemoteBinlogSyncer(dir, destinationPath string, storages ...storage.Storage) *RemoteBinlogSyncer {
+	return &RemoteBinlogSyncer{
+		dir:             dir,
+		destinationPath: destinationPath,
+		storages:        storages,
+	}
+}
+
+// Create the replication config from the database dsn, the server id must be unique among the replicas of the source.
+func NewReplicationConfig(dsn string, serverID uint32) (replication.BinlogSyncerConfig, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, fmt.Errorf("fail to parse database dsn: %s, error: %v", dsn, err)
+	}
+
+	host, port, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		host = cfg.Addr
+		port = "3306"
+	}
+
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, fmt.Errorf("invalid database port: %s, error: %v", port, err)
+	}
+
+	return replication.BinlogSyncerConfig{
+		ServerID:        serverID,
+		Flavor:          "mysql",
+		Host:            host,
+		Port:            uint16(portNumber),
+		User:            cfg.User,
+		Password:        cfg.Passwd,
+		TLSConfig:       cfg.TLS,
+		RawModeEnabled:  true,
+		HeartbeatPeriod: 30 * time.Second,
+		ReadTimeout:     90 * time.Second,
+		Logger:          slog.Default(),
+	}, nil
+}
+
+// Get the oldest binlog file of the server, the stream starts from it if there is no saved position.
+func QueryFirstBinlog(db *sql.DB) (string, error) {
+	rows, err := db.Query(ShowBinaryLogsQuery)
+	if err != nil {
+		return "", fmt.Errorf("fail to run query %s, error: %v", ShowBinaryLogsQuery, err)
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("fail to close database rows", slog.Any("error", err), slog.Any("query", ShowBinaryLogsQuery))
+		}
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("fail to get columns, query: %s, error: %v", ShowBinaryLogsQuery, err)
+	}
+
+	// The number of columns depends on the MySQL version, the first one is the log name.
+	values := make([]any, len(columns))
+	for i := range values {
+		values[i] = new(sql.RawBytes)
+	}
+
+	if rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return "", fmt.Errorf("fail to scan database rows, query: %s, error: %v", ShowBinaryLogsQuery, err)
+		}
+
+		return string(*values[0].(*sql.RawBytes)), nil
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("fail to get binary logs, error: %v", err)
+	}
+
+	return "", errors.New("no binary logs are found, sync requires log_bin to be set to ON")
+}
+
+// Stream the binlog events until the context is canceled.
+// It resumes from the saved position if there is one, otherwise it starts from the start binlog.
+func (r *RemoteBinlogSyncer) Stream(ctx context.Context, cfg replication.BinlogSyncerConfig, startBinlog string) error {
+	if err := r.open(startBinlog); err != nil {
+		return err
+	}
+
+	syncer := replication.NewBinlogSyncer(cfg)
+	defer syncer.Close()
+
+	streamer, err := syncer.StartSync(r.position())
+	if err != nil {
+		return errors.Join(fmt.Errorf("fail to start replication from %s:%d, error: %v", r.state.File, r.state.Position, err), r.close())
+	}
+
+	return r.receive(ctx, streamer)
+}
+
+// Get the position to request from the source.
+func (r *RemoteBinlogSyncer) position() gomysql.Position {
+	return gomysql.Position{Name: r.state.File, Pos: r.state.Position}
+}
+
+// Load the saved state, retry the pending uploads and open the binlog file to append the events to.
+func (r *RemoteBinlogSyncer) open(startBinlog string) error {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("fail to create binlog directory: %s, error: %v", r.dir, err)
+	}
+
+	state, err := r.loadState()
+	if err != nil {
+		return err
+	}
+
+	if state.File == "" {
+		if startBinlog == "" {
+			return errors.New("start binlog is required if there is no saved stream position")
+		}
+
+		state.File = startBinlog
+	}
+
+	r.state = state
+	r.retryPendingUploads()
+
+	return r.openFile(r.state.File, r.state.Position)
+}
+
+// Open the binlog file and discard the incomplete events after the saved position, they are streamed again.
+func (r *RemoteBinlogSyncer) openFile(name string, position uint32) error {
+	path := filepath.Join(r.dir, name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("fail to open binlog file: %s, error: %v", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Join(fmt.Errorf("fail to get binlog file stat: %s, error: %v", path, err), file.Close())
+	}
+
+	// Stream the whole file again if the file is lost or shorter than the saved position.
+	if position < uint32(len(replication.BinLogFileHeader)) || info.Size() < int64(position) {
+		position = 0
+	}
+
+	if err := file.Truncate(int64(position)); err != nil {
+		return errors.Join(fmt.Errorf("fail to truncate binlog file: %s, error: %v", path, err), file.Close())
+	}
+
+	if _, err := file.Seek(int64(position), io.SeekStart); err != nil {
+		return errors.Join(fmt.Errorf("fail to seek binlog file: %s, error: %v", path, err), file.Close())
+	}
+
+	if position == 0 {
+		if _, err := file.Write(replication.BinLogFileHeader); err != nil {
+			return errors.Join(fmt.Errorf("fail to write binlog file header: %s, error: %v", path, err), file.Close())
+		}
+
+		position = uint32(len(replication.BinLogFileHeader))
+	}
+
+	r.file = file
+	r.state.File = name
+	r.state.Position = position
+
+	return nil
+}
+
+func (r *RemoteBinlogSyncer) receive(ctx context.Context, source eventSource) error {
+	for {
+		e, err := source.GetEvent(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				slog.Info("binlog stream stopped", slog.String("file", r.state.File), slog.Any("position", r.state.Position))
+				return r.close()
+			}
+
+			return errors.Join(fmt.Errorf("fail to receive binlog event, error: %v", err), r.close())
+		}
+
+		if err := r.handleEvent(e); err != nil {
+			return errors.Join(err, r.close())
+		}
+	}
+}
+
+func (r *RemoteBinlogSyncer) handleEvent(e *replication.BinlogEvent) error {
+	switch e.Header.EventType {
+	case replication.ROTATE_EVENT:
+		rotate, ok := e.Event.(*replication.RotateEvent)
+		if !ok {
+			return errors.New("invalid rotate event")
+		}
+
+		// The fake rotate event tells the file of the following events, e.g. after the server is restarted.
+		if e.Header.Timestamp == 0 || e.Header.LogPos == 0 {
+			if string(rotate.NextLogName) == r.state.File {
+				return nil
+			}
+
+			return r.rotate(string(rotate.NextLogName))
+		}
+
+		if err := r.write(e); err != nil {
+			return err
+		}
+
+		return r.rotate(string(rotate.NextLogName))
+	case replication.FORMAT_DESCRIPTION_EVENT:
+		// The source sends the format description event again if the stream starts in the middle of a file.
+		if r.state.Position > uint32(len(replication.BinLogFileHeader)) || e.Header.LogPos == 0 {
+			return nil
+		}
+	case replication.HEARTBEAT_EVENT, replication.HEARTBEAT_LOG_EVENT_V2:
+		return nil
+	}
+
+	return r.write(e)
+}
+
+// Append the event to the binlog file, the position of the event must follow the previous one.
+func (r *RemoteBinlogSyncer) write(e *replication.BinlogEvent) error {
+	if e.Header.LogPos != r.state.Position+uint32(len(e.RawData)) {
+		return fmt.Errorf("unexpected event position %d of %s, the current position is %d", e.Header.LogPos, r.state.File, r.state.Position)
+	}
+
+	if _, err := r.file.Write(e.RawData); err != nil {
+		return fmt.Errorf("fail to write binlog event to %s, error: %v", r.file.Name(), err)
+	}
+
+	r.state.Position = e.Header.LogPos
+
+	if time.Since(r.savedAt) < streamStateInterval {
+		return nil
+	}
+
+	return r.saveState()
+}
+
+// Close the current binlog file, save it to the storages and continue with the next file.
+func (r *RemoteBinlogSyncer) rotate(next string) error {
+	closed := r.state.File
+
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+
+	// The closed files are only kept in the local directory without a storage.
+	if len(r.storages) > 0 {
+		r.state.Pending = append(r.state.Pending, closed)
+	}
+
+	if err := r.openFile(next, 0); err != nil {
+		return err
+	}
+
+	r.retryPendingUploads()
+
+	return r.saveState()
+}
+
+// Upload the closed binlog files, the files that fail to be uploaded are kept in the pending list.
+func (r *RemoteBinlogSyncer) retryPendingUploads() {
+	var pending []string
+	for _, name := range r.state.Pending {
+		if err := r.upload(name); err != nil {
+			slog.Error("fail to save binlog file to destination, it will be retried later", slog.String("file", name), slog.Any("error", err))
+			pending = append(pending, name)
+			continue
+		}
+
+		slog.Info("binlog file saved to destination", slog.String("file", name))
+	}
+
+	r.state.Pending = pending
+}
+
+func (r *RemoteBinlogSyncer) upload(name string) error {
+	file, err := os.Open(filepath.Join(r.dir, name))
+	if err != nil {
+		return fmt.Errorf("fail to open file: %s, error %v", name, err)
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			slog.Error("fail to close file.", slog.Any("file", file.Name()), slog.Any("error", err))
+		}
+	}()
+
+	pathGenerator := func(folder string) string {
+		return path.Join(folder, r.destinationPath, name)
+	}
+
+	// The file is uploaded again to all the storages if any of them fails.
+	var allErrors []error
+	for _, destination := range r.storages {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("fail to seek file: %s, error %v", name, err)
+		}
+
+		if err := destination.Save(file, pathGenerator); err != nil {
+			allErrors = append(allErrors, err)
+		}
+	}
+
+	return errors.Join(allErrors...)
+}
+
+func (r *RemoteBinlogSyncer) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+
+	if err := r.file.Sync(); err != nil {
+		return fmt.Errorf("fail to flush binlog file: %s, error: %v", r.file.Name(), err)
+	}
+
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("fail to close binlog file: %s, error: %v", r.file.Name(), err)
+	}
+
+	r.file = nil
+
+	return nil
+}
+
+// Close the active binlog file and save the position to resume from.
+func (r *RemoteBinlogSyncer) close() error {
+	if r.file == nil {
+		return nil
+	}
+
+	if err := r.file.Sync(); err != nil {
+		return fmt.Errorf("fail to flush binlog file: %s, error: %v", r.file.Name(), err)
+	}
+
+	return errors.Join(r.saveState(), r.closeFile())
+}
+
+func (r *RemoteBinlogSyncer) loadState() (*streamState, error) {
+	state := &streamState{}
+
+	content, err := os.ReadFile(filepath.Join(r.dir, StreamStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("fail to read stream state file, error: %v", err)
+	}
+
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("fail to decode stream state file, error: %v", err)
+	}
+
+	return state, nil
+}
+
+// Save the position after the received events are flushed to disk, so the saved position never points beyond the file.
+func (r *RemoteBinlogSyncer) saveState() error {
+	if r.file != nil {
+		if err := r.file.Sync(); err != nil {
+			return fmt.Errorf("fail to flush binlog file: %s, error: %v", r.file.Name(), err)
+		}
+	}
+
+	encoded, err := json.Marshal(r.state)
+	if err != nil {
+		return fmt.Errorf("fail to encode stream state to json, error: %v", err)
+	}
+
+	path := filepath.Join(r.dir, StreamStateFile)
+	if err := os.WriteFile(path+".tmp", encoded, 0o644); err != nil {
+		return fmt.Errorf("fail to write stream state file, error: %v", err)
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("fail to save stream state file, error: %v", err)
+	}
+
+	r.savedAt = time.Now()
+
+	return nil
+}
@@ -0,0 +1,331 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/liweiyi88/onedump/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	err   error
+}
+
+func (m *memoryStorage) Save(reader io.Reader, pathGenerator storage.PathGeneratorFunc) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[pathGenerator("")] = content
+	return nil
+}
+
+func (m *memoryStorage) Delete(pathGenerator storage.PathGeneratorFunc) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.files, pathGenerator(""))
+	return nil
+}
+
+func (m *memoryStorage) get(name string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	content, ok := m.files[name]
+	return content, ok
+}
+
+type fakeEventSource struct {
+	events []*replication.BinlogEvent
+	cancel context.CancelFunc
+}
+
+func (f *fakeEventSource) GetEvent(ctx context.Context) (*replication.BinlogEvent, error) {
+	if len(f.events) == 0 {
+		f.cancel()
+		return nil, ctx.Err()
+	}
+
+	e := f.events[0]
+	f.events = f.events[1:]
+
+	return e, nil
+}
+
+func fakeRotateEvent(next string) *replication.BinlogEvent {
+	return &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.ROTATE_EVENT},
+		Event:  &replication.RotateEvent{Position: 4, NextLogName: []byte(next)},
+	}
+}
+
+// Read the raw events of a binlog file from the position, the same as the events sent by the source.
+func readRawEvents(t *testing.T, binlog string, position uint32) []*replication.BinlogEvent {
+	parser := replication.NewBinlogParser()
+	parser.SetRawMode(true)
+
+	var events []*replication.BinlogEvent
+	err := parser.ParseFile(binlog, 0, func(e *replication.BinlogEvent) error {
+		if e.Header.LogPos-e.Header.EventSize >= position || e.Header.EventType == replication.FORMAT_DESCRIPTION_EVENT {
+			events = append(events, e)
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+
+	return events
+}
+
+func streamEvents(syncer *RemoteBinlogSyncer, startBinlog string, events []*replication.BinlogEvent) error {
+	if err := syncer.open(startBinlog); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return syncer.receive(ctx, &fakeEventSource{events: events, cancel: cancel})
+}
+
+func TestRemoteBinlogSyncerReceive(t *testing.T) {
+	assert := assert.New(t)
+
+	currentDir, err := os.Getwd()
+	assert.NoError(err)
+
+	binlogsDir := filepath.Join(currentDir, "..", "testutils", "mysqlrestore", "binlogs")
+	binlog2, binlog3 := filepath.Join(binlogsDir, "mysql-bin.000002"), filepath.Join(binlogsDir, "mysql-bin.000003")
+
+	original2, err := os.ReadFile(binlog2)
+	assert.NoError(err)
+
+	original3, err := os.ReadFile(binlog3)
+	assert.NoError(err)
+
+	readState := func(dir string) *streamState {
+		content, err := os.ReadFile(filepath.Join(dir, StreamStateFile))
+		assert.NoError(err)
+
+		state := &streamState{}
+		assert.NoError(json.Unmarshal(content, state))
+
+		return state
+	}
+
+	t.Run("it should reassemble the binlog files and save the closed files", func(t *testing.T) {
+		dir := t.TempDir()
+		destination := &memoryStorage{files: make(map[string][]byte)}
+
+		events := []*replication.BinlogEvent{fakeRotateEvent("mysql-bin.000002")}
+		events = append(events, readRawEvents(t, binlog2, 4)...)
+		events = append(events, fakeRotateEvent("mysql-bin.000003"))
+		events = append(events, readRawEvents(t, binlog3, 4)...)
+
+		syncer := NewRemoteBinlogSyncer(dir, "binlogs", destination)
+		assert.NoError(streamEvents(syncer, "mysql-bin.000002", events))
+
+		content, err := os.ReadFile(filepath.Join(dir, "mysql-bin.000002"))
+		assert.NoError(err)
+		assert.True(bytes.Equal(original2, content))
+
+		content, err = os.ReadFile(filepath.Join(dir, "mysql-bin.000003"))
+		assert.NoError(err)
+		assert.True(bytes.Equal(original3, content))
+
+		// The active binlog file is not saved.
+		assert.Len(destination.files, 1)
+		assert.True(bytes.Equal(original2, destination.files["binlogs/mysql-bin.000002"]))
+
+		assert.Equal(&streamState{File: "mysql-bin.000003", Position: 2609}, readState(dir))
+	})
+
+	t.Run("it should resume from the saved position", func(t *testing.T) {
+		dir := t.TempDir()
+		destination := &memoryStorage{files: make(map[string][]byte)}
+
+		// The events after the saved position are incomplete.
+		assert.NoError(os.WriteFile(filepath.Join(dir, "mysql-bin.000003"), append(bytes.Clone(original3[:746]), []byte("incomplete")...), 0o644))
+		assert.NoError(os.WriteFile(filepath.Join(dir, StreamStateFile), []byte(`{"file":"mysql-bin.000003","position":746}`), 0o644))
+
+		// The source sends the format description event with position 0 if the stream starts in the middle of a file.
+		events := readRawEvents(t, binlog3, 746)
+		events[0].Header.LogPos = 0
+		events = append([]*replication.BinlogEvent{fakeRotateEvent("mysql-bin.000003")}, events...)
+
+		syncer := NewRemoteBinlogSyncer(dir, "binlogs", destination)
+		assert.NoError(streamEvents(syncer, "", events))
+
+		content, err := os.ReadFile(filepath.Join(dir, "mysql-bin.000003"))
+		assert.NoError(err)
+		assert.True(bytes.Equal(original3, content))
+		assert.Empty(destination.files)
+	})
+
+	t.Run("it should retry the pending uploads", func(t *testing.T) {
+		dir := t.TempDir()
+		destination := &memoryStorage{files: make(map[string][]byte), err: errors.New("network error")}
+
+		events := []*replication.BinlogEvent{fakeRotateEvent("mysql-bin.000002")}
+		events = append(events, readRawEvents(t, binlog2, 4)...)
+		events = append(events, fakeRotateEvent("mysql-bin.000003"))
+
+		syncer := NewRemoteBinlogSyncer(dir, "binlogs", destination)
+		assert.NoError(streamEvents(syncer, "mysql-bin.000002", events))
+		assert.Equal([]string{"mysql-bin.000002"}, readState(dir).Pending)
+
+		destination.err = nil
+		syncer = NewRemoteBinlogSyncer(dir, "binlogs", destination)
+		assert.NoError(streamEvents(syncer, "", nil))
+		assert.Empty(readState(dir).Pending)
+		assert.True(bytes.Equal(original2, destination.files["binlogs/mysql-bin.000002"]))
+	})
+
+	t.Run("it should save the closed files to all the storages", func(t *testing.T) {
+		dir := t.TempDir()
+		first := &memoryStorage{files: make(map[string][]byte)}
+		second := &memoryStorage{files: make(map[string][]byte), err: errors.New("network error")}
+
+		events := []*replication.BinlogEvent{fakeRotateEvent("mysql-bin.000002")}
+		events = append(events, readRawEvents(t, binlog2, 4)...)
+		events = append(events, fakeRotateEvent("mysql-bin.000003"))
+
+		syncer := NewRemoteBinlogSyncer(dir, "binlogs", first, second)
+		assert.NoError(streamEvents(syncer, "mysql-bin.000002", events))
+		assert.True(bytes.Equal(original2, first.files["binlogs/mysql-bin.000002"]))
+
+		// The file is saved again if any of the storages fails.
+		assert.Equal([]string{"mysql-bin.000002"}, readState(dir).Pending)
+
+		second.err = nil
+		syncer = NewRemoteBinlogSyncer(dir, "binlogs", first, second)
+		assert.NoError(streamEvents(syncer, "", nil))
+		assert.Empty(readState(dir).Pending)
+		assert.True(bytes.Equal(original2, second.files["binlogs/mysql-bin.000002"]))
+	})
+
+	t.Run("it should skip the heartbeat events", func(t *testing.T) {
+		dir := t.TempDir()
+
+		heartbeat := func(eventType replication.EventType) *replication.BinlogEvent {
+			return &replication.BinlogEvent{
+				Header:  &replication.EventHeader{EventType: eventType, LogPos: 2609},
+				RawData: []byte("heartbeat"),
+			}
+		}
+
+		events := readRawEvents(t, binlog3, 4)
+		events = append(events[:3], append([]*replication.BinlogEvent{heartbeat(replication.HEARTBEAT_EVENT), heartbeat(replication.HEARTBEAT_LOG_EVENT_V2)}, events[3:]...)...)
+
+		syncer := NewRemoteBinlogSyncer(dir, "")
+		assert.NoError(streamEvents(syncer, "mysql-bin.000003", events))
+
+		content, err := os.ReadFile(filepath.Join(dir, "mysql-bin.000003"))
+		assert.NoError(err)
+		assert.True(bytes.Equal(original3, content))
+	})
+
+	t.Run("it should keep the closed files locally without a storage", func(t *testing.T) {
+		dir := t.TempDir()
+
+		events := []*replication.BinlogEvent{fakeRotateEvent("mysql-bin.000002")}
+		events = append(events, readRawEvents(t, binlog2, 4)...)
+		events = append(events, fakeRotateEvent("mysql-bin.000003"))
+
+		syncer := NewRemoteBinlogSyncer(dir, "")
+		assert.NoError(streamEvents(syncer, "mysql-bin.000002", events))
+		assert.Empty(readState(dir).Pending)
+
+		content, err := os.ReadFile(filepath.Join(dir, "mysql-bin.000002"))
+		assert.NoError(err)
+		assert.True(bytes.Equal(original2, content))
+	})
+
+	t.Run("it should return an error if events are missing", func(t *testing.T) {
+		dir := t.TempDir()
+		destination := &memoryStorage{files: make(map[string][]byte)}
+
+		events := readRawEvents(t, binlog3, 4)
+		events = append(events[:2], events[3:]...)
+
+		syncer := NewRemoteBinlogSyncer(dir, "binlogs", destination)
+		err := streamEvents(syncer, "mysql-bin.000003", events)
+		assert.ErrorContains(err, "unexpected event position")
+
+		// The position of the written events is saved.
+		assert.Equal("mysql-bin.000003", readState(dir).File)
+	})
+
+	t.Run("it should require a start binlog without saved position", func(t *testing.T) {
+		syncer := NewRemoteBinlogSyncer(t.TempDir(), "binlogs", &memoryStorage{})
+		assert.EqualError(syncer.open(""), "start binlog is required if there is no saved stream position")
+	})
+}
+
+func TestNewReplicationConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg, err := NewReplicationConfig("repl:secret@tcp(db.example.com:3307)/", 1001)
+	assert.NoError(err)
+	assert.Equal(uint32(1001), cfg.ServerID)
+	assert.Equal("db.example.com", cfg.Host)
+	assert.Equal(uint16(3307), cfg.Port)
+	assert.Equal("repl", cfg.User)
+	assert.Equal("secret", cfg.Password)
+	assert.True(cfg.RawModeEnabled)
+
+	cfg, err = NewReplicationConfig("repl:secret@tcp(db.example.com)/", 1001)
+	assert.NoError(err)
+	assert.Equal(uint16(3306), cfg.Port)
+	assert.Nil(cfg.TLSConfig)
+
+	// Managed servers usually require TLS.
+	cfg, err = NewReplicationConfig("repl:secret@tcp(db.example.com)/?tls=true", 1001)
+	assert.NoError(err)
+	assert.NotNil(cfg.TLSConfig)
+	assert.Equal("db.example.com", cfg.TLSConfig.ServerName)
+}
+
+func TestQueryFirstBinlog(t *testing.T) {
+	assert := assert.New(t)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(err)
+	defer db.Close()
+
+	mock.ExpectQuery(ShowBinaryLogsQuery).WillReturnRows(
+		sqlmock.NewRows([]string{"Log_name", "File_size", "Encrypted"}).AddRow("mysql-bin.000002", 2984581, "No").AddRow("mysql-bin.000003", 2609, "No"),
+	)
+
+	binlog, err := QueryFirstBinlog(db)
+	assert.NoError(err)
+	assert.Equal("mysql-bin.000002", binlog)
+
+	mock.ExpectQuery(ShowBinaryLogsQuery).WillReturnRows(sqlmock.NewRows([]string{"Log_name", "File_size"}))
+	_, err = QueryFirstBinlog(db)
+	assert.EqualError(err, "no binary logs are found, sync requires log_bin to be set to ON")
+}
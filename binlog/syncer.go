@@ -1,19 +1,20 @@
 package binlog
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/liweiyi88/onedump/filesync"
-	"github.com/liweiyi88/onedump/fileutil"
 	"github.com/liweiyi88/onedump/storage"
 )
 
@@ -85,71 +86,303 @@ type BinlogSyncer struct {
 	destinationPath string // storage folder
 	saveLog         bool   // is save sync result in a log file
 	logFile         string // if not empty string, save result log in the specific file.
+	manifestFile    string // if not empty string, save the manifest in the specific file.
+	skipActive      bool   // do not upload the binlog file that MySQL is writing
+	encoding        fileEncoding
+	source          BinlogSource // where the binlog files are read from, by default the local disk
+	stateDir        string       // if not empty string, save the manifest and the result log in the local directory.
 	fs              *filesync.FileSync
+	manifest        *BinlogManifest
 	*BinlogInfo
 }
 
-func (b *BinlogSyncer) syncFile(filename string, storage storage.Storage) error {
+type BinlogSyncerOption func(b *BinlogSyncer)
+
+func WithManifestFile(manifestFile string) BinlogSyncerOption {
+	return func(b *BinlogSyncer) {
+		b.manifestFile = manifestFile
+	}
+}
+
+func WithSkipActive(skipActive bool) BinlogSyncerOption {
+	return func(b *BinlogSyncer) {
+		b.skipActive = skipActive
+	}
+}
+
+// Read the binlog files from the source instead of the local disk, e.g. the database host over SFTP.
+func WithSource(source BinlogSource) BinlogSyncerOption {
+	return func(b *BinlogSyncer) {
+		b.source = source
+	}
+}
+
+// Save the manifest and the result log in the local directory instead of the binlog directory.
+func WithStateDir(stateDir string) BinlogSyncerOption {
+	return func(b *BinlogSyncer) {
+		b.stateDir = stateDir
+	}
+}
+
+// Compress the binlog files before they are saved, gzip or zstd.
+func WithCompression(compression string) BinlogSyncerOption {
+	return func(b *BinlogSyncer) {
+		b.encoding.compression = compression
+	}
+}
+
+// Encrypt the binlog files with the 256-bit key before they are saved.
+func WithEncryptionKey(key []byte) BinlogSyncerOption {
+	return func(b *BinlogSyncer) {
+		b.encoding.key = key
+	}
+}
+
+// Get the file name in the destinations, it records how the binlog file is compressed and encrypted.
+func (b *BinlogSyncer) remoteName(name string) string {
+	return name + b.encoding.suffix()
+}
+
+// Check if MySQL is writing the binlog file.
+func (b *BinlogSyncer) isActive(filename string) bool {
+	return b.BinlogInfo != nil && filepath.Base(filename) == b.currentBinlogFile
+}
+
+// Get the number of bytes to upload.
+// Only the events before the binlog position are complete in the active binlog file.
+func (b *BinlogSyncer) uploadSize(filename string, size int64) int64 {
+	if b.isActive(filename) && b.position > 0 && int64(b.position) < size {
+		return int64(b.position)
+	}
+
+	return size
+}
+
+func (b *BinlogSyncer) getManifestFile() string {
+	if strings.TrimSpace(b.manifestFile) != "" {
+		return b.manifestFile
+	}
+
+	return filepath.Join(b.getStateDir(), ManifestFile)
+}
+
+func (b *BinlogSyncer) getStateDir() string {
+	if strings.TrimSpace(b.stateDir) != "" {
+		return b.stateDir
+	}
+
+	return b.binlogDir
+}
+
+func (b *BinlogSyncer) getSource() BinlogSource {
+	if b.source == nil {
+		return localSource{}
+	}
+
+	return b.source
+}
+
+// Compare the binlog file with the uploaded version in the manifest.
+// It returns nil if nothing has changed, the entry and false if only the state has changed, e.g. the active file is closed without new events.
+func (b *BinlogSyncer) plan(filename string) (*ManifestEntry, bool, error) {
+	active := b.isActive(filename)
+	if active && b.skipActive {
+		return nil, false, nil
+	}
+
+	info, err := b.getSource().Stat(filename)
+	if err != nil {
+		return nil, false, fmt.Errorf("fail to get file stat: %s, error %v", filename, err)
+	}
+
+	size := b.uploadSize(filename, info.Size())
+	remoteName := b.remoteName(info.Name())
+
+	uploaded := b.manifest.get(info.Name())
+
+	// The file is expired, it is never uploaded again.
+	if uploaded != nil && uploaded.DeletedAt != nil {
+		return nil, false, nil
+	}
+
+	// The file is uploaded again if the compression or encryption has changed.
+	if uploaded != nil && uploaded.remoteName() != remoteName {
+		uploaded = nil
+	}
+
+	if uploaded != nil && uploaded.Size == size && uploaded.Active == active {
+		return nil, false, nil
+	}
+
+	sum, err := computeSHA256(b.getSource(), filename, size)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entry := &ManifestEntry{
+		Name:        info.Name(),
+		Size:        size,
+		SHA256:      sum,
+		Active:      active,
+		RemoteName:  remoteName,
+		Compression: b.encoding.compression,
+		Encrypted:   len(b.encoding.key) > 0,
+	}
+
+	if uploaded != nil && uploaded.SHA256 == sum {
+		entry.UploadedAt = uploaded.UploadedAt
+		return entry, false, nil
+	}
+
+	return entry, true, nil
+}
+
+// Save the binlog file to all the storages, it is synced again next time if any of them fails.
+func (b *BinlogSyncer) syncFile(filename string, storages ...storage.Storage) error {
 	syncFunc := func() error {
-		f, err := os.Open(filename)
+		f, err := b.getSource().Open(filename)
 		if err != nil {
 			return fmt.Errorf("fail to open file: %s, error %v", filename, err)
 		}
 
 		defer func() {
 			if err := f.Close(); err != nil {
-				slog.Error("fail to close file.", slog.Any("file", f.Name()), slog.Any("error", err))
+				slog.Error("fail to close file.", slog.Any("file", filename), slog.Any("error", err))
 			}
 		}()
 
-		s, err := f.Stat()
+		s, err := b.getSource().Stat(filename)
 		if err != nil {
 			return fmt.Errorf("fail to get file stat: %s, error %v", filename, err)
 		}
 
+		// The configured path of a storage, e.g. the local path or the S3 key, is the folder of the binlog files.
+		pathGenerator := func(folder string) string {
+			return path.Join(folder, b.destinationPath, b.remoteName(s.Name()))
+		}
+
 		// binlog file can be updated during upload (MySQL flush logs).
 		// Enforce the size based on the current read for consistency.
-		limitedReader := io.LimitReader(f, s.Size())
+		size := b.uploadSize(filename, s.Size())
 
-		pathGenerator := func(filename string) string {
-			return b.destinationPath + "/" + s.Name()
-		}
+		var allErrors []error
+		for _, storage := range storages {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("fail to seek file: %s, error %v", filename, err)
+			}
 
-		if err = storage.Save(limitedReader, pathGenerator); err != nil {
-			return fmt.Errorf("fail to save file to destination, error: %v", err)
+			if err = b.save(storage, io.LimitReader(f, size), pathGenerator); err != nil {
+				allErrors = append(allErrors, fmt.Errorf("fail to save file to destination, error: %v", err))
+			}
 		}
 
-		return nil
+		return errors.Join(allErrors...)
 	}
 
 	return b.fs.SyncFile(filename, syncFunc)
 }
 
-func (b *BinlogSyncer) Sync(storage storage.Storage) error {
-	files, err := fileutil.ListFiles(b.binlogDir, b.binlogPrefix+"*", "")
+// Compress and encrypt the content while it is saved to the storage.
+func (b *BinlogSyncer) save(destination storage.Storage, reader io.Reader, pathGenerator storage.PathGeneratorFunc) error {
+	if b.encoding.isPlain() {
+		return destination.Save(reader, pathGenerator)
+	}
+
+	encoded := b.encoding.reader(reader)
+
+	// Stop the encoding if the storage does not read all the content.
+	defer func() {
+		if err := encoded.Close(); err != nil {
+			slog.Error("fail to close encoded reader", slog.Any("error", err))
+		}
+	}()
+
+	return destination.Save(encoded, pathGenerator)
+}
+
+// Save the manifest locally and alongside the binlog files in the storages.
+func (b *BinlogSyncer) saveManifest(storages ...storage.Storage) error {
+	manifestFile := b.getManifestFile()
+	if err := b.manifest.save(manifestFile); err != nil {
+		return err
+	}
+
+	pathGenerator := func(folder string) string {
+		return path.Join(folder, b.destinationPath, ManifestFile)
+	}
+
+	var allErrors []error
+	for _, storage := range storages {
+		encoded, err := b.manifest.encode()
+		if err != nil {
+			return err
+		}
+
+		if err := storage.Save(bytes.NewReader(encoded), pathGenerator); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("fail to save manifest to destination, error: %v", err))
+		}
+	}
+
+	return errors.Join(allErrors...)
+}
+
+// Save the binlog files to the storages.
+// The files are only uploaded again if they have grown or been closed since the last upload.
+func (b *BinlogSyncer) Sync(storages ...storage.Storage) error {
+	if len(storages) == 0 {
+		return errors.New("at least one destination is required to sync binlog files")
+	}
+
+	files, err := b.getSource().List(b.binlogDir, b.binlogPrefix+"*")
 	if err != nil {
 		return fmt.Errorf("fail to list all binlog files, error: %v", err)
 	}
 
+	if b.manifest, err = loadManifest(b.getManifestFile()); err != nil {
+		return err
+	}
+
 	limiter := make(chan struct{}, MaxConcurrentSync)
 
 	var syncFiles []string
-	// Filter out files that have been synced before.
-	// So the save log will persist proper file names.
-	if b.fs.SaveChecksum {
-		for _, file := range files {
+	entries := make(map[string]*ManifestEntry)
+	updated := false
+
+	for _, file := range files {
+		entry, upload, err := b.plan(file)
+		if err != nil {
+			return err
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		updated = true
+
+		if !upload {
+			b.manifest.set(entry)
+			continue
+		}
+
+		// Filter out files that have been synced before.
+		// So the save log will persist proper file names.
+		if b.fs.SaveChecksum {
 			synced, err := b.fs.HasSynced(file)
 
 			if err != nil {
 				return fmt.Errorf("fail to check if %s has been transferred, error: %v", file, err)
 			}
 
-			if !synced {
-				syncFiles = append(syncFiles, file)
+			if synced {
+				entry.UploadedAt = time.Now().UTC()
+				b.manifest.set(entry)
+				continue
 			}
 		}
-	} else {
-		syncFiles = files
+
+		entries[file] = entry
+		syncFiles = append(syncFiles, file)
 	}
 
 	var wg sync.WaitGroup
@@ -165,9 +398,14 @@ func (b *BinlogSyncer) Sync(storage storage.Storage) error {
 				wg.Done()
 			}()
 
-			if err := b.syncFile(file, storage); err != nil {
+			if err := b.syncFile(file, storages...); err != nil {
 				errCh <- fmt.Errorf("fail to sync file: %s, error: %v", file, err)
+				return
 			}
+
+			entry := entries[file]
+			entry.UploadedAt = time.Now().UTC()
+			b.manifest.set(entry)
 		}(file)
 	}
 
@@ -181,13 +419,19 @@ func (b *BinlogSyncer) Sync(storage storage.Storage) error {
 		allErrors = append(allErrors, err)
 	}
 
+	if updated {
+		if err := b.saveManifest(storages...); err != nil {
+			allErrors = append(allErrors, err)
+		}
+	}
+
 	syncError := errors.Join(allErrors...)
 
 	if !b.saveLog {
 		return syncError
 	}
 
-	saveErr := newSyncResult(syncFiles, syncError).save(b.binlogDir, b.logFile)
+	saveErr := newSyncResult(syncFiles, syncError).save(b.getStateDir(), b.logFile)
 	if saveErr != nil {
 		return errors.Join(syncError, saveErr)
 	}
@@ -201,12 +445,19 @@ func NewBinlogSyncer(
 	logFile string,
 	fileSync *filesync.FileSync,
 	binlogInfo *BinlogInfo,
+	opts ...BinlogSyncerOption,
 ) *BinlogSyncer {
-	return &BinlogSyncer{
+	syncer := &BinlogSyncer{
 		destinationPath: destinationPath,
 		saveLog:         saveLog,
 		logFile:         logFile,
 		fs:              fileSync,
 		BinlogInfo:      binlogInfo,
 	}
+
+	for _, opt := range opts {
+		opt(syncer)
+	}
+
+	return syncer
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/liweiyi88/onedump/filesync"
 	"github.com/liweiyi88/onedump/fileutil"
 	"github.com/liweiyi88/onedump/storage"
+	"github.com/liweiyi88/onedump/storage/local"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
@@ -164,6 +165,10 @@ func TestBinlogSyncerSync(t *testing.T) {
 				ms.On("Save", mock.Anything, mock.AnythingOfType("storage.PathGeneratorFunc")).
 					Return(nil).
 					Once()
+				// The manifest of the synced files is saved
+				ms.On("Save", mock.Anything, mock.AnythingOfType("storage.PathGeneratorFunc")).
+					Return(nil).
+					Once()
 			},
 			expectError: true,
 		},
@@ -237,6 +242,50 @@ func TestBinlogSyncerSync(t *testing.T) {
 	}
 }
 
+func TestBinlogSyncerSyncToMultipleStorages(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	for _, f := range []string{"binlog.000001", "binlog.000002"} {
+		assert.NoError(os.WriteFile(filepath.Join(dir, f), []byte("test data "+f), 0644))
+	}
+
+	first, second := t.TempDir(), t.TempDir()
+	assert.NoError(os.Mkdir(filepath.Join(first, "binlogs"), 0755))
+	assert.NoError(os.Mkdir(filepath.Join(second, "binlogs"), 0755))
+
+	syncer := NewBinlogSyncer("binlogs", false, "", filesync.NewFileSync(false, ""), &BinlogInfo{
+		binlogDir:    dir,
+		binlogPrefix: "binlog",
+	})
+
+	assert.NoError(syncer.Sync(&local.Local{Path: first}, &local.Local{Path: second}))
+
+	for _, folder := range []string{first, second} {
+		for _, f := range []string{"binlog.000001", "binlog.000002"} {
+			content, err := os.ReadFile(filepath.Join(folder, "binlogs", f))
+			assert.NoError(err)
+			assert.Equal("test data "+f, string(content))
+		}
+	}
+
+	t.Run("it should save to the other storages if one of them fails", func(t *testing.T) {
+		third := t.TempDir()
+		assert.NoError(os.Mkdir(filepath.Join(third, "binlogs"), 0755))
+
+		// A new manifest, so the files are synced again.
+		syncer := NewBinlogSyncer("binlogs", false, "", filesync.NewFileSync(false, ""), syncer.BinlogInfo, WithManifestFile(filepath.Join(t.TempDir(), ManifestFile)))
+
+		err := syncer.Sync(&local.Local{Path: filepath.Join(first, "missing")}, &local.Local{Path: third})
+		assert.Error(err)
+		assert.FileExists(filepath.Join(third, "binlogs", "binlog.000001"))
+	})
+
+	t.Run("it should require a storage", func(t *testing.T) {
+		assert.EqualError(syncer.Sync(), "at least one destination is required to sync binlog files")
+	})
+}
+
 func TestNewBinlogSyncer(t *testing.T) {
 
 	fs := filesync.NewFileSync(false, "")
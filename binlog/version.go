@@ -1,6 +1,7 @@
 package binlog
 
 import (
+	"fmt"
 	"log/slog"
 	"strconv"
 	"strings"
@@ -9,9 +10,11 @@ import (
 
 type mysqlVersion struct {
 	major, minor, patch int
+	mariaDB             bool
 }
 
 // It parses a MySQL version string (e.g., "8.0.34") into major, minor, and patch numbers.
+// MariaDB is detected by its version string, e.g. "10.11.6-MariaDB-log".
 func splitServerVersion(version string) *mysqlVersion {
 	if version == "" {
 		return &mysqlVersion{major: 0, minor: 0, patch: 0}
@@ -37,9 +40,10 @@ func splitServerVersion(version string) *mysqlVersion {
 			if err != nil {
 				minor = extractNumber(v)
 				return &mysqlVersion{
-					major: major,
-					minor: minor,
-					patch: 0,
+					major:   major,
+					minor:   minor,
+					patch:   0,
+					mariaDB: strings.Contains(strings.ToLower(version), "mariadb"),
 				}
 			}
 
@@ -49,7 +53,43 @@ func splitServerVersion(version string) *mysqlVersion {
 		}
 	}
 
-	return &mysqlVersion{major, minor, patch}
+	return &mysqlVersion{major: major, minor: minor, patch: patch, mariaDB: strings.Contains(strings.ToLower(version), "mariadb")}
+}
+
+// SHOW MASTER STATUS was replaced by SHOW BINARY LOG STATUS since MySQL 8.2, MariaDB still supports SHOW MASTER STATUS.
+func (v *mysqlVersion) binlogStatusQuery() string {
+	if v.mariaDB || v.major < 8 || (v.major == 8 && v.minor < 2) {
+		return ShowMasterStatusQuery
+	}
+
+	return ShowBinlogStatusQuery
+}
+
+// CHANGE MASTER TO was replaced by CHANGE REPLICATION SOURCE TO since MySQL 8.0.23, MariaDB only supports CHANGE MASTER TO.
+func (v *mysqlVersion) supportsReplicationSource() bool {
+	if v.mariaDB {
+		return false
+	}
+
+	if v.major != 8 {
+		return v.major > 8
+	}
+
+	return v.minor > 0 || v.patch >= 23
+}
+
+// Get the query that shows the current binlog file and position for a MySQL server version.
+func BinlogStatusQuery(version string) string {
+	return splitServerVersion(version).binlogStatusQuery()
+}
+
+// Get the CHANGE REPLICATION SOURCE TO (or CHANGE MASTER TO for old servers) statement of a binlog file and position.
+func ChangeReplicationSourceStatement(version string, file string, position uint64) string {
+	if splitServerVersion(version).supportsReplicationSource() {
+		return fmt.Sprintf("CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='%s', SOURCE_LOG_POS=%d;", file, position)
+	}
+
+	return fmt.Sprintf("CHANGE MASTER TO MASTER_LOG_FILE='%s', MASTER_LOG_POS=%d;", file, position)
 }
 
 func extractNumber(v string) int {
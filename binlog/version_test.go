@@ -23,4 +23,31 @@ func TestSplitServerVersion(t *testing.T) {
 	assert.Equal(8, mysqlVersion.major)
 	assert.Equal(4, mysqlVersion.minor)
 	assert.Equal(0, mysqlVersion.patch)
+	assert.False(mysqlVersion.mariaDB)
+
+	mysqlVersion = splitServerVersion("10.11.6-MariaDB-1:10.11.6+maria~ubu2204-log")
+
+	assert.Equal(10, mysqlVersion.major)
+	assert.Equal(11, mysqlVersion.minor)
+	assert.Equal(6, mysqlVersion.patch)
+	assert.True(mysqlVersion.mariaDB)
+}
+
+func TestBinlogStatusQuery(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(ShowMasterStatusQuery, BinlogStatusQuery("5.7.44"))
+	assert.Equal(ShowMasterStatusQuery, BinlogStatusQuery("8.0.42"))
+	assert.Equal(ShowBinlogStatusQuery, BinlogStatusQuery("8.4.5"))
+	assert.Equal(ShowBinlogStatusQuery, BinlogStatusQuery("9.1.0"))
+	assert.Equal(ShowMasterStatusQuery, BinlogStatusQuery("10.11.6-MariaDB-log"))
+	assert.Equal(ShowMasterStatusQuery, BinlogStatusQuery("11.4.2-MariaDB"))
+}
+
+func TestChangeReplicationSourceStatement(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("CHANGE MASTER TO MASTER_LOG_FILE='binlog.000001', MASTER_LOG_POS=4;", ChangeReplicationSourceStatement("8.0.22", "binlog.000001", 4))
+	assert.Equal("CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='binlog.000001', SOURCE_LOG_POS=4;", ChangeReplicationSourceStatement("8.0.23", "binlog.000001", 4))
+	assert.Equal("CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='binlog.000001', SOURCE_LOG_POS=4;", ChangeReplicationSourceStatement("8.4.0", "binlog.000001", 4))
+	assert.Equal("CHANGE MASTER TO MASTER_LOG_FILE='mysql-bin.000001', MASTER_LOG_POS=4;", ChangeReplicationSourceStatement("10.11.6-MariaDB-log", "mysql-bin.000001", 4))
+	assert.Equal("CHANGE MASTER TO MASTER_LOG_FILE='mysql-bin.000001', MASTER_LOG_POS=4;", ChangeReplicationSourceStatement("11.4.2-MariaDB", "mysql-bin.000001", 4))
 }
@@ -0,0 +1,222 @@
+package binlog
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/liweiyi88/onedump/storage"
+)
+
+const (
+	FlushBinaryLogsQuery = "FLUSH BINARY LOGS;"
+
+	// Wait for the events of a rotation, e.g. the new binlog file and the index file update, before syncing.
+	watchDebounce = 500 * time.Millisecond
+
+	// Sync periodically as well, so the failed uploads are retried and a missed event does not delay the upload.
+	watchSyncInterval = time.Minute
+)
+
+// Watch the binlog directory and upload each binlog file as soon as MySQL rotates it.
+// The active binlog file is not uploaded, flush the binary logs periodically to bound the RPO.
+type BinlogWatcher struct {
+	syncer        *BinlogSyncer
+	db            *sql.DB // it is only required to flush the binary logs
+	flushInterval time.Duration
+	retention     *BinlogRetention // clean up the archived binlog files after each sync
+}
+
+type BinlogWatcherOption func(w *BinlogWatcher)
+
+func WithRetention(retention *BinlogRetention) BinlogWatcherOption {
+	return func(w *BinlogWatcher) {
+		w.retention = retention
+	}
+}
+
+func NewBinlogWatcher(syncer *BinlogSyncer, db *sql.DB, flushInterval time.Duration, opts ...BinlogWatcherOption) *BinlogWatcher {
+	syncer.skipActive = true
+
+	watcher := &BinlogWatcher{
+		syncer:        syncer,
+		db:            db,
+		flushInterval: flushInterval,
+	}
+
+	for _, opt := range opts {
+		opt(watcher)
+	}
+
+	return watcher
+}
+
+// Watch until the context is canceled, the in-flight uploads are finished before it returns.
+func (w *BinlogWatcher) Watch(ctx context.Context, storages ...storage.Storage) error {
+	if w.flushInterval > 0 && w.db == nil {
+		return errors.New("database is required to flush binary logs")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("fail to create file watcher, error: %v", err)
+	}
+
+	defer func() {
+		if err := watcher.Close(); err != nil {
+			slog.Error("fail to close file watcher", slog.Any("error", err))
+		}
+	}()
+
+	if err := watcher.Add(w.syncer.binlogDir); err != nil {
+		return fmt.Errorf("fail to watch binlog directory: %s, error: %v", w.syncer.binlogDir, err)
+	}
+
+	w.sync(storages...)
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	syncTicker := time.NewTicker(watchSyncInterval)
+	defer syncTicker.Stop()
+
+	var flush <-chan time.Time
+	if w.flushInterval > 0 {
+		flushTicker := time.NewTicker(w.flushInterval)
+		defer flushTicker.Stop()
+
+		flush = flushTicker.C
+	}
+
+	slog.Info("watching binlog directory", slog.String("dir", w.syncer.binlogDir))
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("stop watching binlog directory")
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("file watcher is closed")
+			}
+
+			if w.isRotation(event) {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("file watcher is closed")
+			}
+
+			slog.Error("file watcher error", slog.Any("error", err))
+		case <-debounce.C:
+			w.sync(storages...)
+		case <-syncTicker.C:
+			w.sync(storages...)
+		case <-flush:
+			// The rotation is picked up by the file watcher.
+			if _, err := w.db.ExecContext(ctx, FlushBinaryLogsQuery); err != nil {
+				slog.Error("fail to flush binary logs", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// MySQL creates a new binlog file and adds it to the index file when it rotates the binlog.
+func (w *BinlogWatcher) isRotation(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		return false
+	}
+
+	name := filepath.Base(event.Name)
+	if name == w.indexFile() {
+		return true
+	}
+
+	return event.Has(fsnotify.Create) && strings.HasPrefix(name, w.syncer.binlogPrefix+".") && extractBinlogNumber(name) > 0
+}
+
+func (w *BinlogWatcher) indexFile() string {
+	return w.syncer.binlogPrefix + ".index"
+}
+
+// Sync the closed binlog files, the errors are logged and the files are retried in the next sync.
+func (w *BinlogWatcher) sync(storages ...storage.Storage) {
+	active, err := w.readActiveBinlog()
+	if err != nil {
+		slog.Error("fail to get the active binlog file", slog.Any("error", err))
+		return
+	}
+
+	w.syncer.currentBinlogFile = active
+
+	if err := w.syncer.Sync(storages...); err != nil {
+		slog.Error("fail to sync binlog files", slog.Any("error", err))
+		return
+	}
+
+	slog.Debug("binlog files synced", slog.String("active", active))
+
+	if w.retention == nil {
+		return
+	}
+
+	report, err := w.retention.Apply(storages...)
+	if err != nil {
+		slog.Error("fail to apply binlog retention", slog.Any("error", err))
+	}
+
+	if report != nil && (report.PurgeTo != "" || len(report.Deleted) > 0) {
+		slog.Info("binlog retention applied", slog.String("purge_to", report.PurgeTo), slog.Any("deleted", report.Deleted))
+	}
+}
+
+// Get the active binlog file, it is the last file of the index file or the last binlog file if there is no index file.
+func (w *BinlogWatcher) readActiveBinlog() (string, error) {
+	file, err := os.Open(filepath.Join(w.syncer.binlogDir, w.indexFile()))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("fail to open binlog index file, error: %v", err)
+		}
+
+		binlogs, err := listSortedBinlogs(w.syncer.binlogDir)
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Base(binlogs[len(binlogs)-1]), nil
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			slog.Error("fail to close binlog index file", slog.Any("error", err))
+		}
+	}()
+
+	var active string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			active = filepath.Base(line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("fail to read binlog index file, error: %v", err)
+	}
+
+	if active == "" {
+		return "", errors.New("binlog index file is empty")
+	}
+
+	return active, nil
+}
@@ -0,0 +1,85 @@
+package binlog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/filesync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinlogWatcherWatch(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000001"), []byte("closed"), 0644))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000002"), []byte("active"), 0644))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.index"), []byte("./binlog.000001\n./binlog.000002\n"), 0644))
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(err)
+	defer db.Close()
+
+	mock.ExpectExec(FlushBinaryLogsQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	destination := &memoryStorage{files: make(map[string][]byte)}
+	syncer := NewBinlogSyncer("binlogs", false, "", filesync.NewFileSync(false, ""), &BinlogInfo{binlogDir: dir, binlogPrefix: "binlog"})
+	watcher := NewBinlogWatcher(syncer, db, 200*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watcher.Watch(ctx, destination)
+	}()
+
+	uploaded := func(name string) func() bool {
+		return func() bool {
+			_, ok := destination.get("binlogs/" + name)
+			return ok
+		}
+	}
+
+	assert.Eventually(uploaded("binlog.000001"), 2*time.Second, 10*time.Millisecond)
+	_, ok := destination.get("binlogs/binlog.000002")
+	assert.False(ok)
+
+	// The binlog is flushed periodically.
+	assert.Eventually(func() bool { return mock.ExpectationsWereMet() == nil }, 2*time.Second, 10*time.Millisecond)
+
+	// MySQL rotates the binlog.
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000003"), []byte("new"), 0644))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.index"), []byte("./binlog.000001\n./binlog.000002\n./binlog.000003\n"), 0644))
+
+	assert.Eventually(uploaded("binlog.000002"), 3*time.Second, 10*time.Millisecond)
+	_, ok = destination.get("binlogs/binlog.000003")
+	assert.False(ok)
+
+	cancel()
+	assert.NoError(<-done)
+}
+
+func TestBinlogWatcherReadActiveBinlog(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000009"), []byte("closed"), 0644))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.000010"), []byte("active"), 0644))
+
+	watcher := NewBinlogWatcher(&BinlogSyncer{BinlogInfo: &BinlogInfo{binlogDir: dir, binlogPrefix: "binlog"}}, nil, 0)
+
+	// Without the index file.
+	active, err := watcher.readActiveBinlog()
+	assert.NoError(err)
+	assert.Equal("binlog.000010", active)
+
+	assert.NoError(os.WriteFile(filepath.Join(dir, "binlog.index"), []byte("/var/lib/mysql/binlog.000009\n"), 0644))
+	active, err = watcher.readActiveBinlog()
+	assert.NoError(err)
+	assert.Equal("binlog.000009", active)
+
+	assert.EqualError(NewBinlogWatcher(&BinlogSyncer{BinlogInfo: &BinlogInfo{binlogDir: dir}}, nil, time.Minute).Watch(context.Background()), "database is required to flush binary logs")
+}
@@ -16,8 +16,11 @@ var OpenDB = func(dsn string) (*sql.DB, error) {
 }
 
 func init() {
-	BinlogCmd.AddCommand(BinlogSyncS3Cmd)
+	BinlogCmd.AddCommand(BinlogSyncCmd)
 	BinlogCmd.AddCommand(BinlogRestoreCmd)
+	BinlogCmd.AddCommand(BinlogInspectCmd)
+	BinlogCmd.AddCommand(BinlogFlashbackCmd)
+	BinlogCmd.AddCommand(BinlogStreamCmd)
 }
 
 var BinlogCmd = &cobra.Command{
@@ -0,0 +1,127 @@
+package binlogcmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/liweiyi88/onedump/binlog"
+	"github.com/liweiyi88/onedump/config"
+	"github.com/liweiyi88/onedump/env"
+	"github.com/spf13/cobra"
+)
+
+var (
+	startDateTime, stopBinlog, outputFile string
+	stopPosition                          int
+)
+
+func init() {
+	BinlogFlashbackCmd.Flags().StringVarP(&dir, "dir", "d", "", "A directory that contains binlog files (required)")
+	BinlogFlashbackCmd.Flags().StringVar(&startBinlog, "start-binlog", "", "Binlog file to start from, default: the first binlog file (optional)")
+	BinlogFlashbackCmd.Flags().IntVar(&startPosition, "start-position", 0, "Position in the start binlog file to start from (optional)")
+	BinlogFlashbackCmd.Flags().StringVar(&stopBinlog, "stop-binlog", "", "Binlog file to stop at, default: the last binlog file (optional)")
+	BinlogFlashbackCmd.Flags().IntVar(&stopPosition, "stop-position", 0, "Position in the stop binlog file to stop before, requires --stop-binlog (optional)")
+	BinlogFlashbackCmd.Flags().StringVar(&startDateTime, "start-datetime", "", "Undo the row changes from the datetime in UTC, e.g. 2025-06-03 01:00:00 (optional)")
+	BinlogFlashbackCmd.Flags().StringVar(&stopDateTime, "stop-datetime", "", "Undo the row changes before the datetime in UTC, e.g. 2025-06-03 01:05:00 (optional)")
+	BinlogFlashbackCmd.Flags().StringVar(&database, "database", "", "Only undo the row changes of the database (optional)")
+	BinlogFlashbackCmd.Flags().StringVar(&includeTables, "include-tables", "", "Comma separated table names or glob patterns to undo, e.g. orders,order_* (optional)")
+	BinlogFlashbackCmd.Flags().StringVar(&excludeTables, "exclude-tables", "", "Comma separated table names or glob patterns to skip, e.g. audit_* (optional)")
+	BinlogFlashbackCmd.Flags().BoolVar(&dryRun, "dry-run", false, "If true, print the undo statements instead of applying them. default: false (optional)")
+	BinlogFlashbackCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write the undo statements to the file for review instead of applying them (optional)")
+	BinlogFlashbackCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "prints additional debug information (optional)")
+	BinlogFlashbackCmd.MarkFlagRequired("dir")
+	BinlogFlashbackCmd.MarkFlagsOneRequired("start-binlog", "start-datetime")
+}
+
+var BinlogFlashbackCmd = &cobra.Command{
+	Use:   "flashback",
+	Short: "Undo the row changes of MySQL binlogs",
+	Long: `Undo the row changes of MySQL binlogs, e.g. an UPDATE or DELETE without WHERE.
+It generates the inverse statements of the row changes in reverse order and applies them in a transaction.
+The binlogs must be written with binlog_format=ROW and binlog_row_image=FULL.
+It requires the following environment variables:
+  - DATABASE_DSN // e.g. root@tcp(127.0.0.1)/
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envs, err := env.NewEnvResolver(env.WithDatabaseDSN()).Resolve()
+		if err != nil {
+			return err
+		}
+
+		if verbose {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+		}
+
+		options := binlog.FlashbackOptions{
+			StartBinlog:   startBinlog,
+			StartPosition: startPosition,
+			StopBinlog:    stopBinlog,
+			StopPosition:  stopPosition,
+			Database:      database,
+			Tables:        config.TableFilter{Include: splitList(includeTables), Exclude: splitList(excludeTables)},
+		}
+
+		if options.StartDateTime, err = parseDateTime("start-datetime", startDateTime); err != nil {
+			return err
+		}
+
+		if options.StopDateTime, err = parseDateTime("stop-datetime", stopDateTime); err != nil {
+			return err
+		}
+
+		flashback, err := binlog.NewFlashback(dir, options)
+		if err != nil {
+			return err
+		}
+
+		db, err := OpenDB(envs.DatabaseDSN)
+		if err != nil {
+			return fmt.Errorf("fail to open database, error: %v", err)
+		}
+
+		defer func() {
+			if err := db.Close(); err != nil {
+				slog.Error("fail to close DB", slog.Any("error", err))
+			}
+		}()
+
+		var output io.Writer
+		if dryRun {
+			output = cmd.OutOrStdout()
+		}
+
+		if strings.TrimSpace(outputFile) != "" {
+			file, err := os.Create(outputFile)
+			if err != nil {
+				return fmt.Errorf("fail to create output file: %s, error: %v", outputFile, err)
+			}
+
+			defer func() {
+				if err := file.Close(); err != nil {
+					slog.Error("fail to close output file", slog.String("file", outputFile), slog.Any("error", err))
+				}
+			}()
+
+			output = file
+		}
+
+		return flashback.Run(db, output)
+	},
+}
+
+func parseDateTime(option, value string) (time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return time.Time{}, nil
+	}
+
+	parsedTime, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid value of --%s option, error: %v", option, err)
+	}
+
+	return parsedTime, nil
+}
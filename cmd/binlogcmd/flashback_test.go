@@ -0,0 +1,77 @@
+package binlogcmd_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/cmd"
+	"github.com/liweiyi88/onedump/cmd/binlogcmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlashback(t *testing.T) {
+	assert := assert.New(t)
+
+	currentDir, err := os.Getwd()
+	assert.NoError(err)
+
+	binlogsDir := filepath.Join(currentDir, "..", "..", "testutils", "mysqlrestore", "binlogs")
+
+	t.Setenv("DATABASE_DSN", "root:root@tcp(127.0.0.1:33044)/")
+
+	t.Run("it should require a start boundary", func(t *testing.T) {
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "flashback", "--dir=" + binlogsDir})
+		assert.EqualError(cmd.Execute(), "at least one of the flags in the group [start-binlog start-datetime] is required")
+	})
+
+	t.Run("it should reject invalid datetime", func(t *testing.T) {
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "flashback", "--dir=" + binlogsDir, "--start-datetime=yesterday"})
+		assert.ErrorContains(cmd.Execute(), "invalid value of --start-datetime option")
+	})
+
+	t.Run("it should write the undo statements to the output file", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(err)
+
+		rows := sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE", "COLUMN_KEY"})
+		for i := range 51 {
+			rows.AddRow(fmt.Sprintf("c%d", i+1), "varchar(255)", "")
+		}
+
+		mock.ExpectQuery("SELECT COLUMN_NAME, COLUMN_TYPE, COLUMN_KEY FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION").
+			WithArgs("mysql", "user").
+			WillReturnRows(rows)
+		mock.ExpectClose()
+
+		binlogcmd.OpenDB = func(dsn string) (*sql.DB, error) {
+			return mockDB, nil
+		}
+
+		defer func() {
+			binlogcmd.OpenDB = func(dsn string) (*sql.DB, error) {
+				return sql.Open("mysql", dsn)
+			}
+		}()
+
+		outputFile := filepath.Join(t.TempDir(), "flashback.sql")
+
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{
+			"binlog", "flashback", "--dir=" + binlogsDir, "--start-binlog=mysql-bin.000003", "--start-datetime=2025-06-03 00:00:00",
+			"--stop-datetime=2025-06-04 00:00:00", "--output=" + outputFile,
+		})
+		assert.NoError(cmd.Execute())
+		assert.NoError(mock.ExpectationsWereMet())
+
+		content, err := os.ReadFile(outputFile)
+		assert.NoError(err)
+		assert.True(strings.Contains(string(content), "INSERT INTO `mysql`.`user`"), string(content))
+	})
+}
@@ -0,0 +1,111 @@
+package binlogcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/liweiyi88/onedump/binlog"
+	"github.com/spf13/cobra"
+)
+
+var format string
+
+func init() {
+	BinlogInspectCmd.Flags().StringVarP(&dir, "dir", "d", "", "A directory that contains binlog files (required)")
+	BinlogInspectCmd.Flags().StringVar(&format, "format", "table", "Set the report format, table or json, default: table (optional)")
+	BinlogInspectCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "prints additional debug information (optional)")
+	BinlogInspectCmd.MarkFlagRequired("dir")
+}
+
+var BinlogInspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Summarize MySQL binlog files",
+	Long: `Summarize MySQL binlog files, it reports the first and last event time and position, GTIDs,
+event counts by type and rows changed per table of each binlog file.
+The event times are in UTC, the same as the value of the --stop-datetime option of the restore command.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if format != "table" && format != "json" {
+			return fmt.Errorf("unsupported format: %s, support [table json]", format)
+		}
+
+		if verbose {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+		}
+
+		reports, err := binlog.InspectBinlogs(dir)
+		if err != nil {
+			return fmt.Errorf("fail to inspect binlog files, error: %v", err)
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(reports)
+		}
+
+		return writeInspectTable(cmd.OutOrStdout(), reports)
+	},
+}
+
+func writeInspectTable(output io.Writer, reports []*binlog.BinlogReport) error {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "FILE\tFIRST EVENT TIME\tLAST EVENT TIME\tFIRST POSITION\tLAST POSITION\tEND POSITION\tGTIDS")
+	for _, report := range reports {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			report.File,
+			formatEventTime(report.FirstEventTime),
+			formatEventTime(report.LastEventTime),
+			report.FirstPosition,
+			report.LastPosition,
+			report.EndPosition,
+			orDash(report.GTIDs),
+		)
+	}
+
+	for _, report := range reports {
+		fmt.Fprintf(w, "\n%s\n", report.File)
+
+		fmt.Fprintln(w, "  EVENT TYPE\tCOUNT")
+		for _, eventType := range slices.Sorted(maps.Keys(report.Events)) {
+			fmt.Fprintf(w, "  %s\t%d\n", eventType, report.Events[eventType])
+		}
+
+		if len(report.Rows) == 0 {
+			continue
+		}
+
+		fmt.Fprintln(w, "\n  TABLE\tINSERTED\tUPDATED\tDELETED")
+		for _, table := range slices.Sorted(maps.Keys(report.Rows)) {
+			rows := report.Rows[table]
+			fmt.Fprintf(w, "  %s\t%d\t%d\t%d\n", table, rows.Inserted, rows.Updated, rows.Deleted)
+		}
+	}
+
+	return w.Flush()
+}
+
+func formatEventTime(eventTime time.Time) string {
+	if eventTime.IsZero() {
+		return "-"
+	}
+
+	return eventTime.Format(time.DateTime)
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,53 @@
+package binlogcmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liweiyi88/onedump/binlog"
+	"github.com/liweiyi88/onedump/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspect(t *testing.T) {
+	assert := assert.New(t)
+
+	currentDir, err := os.Getwd()
+	assert.NoError(err)
+
+	binlogsDir := filepath.Join(currentDir, "..", "..", "testutils", "mysqlrestore", "binlogs")
+
+	var output bytes.Buffer
+	cmd := cmd.RootCmd
+	cmd.SetOut(&output)
+
+	defer cmd.SetOut(nil)
+
+	t.Run("it should reject unsupported formats", func(t *testing.T) {
+		cmd.SetArgs([]string{"binlog", "inspect", "--dir=" + binlogsDir, "--format=xml"})
+		assert.EqualError(cmd.Execute(), "unsupported format: xml, support [table json]")
+	})
+
+	t.Run("it should print the json report", func(t *testing.T) {
+		output.Reset()
+		cmd.SetArgs([]string{"binlog", "inspect", "--dir=" + binlogsDir, "--format=json"})
+		assert.NoError(cmd.Execute())
+
+		var reports []*binlog.BinlogReport
+		assert.NoError(json.Unmarshal(output.Bytes(), &reports))
+		assert.Len(reports, 3)
+		assert.Equal(1, reports[2].Rows["mysql.user"].Deleted)
+	})
+
+	t.Run("it should print the table report", func(t *testing.T) {
+		output.Reset()
+		cmd.SetArgs([]string{"binlog", "inspect", "--dir=" + binlogsDir, "--format=table"})
+		assert.NoError(cmd.Execute())
+
+		assert.Contains(output.String(), "mysql-bin.000003  2025-06-03 00:58:52  2025-06-03 01:00:43  4               2522           2609          -")
+		assert.Contains(output.String(), "  mysql.user  0         0        1")
+	})
+}
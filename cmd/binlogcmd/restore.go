@@ -2,6 +2,7 @@ package binlogcmd
 
 import (
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/liweiyi88/onedump/binlog"
+	"github.com/liweiyi88/onedump/config"
 	"github.com/liweiyi88/onedump/env"
 	"github.com/liweiyi88/onedump/fileutil"
 	"github.com/spf13/cobra"
@@ -16,7 +18,10 @@ import (
 
 var (
 	dir, mysqlbinlogPath, mysqlPath, stopDateTime, startBinlog, dumpFilePath string
+	startGTIDSet, excludeGTIDs, stopBeforeGTID                               string
+	database, includeTables, excludeTables, rewriteDBs                       string
 	startPosition                                                            int
+	native                                                                   bool
 )
 
 func init() {
@@ -27,11 +32,19 @@ func init() {
 	BinlogRestoreCmd.Flags().StringVar(&startBinlog, "start-binlog", "", "Binlog file to start recovery from (optional if --dump-file is provided)")
 	BinlogRestoreCmd.Flags().IntVar(&startPosition, "start-position", 0, "Position in the binlog file to begin recovery (optional if --dump-file is provided)")
 	BinlogRestoreCmd.Flags().StringVar(&dumpFilePath, "dump-file", "", "A Database dump file that contains binlog file and position (optional if --start-binlog and --start-position are provided)")
+	BinlogRestoreCmd.Flags().StringVar(&startGTIDSet, "start-gtid-set", "", "Skip the transactions of the GTID set, e.g. the gtid_purged of the dump. Defaults to the GTID_PURGED of --dump-file (optional)")
+	BinlogRestoreCmd.Flags().StringVar(&excludeGTIDs, "exclude-gtids", "", "Skip the transactions of the GTID set, e.g. a transaction that dropped a table by mistake (optional)")
+	BinlogRestoreCmd.Flags().StringVar(&stopBeforeGTID, "stop-before-gtid", "", "Stop the recovery right before the transaction of the GTID, e.g. 3E11FA47-71CA-11E1-9E33-C80AA9429562:23 (optional)")
+	BinlogRestoreCmd.Flags().StringVar(&database, "database", "", "Only restore the events of the database, it is the database name before --rewrite-db (optional)")
+	BinlogRestoreCmd.Flags().StringVar(&includeTables, "include-tables", "", "Comma separated table names or glob patterns to restore, requires --native, e.g. orders,order_* (optional)")
+	BinlogRestoreCmd.Flags().StringVar(&excludeTables, "exclude-tables", "", "Comma separated table names or glob patterns to skip, requires --native, e.g. audit_* (optional)")
+	BinlogRestoreCmd.Flags().StringVar(&rewriteDBs, "rewrite-db", "", "Comma separated database rewrites in the format of from->to, e.g. orders->orders_recovered (optional)")
 	BinlogRestoreCmd.Flags().BoolVar(&dryRun, "dry-run", false, "If true, output the parsed binlog events instead of applying them. default: false (optional)")
+	BinlogRestoreCmd.Flags().BoolVar(&native, "native", false, "If true, decode the binlog events and apply them through the database connection, mysqlbinlog and mysql are not required. default: false (optional)")
 	BinlogRestoreCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "prints additional debug information (optional)")
 	BinlogRestoreCmd.MarkFlagRequired("dir")
 	BinlogRestoreCmd.MarkFlagsRequiredTogether("start-binlog", "start-position")
-	BinlogRestoreCmd.MarkFlagsOneRequired("start-binlog", "dump-file")
+	BinlogRestoreCmd.MarkFlagsOneRequired("start-binlog", "dump-file", "start-gtid-set")
 }
 
 var BinlogRestoreCmd = &cobra.Command{
@@ -40,6 +53,8 @@ var BinlogRestoreCmd = &cobra.Command{
 	Long: `Restore the database from MySQL binlogs
 It requires the following environment variables:
   - DATABASE_DSN // e.g. root@tcp(127.0.0.1)/
+
+  BINLOG_ENCRYPTION_KEY is required if the binlog files are encrypted by binlog sync
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		envs, err := env.NewEnvResolver(env.WithDatabaseDSN()).Resolve()
@@ -67,13 +82,31 @@ It requires the following environment variables:
 		}
 
 		if strings.TrimSpace(dumpFilePath) != "" {
-			file, pos, err := extractBinlogStartFilePosition(dumpFilePath)
+			gtidPurged, err := extractGTIDPurged(dumpFilePath)
 			if err != nil {
+				return fmt.Errorf("fail to extract GTID_PURGED from dump file: %s, error: %v", dumpFilePath, err)
+			}
+
+			file, pos, err := extractBinlogStartFilePosition(dumpFilePath)
+
+			// A dump with GTID_PURGED can be restored without the binlog position.
+			if err != nil && gtidPurged == "" {
 				return fmt.Errorf("fail to extract binlog and position from dump file: %s, error: %v", dumpFilePath, err)
 			}
 
-			startBinlog = file
-			startPosition = pos
+			if err == nil {
+				startBinlog = file
+				startPosition = pos
+			}
+
+			if strings.TrimSpace(startGTIDSet) == "" {
+				startGTIDSet = gtidPurged
+			}
+		}
+
+		key, err := getDecryptionKey()
+		if err != nil {
+			return err
 		}
 
 		binlogRestorer := binlog.NewBinlogRestorer(
@@ -83,7 +116,15 @@ It requires the following environment variables:
 			binlog.WithMySQLBinlogPath(mysqlbinlogPath),
 			binlog.WithMySQLPath(mysqlPath),
 			binlog.WithDryRun(dryRun),
+			binlog.WithNative(native),
 			binlog.WithStopDateTime(stopDateTime),
+			binlog.WithStartGTIDSet(startGTIDSet),
+			binlog.WithExcludeGTIDs(excludeGTIDs),
+			binlog.WithStopBeforeGTID(stopBeforeGTID),
+			binlog.WithDatabase(database),
+			binlog.WithTables(config.TableFilter{Include: splitList(includeTables), Exclude: splitList(excludeTables)}),
+			binlog.WithRewriteDBs(splitList(rewriteDBs)),
+			binlog.WithDecryptionKey(key),
 			binlog.WithDatabaseDSN(envs.DatabaseDSN),
 		)
 
@@ -91,41 +132,89 @@ It requires the following environment variables:
 	},
 }
 
-func extractBinlogStartFilePosition(filePath string) (string, int, error) {
-	var dumpReader io.Reader
-	dumpFile, err := os.Open(filePath)
+// Get the key of the encrypted binlog files, it is optional as the binlog files may not be encrypted.
+func getDecryptionKey() ([]byte, error) {
+	encoded := os.Getenv(env.BINLOG_ENCRYPTION_KEY)
+	if encoded == "" {
+		return nil, nil
+	}
+
+	return binlog.ParseEncryptionKey(encoded)
+}
+
+// Split a comma separated option value, the empty items are ignored.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
 
+// Open the dump file, the gzipped dump file is decompressed.
+func openDumpFile(filePath string) (io.ReadCloser, error) {
+	dumpFile, err := os.Open(filePath)
 	if err != nil {
-		return "", 0, fmt.Errorf("fail to open dump file: %s, error: %v", filePath, err)
+		return nil, fmt.Errorf("fail to open dump file: %s, error: %v", filePath, err)
 	}
 
-	defer func() {
-		if err := dumpFile.Close(); err != nil {
-			slog.Error("fail to close dump file", slog.String("dumpFile", filePath), slog.Any("error", err))
-		}
-	}()
+	if !fileutil.IsGzipped(filePath) {
+		return dumpFile, nil
+	}
 
-	if fileutil.IsGzipped(filePath) {
-		gzipReader, err := gzip.NewReader(dumpFile)
-		if err != nil {
-			return "", 0, fmt.Errorf("fail to create a gzip reader, error: %v", err)
+	gzipReader, err := gzip.NewReader(dumpFile)
+	if err != nil {
+		if closeErr := dumpFile.Close(); closeErr != nil {
+			slog.Error("fail to close dump file", slog.String("dumpFile", filePath), slog.Any("error", closeErr))
 		}
 
-		defer func() {
-			if err := gzipReader.Close(); err != nil {
-				slog.Error("fail to close gzip reader when parse slow log", slog.Any("error", err))
-			}
-		}()
+		return nil, fmt.Errorf("fail to create a gzip reader, error: %v", err)
+	}
+
+	return &gzipDumpFile{Reader: gzipReader, file: dumpFile}, nil
+}
+
+type gzipDumpFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipDumpFile) Close() error {
+	return errors.Join(g.Reader.Close(), g.file.Close())
+}
 
-		dumpReader = gzipReader
-	} else {
-		dumpReader = dumpFile
+func closeDumpFile(dumpFile io.Closer, filePath string) {
+	if err := dumpFile.Close(); err != nil {
+		slog.Error("fail to close dump file", slog.String("dumpFile", filePath), slog.Any("error", err))
 	}
+}
+
+func extractBinlogStartFilePosition(filePath string) (string, int, error) {
+	dumpFile, err := openDumpFile(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+
+	defer closeDumpFile(dumpFile, filePath)
 
-	file, pos, err := binlog.ParseBinlogFilePosition(dumpReader)
+	file, pos, err := binlog.ParseBinlogFilePosition(dumpFile)
 	if err != nil {
-		return "", 0, fmt.Errorf("fail to parse binlog file position from %s, error: %v", dumpFilePath, err)
+		return "", 0, fmt.Errorf("fail to parse binlog file position from %s, error: %v", filePath, err)
 	}
 
 	return file, pos, nil
 }
+
+func extractGTIDPurged(filePath string) (string, error) {
+	dumpFile, err := openDumpFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	defer closeDumpFile(dumpFile, filePath)
+
+	return binlog.ParseGTIDPurged(dumpFile)
+}
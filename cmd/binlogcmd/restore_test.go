@@ -20,7 +20,7 @@ func TestRestoreMissingRequiredArgs(t *testing.T) {
 	err := cmd.Execute()
 
 	assert.Error(err)
-	assert.Equal("at least one of the flags in the group [start-binlog dump-file] is required", err.Error())
+	assert.Equal("at least one of the flags in the group [start-binlog dump-file start-gtid-set] is required", err.Error())
 }
 
 func TestMissingRequiredEnvs(t *testing.T) {
@@ -0,0 +1,101 @@
+package binlogcmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/liweiyi88/onedump/binlog"
+	"github.com/liweiyi88/onedump/env"
+	"github.com/spf13/cobra"
+)
+
+var serverID uint32
+
+func init() {
+	BinlogStreamCmd.Flags().StringVarP(&dir, "dir", "d", "", "A local directory that saves the streamed binlog files and the stream position (required)")
+	BinlogStreamCmd.Flags().Uint32Var(&serverID, "server-id", 0, "Replica server id that must be unique among the replicas of the source, default: a random id (optional)")
+	BinlogStreamCmd.Flags().StringVar(&startBinlog, "start-binlog", "", "Binlog file to start from if there is no saved stream position, default: the oldest binlog file of the server (optional)")
+	addDestinationFlags(BinlogStreamCmd)
+	BinlogStreamCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "prints additional debug information (optional)")
+	BinlogStreamCmd.MarkFlagRequired("dir")
+}
+
+var BinlogStreamCmd = &cobra.Command{
+	Use:   "stream",
+	Short: "Stream MySQL binlogs from a remote server via the replication protocol",
+	Long: `Stream MySQL binlogs from a remote server via the replication protocol.
+It connects as a replica, reassembles the binlog events into binlog files in a local directory and saves the closed files to the destinations:
+a local directory, AWS S3, SFTP, Dropbox, Google Drive or the storages of a job in a config file. Without a destination, the files are only kept in --dir.
+It resumes from the saved position after restarts and runs until it receives SIGINT or SIGTERM.
+The database user requires the REPLICATION SLAVE and REPLICATION CLIENT privileges.
+It requires the following environment variables:
+  - DATABASE_DSN // e.g. repl:password@tcp(db.example.com:3306)/
+  - AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY if --s3-bucket is set, AWS_SESSION_TOKEN is optional
+  - DROPBOX_REFRESH_TOKEN, DROPBOX_CLIENT_ID and DROPBOX_CLIENT_SECRET if --dropbox-path is set
+  - GDRIVE_EMAIL and GDRIVE_PRIVATE_KEY of a service account if --gdrive-folder-id is set
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envs, err := env.NewEnvResolver(env.WithDatabaseDSN()).Resolve()
+		if err != nil {
+			return err
+		}
+
+		if verbose {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+		}
+
+		if serverID == 0 {
+			// Avoid the small ids that are usually taken by the source and the other replicas.
+			serverID = 100000 + rand.Uint32N(1<<31)
+		}
+
+		cfg, err := binlog.NewReplicationConfig(envs.DatabaseDSN, serverID)
+		if err != nil {
+			return err
+		}
+
+		// The configured path of a destination is the folder of the binlog files.
+		destinations, err := getDestinations()
+		if err != nil {
+			return err
+		}
+
+		syncer := binlog.NewRemoteBinlogSyncer(dir, "", destinations...)
+
+		if startBinlog == "" {
+			if _, err := os.Stat(filepath.Join(dir, binlog.StreamStateFile)); os.IsNotExist(err) {
+				if startBinlog, err = queryFirstBinlog(envs.DatabaseDSN); err != nil {
+					return err
+				}
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		slog.Info("start streaming binlogs", slog.Any("server id", serverID), slog.String("dir", dir))
+
+		return syncer.Stream(ctx, cfg, startBinlog)
+	},
+}
+
+func queryFirstBinlog(dsn string) (string, error) {
+	db, err := OpenDB(dsn)
+	if err != nil {
+		return "", fmt.Errorf("fail to open database, error: %v", err)
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			slog.Error("fail to close DB", slog.Any("error", err))
+		}
+	}()
+
+	return binlog.QueryFirstBinlog(db)
+}
@@ -0,0 +1,69 @@
+package binlogcmd_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/binlog"
+	"github.com/liweiyi88/onedump/cmd"
+	"github.com/liweiyi88/onedump/cmd/binlogcmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("it should require the dir", func(t *testing.T) {
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "stream"})
+		assert.EqualError(cmd.Execute(), `required flag(s) "dir" not set`)
+	})
+
+	t.Run("it should require the AWS credentials to save to S3", func(t *testing.T) {
+		t.Setenv("DATABASE_DSN", "repl:secret@tcp(127.0.0.1:33044)/")
+		t.Setenv("AWS_REGION", "")
+
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "stream", "--dir=" + t.TempDir(), "--s3-bucket=onedump"})
+		assert.Error(cmd.Execute())
+	})
+
+	t.Run("it should require binary logs on the server", func(t *testing.T) {
+		t.Setenv("DATABASE_DSN", "repl:secret@tcp(127.0.0.1:33044)/")
+
+		mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(err)
+
+		mock.ExpectQuery(binlog.ShowBinaryLogsQuery).WillReturnRows(sqlmock.NewRows([]string{"Log_name", "File_size"}))
+		mock.ExpectClose()
+
+		binlogcmd.OpenDB = func(dsn string) (*sql.DB, error) {
+			return mockDB, nil
+		}
+
+		defer func() {
+			binlogcmd.OpenDB = func(dsn string) (*sql.DB, error) {
+				return sql.Open("mysql", dsn)
+			}
+		}()
+
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "stream", "--dir=" + t.TempDir(), "--s3-bucket=", "--start-binlog="})
+		assert.EqualError(cmd.Execute(), "no binary logs are found, sync requires log_bin to be set to ON")
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return an error if the job is not found", func(t *testing.T) {
+		t.Setenv("DATABASE_DSN", "repl:secret@tcp(127.0.0.1:33044)/")
+
+		configFile := filepath.Join(t.TempDir(), "onedump.yaml")
+		assert.NoError(os.WriteFile(configFile, []byte("jobs: []\n"), 0o644))
+
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "stream", "--dir=" + t.TempDir(), "--config=" + configFile, "--job=db1"})
+		assert.EqualError(cmd.Execute(), "job db1 is not found in "+configFile)
+	})
+}
@@ -0,0 +1,461 @@
+package binlogcmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/liweiyi88/onedump/binlog"
+	"github.com/liweiyi88/onedump/config"
+	"github.com/liweiyi88/onedump/env"
+	"github.com/liweiyi88/onedump/filesync"
+	"github.com/liweiyi88/onedump/storage"
+	"github.com/liweiyi88/onedump/storage/dropbox"
+	"github.com/liweiyi88/onedump/storage/gdrive"
+	"github.com/liweiyi88/onedump/storage/local"
+	"github.com/liweiyi88/onedump/storage/s3"
+	"github.com/liweiyi88/onedump/storage/sftp"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	localPath, sftpHost, sftpUser, sftpKey, sftpPath string
+	dropboxPath, gdriveFolderID, configFile, jobName string
+	retentionDump, sourceHost, sourceUser, sourceKey string
+	stateDir                                         string
+	sftpMaxAttempts, sourceMaxAttempts               int
+	watch, purge, retentionDryRun                    bool
+	flushInterval, retentionAge                      time.Duration
+)
+
+func init() {
+	addDestinationFlags(BinlogSyncCmd)
+	BinlogSyncCmd.Flags().BoolVar(&checksum, "checksum", false, "whether to save the checksum to avoid repeating file transfers, default: false (optional)")
+	BinlogSyncCmd.Flags().StringVar(&checksumFile, "checksum-file", "", "save checksum results in a specific file if --checksum=true, default: /path/to/sync/folder/checksum.onedump (optional)")
+	BinlogSyncCmd.Flags().BoolVar(&saveLog, "save-log", false, "whether to save the sync results in a log file, default: false (optional)")
+	BinlogSyncCmd.Flags().StringVar(&logFile, "log-file", "", "save result log in a specific file if --save-log=true. default: /path/to/binlogs/onedump-binlog-sync.log (optional)")
+	BinlogSyncCmd.Flags().StringVar(&manifestFile, "manifest-file", "", "save the manifest of the uploaded binlog files in a specific file. default: /path/to/binlogs/onedump-binlog-manifest.json (optional)")
+	BinlogSyncCmd.Flags().BoolVar(&skipActive, "skip-active", false, "whether to skip the binlog file that MySQL is writing, default: false (optional)")
+	BinlogSyncCmd.Flags().StringVar(&compression, "compress", "", "compress the binlog files before uploading them, gzip or zstd. default: no compression (optional)")
+	BinlogSyncCmd.Flags().BoolVar(&encrypt, "encrypt", false, "whether to encrypt the binlog files with the BINLOG_ENCRYPTION_KEY environment variable before uploading them, default: false (optional)")
+	BinlogSyncCmd.Flags().BoolVar(&watch, "watch", false, "keep running and upload each binlog file as soon as MySQL rotates it, the active binlog file is skipped. default: false (optional)")
+	BinlogSyncCmd.Flags().DurationVar(&flushInterval, "flush-interval", 0, "run FLUSH BINARY LOGS on the interval in --watch mode to bound the RPO, e.g. 5m. default: 0, disabled (optional)")
+	BinlogSyncCmd.Flags().BoolVar(&purge, "purge", false, "run PURGE BINARY LOGS TO for the binlog files that are uploaded and verified by checksum, the active binlog file is never purged. default: false (optional)")
+	BinlogSyncCmd.Flags().DurationVar(&retentionAge, "retention", 0, "delete the archived binlog files that were uploaded before the duration, e.g. 720h. default: 0, keep forever (optional)")
+	BinlogSyncCmd.Flags().StringVar(&retentionDump, "retention-dump", "", "delete the archived binlog files before the binlog file of the dump file, e.g. the oldest retained full dump (optional)")
+	BinlogSyncCmd.Flags().BoolVar(&retentionDryRun, "retention-dry-run", false, "only report the binlog files that would be purged and deleted by the retention. default: false (optional)")
+	BinlogSyncCmd.Flags().StringVar(&sourceHost, "source-ssh-host", "", "read the binlog files from the database host over SFTP instead of the local disk, e.g. db1.example.com:22 (optional)")
+	BinlogSyncCmd.Flags().StringVar(&sourceUser, "source-ssh-user", "", "the SSH user of the database host, required with --source-ssh-host (optional)")
+	BinlogSyncCmd.Flags().StringVar(&sourceKey, "source-ssh-key", "", "the base64 encoded ssh private key content or the ssh private key file path or the raw private content, required with --source-ssh-host (optional)")
+	BinlogSyncCmd.Flags().IntVar(&sourceMaxAttempts, "source-max-attempts", 0, "the maximum number of retries of reading a binlog file from the database host; by default, retries are unlimited (optional)")
+	BinlogSyncCmd.Flags().StringVar(&stateDir, "state-dir", "", "the local directory of the manifest and the result log, required with --source-ssh-host. default: /path/to/binlogs (optional)")
+	BinlogSyncCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "prints additional debug information (optional)")
+	BinlogSyncCmd.MarkFlagsRequiredTogether("source-ssh-host", "source-ssh-user", "source-ssh-key")
+	BinlogSyncCmd.MarkFlagsOneRequired("local-path", "s3-bucket", "sftp-host", "dropbox-path", "gdrive-folder-id", "config")
+
+	BinlogSyncCmd.AddCommand(BinlogSyncS3Cmd)
+}
+
+// Add the flags of the destinations that are read by getDestinations, they are shared by binlog sync and binlog stream.
+func addDestinationFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&localPath, "local-path", "", "A local directory that used for saving binlog files, e.g. a mounted network drive (optional)")
+	cmd.Flags().StringVarP(&s3Bucket, "s3-bucket", "b", "", "AWS S3 bucket name that used for saving binlog files (optional)")
+	cmd.Flags().StringVarP(&s3Prefix, "s3-prefix", "p", "", "AWS S3 file prefix (folder) that used for saving binlog files (optional)")
+	cmd.Flags().StringVar(&sftpHost, "sftp-host", "", "the remote SSH host that used for saving binlog files (optional)")
+	cmd.Flags().StringVar(&sftpUser, "sftp-user", "", "the remote SSH user, required with --sftp-host (optional)")
+	cmd.Flags().StringVar(&sftpKey, "sftp-key", "", "the base64 encoded ssh private key content or the ssh private key file path or the raw private content, required with --sftp-host (optional)")
+	cmd.Flags().StringVar(&sftpPath, "sftp-path", "", "the remote directory that used for saving binlog files, required with --sftp-host (optional)")
+	cmd.Flags().IntVar(&sftpMaxAttempts, "sftp-max-attempts", 0, "the maximum number of retries of a SFTP transfer; by default, retries are unlimited (optional)")
+	cmd.Flags().StringVar(&dropboxPath, "dropbox-path", "", "Dropbox folder that used for saving binlog files, e.g. /binlogs (optional)")
+	cmd.Flags().StringVar(&gdriveFolderID, "gdrive-folder-id", "", "Google Drive folder id that used for saving binlog files (optional)")
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "jobs yaml file path, save binlog files to the storages of the job (optional)")
+	cmd.Flags().StringVarP(&jobName, "job", "j", "", "the job name in the config file (required with --config)")
+	cmd.MarkFlagsRequiredTogether("sftp-host", "sftp-user", "sftp-key", "sftp-path")
+	cmd.MarkFlagsRequiredTogether("config", "job")
+}
+
+var BinlogSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync local MySQL binlog files to one or more destinations",
+	Long: `Sync local MySQL binlog files to one or more destinations: a local directory, AWS S3, SFTP, Dropbox, Google Drive or the storages of a job in a config file.
+It requires the following environment variables:
+  - DATABASE_DSN // e.g. root@tcp(127.0.0.1)/
+  - AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY if --s3-bucket is set, AWS_SESSION_TOKEN is optional
+  - DROPBOX_REFRESH_TOKEN, DROPBOX_CLIENT_ID and DROPBOX_CLIENT_SECRET if --dropbox-path is set
+  - GDRIVE_EMAIL and GDRIVE_PRIVATE_KEY of a service account if --gdrive-folder-id is set
+  - BINLOG_ENCRYPTION_KEY if --encrypt is set, a base64 encoded 256-bit key, e.g. openssl rand -base64 32
+Use --source-ssh-host to read the binlog files from the database host over SFTP, so one backup host can archive many database servers.
+`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envs, err := env.NewEnvResolver(env.WithDatabaseDSN()).Resolve()
+		if err != nil {
+			return err
+		}
+
+		if verbose {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+		}
+
+		if flushInterval > 0 && !watch {
+			return errors.New("--flush-interval option requires --watch")
+		}
+
+		opts, err := getSyncerOptions()
+		if err != nil {
+			return err
+		}
+
+		if sourceHost != "" {
+			source, err := getSftpSource()
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				if err := source.Close(); err != nil {
+					slog.Error("fail to close sftp source", slog.Any("error", err))
+				}
+			}()
+
+			opts = append(opts, binlog.WithSource(source))
+		}
+
+		opts = append(opts, binlog.WithStateDir(stateDir))
+
+		destinations, err := getDestinations()
+		if err != nil {
+			return err
+		}
+
+		binlogInfo, err := getBinlogInfo(envs.DatabaseDSN)
+		if err != nil {
+			return err
+		}
+
+		fs := filesync.NewFileSync(checksum, checksumFile)
+		syncer := binlog.NewBinlogSyncer("", saveLog, logFile, fs, binlogInfo, opts...)
+
+		// The database is required to flush and purge the binary logs.
+		var db *sql.DB
+		if flushInterval > 0 || purge {
+			if db, err = OpenDB(envs.DatabaseDSN); err != nil {
+				return fmt.Errorf("fail to open database, error: %v", err)
+			}
+
+			defer func() {
+				if err := db.Close(); err != nil {
+					slog.Error("fail to close DB", slog.Any("error", err))
+				}
+			}()
+		}
+
+		retention, err := getRetention(syncer, db)
+		if err != nil {
+			return err
+		}
+
+		if watch {
+			return watchBinlogs(syncer, db, retention, destinations)
+		}
+
+		if err := syncer.Sync(destinations...); err != nil {
+			return err
+		}
+
+		if !retention.Enabled() {
+			return nil
+		}
+
+		report, err := retention.Apply(destinations...)
+		if report != nil {
+			writeRetentionReport(cmd.OutOrStdout(), report)
+		}
+
+		return err
+	},
+}
+
+// Get the retention of the binlog files from the flags.
+func getRetention(syncer *binlog.BinlogSyncer, db *sql.DB) (*binlog.BinlogRetention, error) {
+	var dumpBinlog string
+	if strings.TrimSpace(retentionDump) != "" {
+		file, _, err := extractBinlogStartFilePosition(retentionDump)
+		if err != nil {
+			return nil, err
+		}
+
+		dumpBinlog = file
+	}
+
+	return binlog.NewBinlogRetention(
+		syncer,
+		db,
+		binlog.WithPurge(purge),
+		binlog.WithMaxAge(retentionAge),
+		binlog.WithDumpBinlog(dumpBinlog),
+		binlog.WithRetentionDryRun(retentionDryRun),
+	), nil
+}
+
+func writeRetentionReport(w io.Writer, report *binlog.RetentionReport) {
+	action := ""
+	if report.DryRun {
+		action = "would be "
+	}
+
+	if report.PurgeTo == "" {
+		fmt.Fprintf(w, "No binary logs %spurged on the server\n", action)
+	} else {
+		fmt.Fprintf(w, "Binary logs %spurged to %s: %s\n", action, report.PurgeTo, strings.Join(report.Purged, ", "))
+	}
+
+	if len(report.Deleted) == 0 {
+		fmt.Fprintf(w, "No archived binlog files %sdeleted\n", action)
+	} else {
+		fmt.Fprintf(w, "Archived binlog files %sdeleted: %s\n", action, strings.Join(report.Deleted, ", "))
+	}
+}
+
+// Get the syncer options from the flags shared by binlog sync and binlog sync s3.
+func getSyncerOptions() ([]binlog.BinlogSyncerOption, error) {
+	if compression != "" && !slices.Contains(binlog.Compressions, compression) {
+		return nil, fmt.Errorf("unsupported compression: %s, support %v", compression, binlog.Compressions)
+	}
+
+	opts := []binlog.BinlogSyncerOption{
+		binlog.WithManifestFile(manifestFile),
+		binlog.WithSkipActive(skipActive),
+		binlog.WithCompression(compression),
+	}
+
+	if encrypt {
+		envs, err := env.NewEnvResolver(env.WithBinlogEncryptionKey()).Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := binlog.ParseEncryptionKey(envs.BinlogEncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, binlog.WithEncryptionKey(key))
+	}
+
+	return opts, nil
+}
+
+// Get the SFTP source of the binlog files on the database host.
+// The watch mode and the checksum read the local binlog files, so they are not supported.
+func getSftpSource() (*binlog.SftpSource, error) {
+	if watch {
+		return nil, errors.New("--watch option does not support --source-ssh-host")
+	}
+
+	if checksum {
+		return nil, errors.New("--checksum option does not support --source-ssh-host, the manifest already avoids repeating file transfers")
+	}
+
+	if strings.TrimSpace(stateDir) == "" {
+		return nil, errors.New("--state-dir option is required with --source-ssh-host")
+	}
+
+	return binlog.NewSftpSource(sourceHost, sourceUser, sourceKey, sourceMaxAttempts), nil
+}
+
+// Upload the binlog files as soon as MySQL rotates them until SIGINT or SIGTERM is received.
+func watchBinlogs(syncer *binlog.BinlogSyncer, db *sql.DB, retention *binlog.BinlogRetention, destinations []storage.Storage) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var opts []binlog.BinlogWatcherOption
+	if retention.Enabled() {
+		opts = append(opts, binlog.WithRetention(retention))
+	}
+
+	return binlog.NewBinlogWatcher(syncer, db, flushInterval, opts...).Watch(ctx, destinations...)
+}
+
+// A SFTP destination that transfers each binlog file with its own connection,
+// sftp.Sftp keeps the offset of a single transfer so it cannot save files concurrently.
+type sftpDestination struct {
+	config *sftp.SftpConifg
+	path   string
+}
+
+func (d *sftpDestination) Save(reader io.Reader, pathGenerator storage.PathGeneratorFunc) error {
+	s := sftp.NewSftp(d.config)
+	s.Path = d.path
+
+	return s.Save(reader, pathGenerator)
+}
+
+func (d *sftpDestination) Delete(pathGenerator storage.PathGeneratorFunc) error {
+	s := sftp.NewSftp(d.config)
+	s.Path = d.path
+
+	return s.Delete(pathGenerator)
+}
+
+// Get the destinations from the flags and the config file, the configured path of a destination is the folder of the binlog files.
+func getDestinations() ([]storage.Storage, error) {
+	var destinations []storage.Storage
+
+	if configFile != "" {
+		job, err := findJob(configFile, jobName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range job.Storage.Local {
+			destinations = append(destinations, l)
+		}
+
+		for _, s := range job.Storage.S3 {
+			destinations = append(destinations, s)
+		}
+
+		for _, s := range job.Storage.Sftp {
+			destinations = append(destinations, &sftpDestination{
+				config: &sftp.SftpConifg{Host: s.SshHost, User: s.SshUser, Key: s.SshKey, MaxAttempts: s.MaxAttempts},
+				path:   s.Path,
+			})
+		}
+
+		for _, d := range job.Storage.Dropbox {
+			destinations = append(destinations, d)
+		}
+
+		for _, g := range job.Storage.GDrive {
+			destinations = append(destinations, g)
+		}
+
+		if len(destinations) == 0 {
+			return nil, fmt.Errorf("job %s has no storage", jobName)
+		}
+	}
+
+	if localPath != "" {
+		destinations = append(destinations, &local.Local{Path: localPath})
+	}
+
+	if s3Bucket != "" {
+		envs, err := env.NewEnvResolver(env.WithAWS()).Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		credentials := envs.AWSCredentials
+		destinations = append(destinations, s3.NewS3(
+			s3Bucket,
+			s3Prefix,
+			credentials.Region,
+			credentials.AccessKeyID,
+			credentials.SecretAccessKey,
+			credentials.SessionToken))
+	}
+
+	if sftpHost != "" {
+		destinations = append(destinations, &sftpDestination{
+			config: &sftp.SftpConifg{Host: sftpHost, User: sftpUser, Key: sftpKey, MaxAttempts: sftpMaxAttempts},
+			path:   sftpPath,
+		})
+	}
+
+	if dropboxPath != "" {
+		envs, err := env.NewEnvResolver(env.WithDropbox()).Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		credentials := envs.DropboxCredentials
+		destinations = append(destinations, &dropbox.Dropbox{
+			Path:         dropboxPath,
+			RefreshToken: credentials.RefreshToken,
+			ClientId:     credentials.ClientId,
+			ClientSecret: credentials.ClientSecret,
+		})
+	}
+
+	if gdriveFolderID != "" {
+		envs, err := env.NewEnvResolver(env.WithGDrive()).Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		credentials := envs.GDriveCredentials
+		destinations = append(destinations, &gdrive.GDrive{
+			Email:      credentials.Email,
+			PrivateKey: credentials.PrivateKey,
+			FolderId:   gdriveFolderID,
+		})
+	}
+
+	// The local storage does not create the folder.
+	for _, destination := range destinations {
+		if l, ok := destination.(*local.Local); ok {
+			if err := os.MkdirAll(l.Path, 0o755); err != nil {
+				return nil, fmt.Errorf("fail to create local directory: %s, error: %v", l.Path, err)
+			}
+		}
+	}
+
+	return destinations, nil
+}
+
+// Find the job in the config file.
+func findJob(configFile, jobName string) (*config.Job, error) {
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job file from %s, error: %v", configFile, err)
+	}
+
+	var oneDump config.Dump
+	if err := yaml.Unmarshal(content, &oneDump); err != nil {
+		return nil, fmt.Errorf("failed to read job content from %s, error: %v", configFile, err)
+	}
+
+	for _, job := range oneDump.Jobs {
+		if job.Name == jobName {
+			return job, nil
+		}
+	}
+
+	return nil, fmt.Errorf("job %s is not found in %s", jobName, configFile)
+}
+
+// Get the binlog directory and files of the database.
+func getBinlogInfo(dsn string) (*binlog.BinlogInfo, error) {
+	db, err := OpenDB(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open database, error: %v", err)
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			slog.Error("fail to close DB", slog.Any("error", err))
+		}
+	}()
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("fail to connect to database, error: %v", err)
+	}
+
+	binlogInfo, err := binlog.NewBinlogQuerier(db).GetBinlogInfo()
+	if err != nil {
+		return nil, fmt.Errorf("fail to get binlog info, error: %v", err)
+	}
+
+	return binlogInfo, nil
+}
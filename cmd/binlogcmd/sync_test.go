@@ -0,0 +1,184 @@
+package binlogcmd_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/binlog"
+	"github.com/liweiyi88/onedump/cmd"
+	"github.com/liweiyi88/onedump/cmd/binlogcmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncCmd(t *testing.T) {
+	assert := assert.New(t)
+
+	currentDir, err := os.Getwd()
+	assert.NoError(err)
+
+	binlogsDir := filepath.Join(currentDir, "..", "..", "testutils", "mysqlrestore", "binlogs")
+
+	t.Setenv("DATABASE_DSN", "root:root@tcp(127.0.0.1:33044)/")
+
+	mockBinlogInfo := func() {
+		mockDB, mock, err := sqlmock.New()
+		assert.NoError(err)
+
+		binlogcmd.OpenDB = func(dsn string) (*sql.DB, error) {
+			return mockDB, nil
+		}
+
+		mock.ExpectQuery(binlog.ShowLogBinQuery).WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("log_bin", "ON"))
+		mock.ExpectQuery(regexp.QuoteMeta(binlog.VersionQuery)).WillReturnRows(sqlmock.NewRows([]string{"mysql_version"}).AddRow("8.0.42"))
+		mock.ExpectQuery(binlog.ShowMasterStatusQuery).WillReturnRows(
+			sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}).AddRow("mysql-bin.000003", 2609, "", "", ""),
+		)
+		mock.ExpectQuery(binlog.ShowLogBinBasenameQuery).WillReturnRows(
+			sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("log_bin_basename", filepath.Join(binlogsDir, "mysql-bin")),
+		)
+		mock.ExpectClose()
+	}
+
+	defer func() {
+		binlogcmd.OpenDB = func(dsn string) (*sql.DB, error) {
+			return sql.Open("mysql", dsn)
+		}
+	}()
+
+	assertSynced := func(dir string) {
+		for _, name := range []string{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003"} {
+			expected, err := os.ReadFile(filepath.Join(binlogsDir, name))
+			assert.NoError(err)
+
+			actual, err := os.ReadFile(filepath.Join(dir, name))
+			assert.NoError(err)
+			assert.True(bytes.Equal(expected, actual))
+		}
+	}
+
+	t.Run("it should require a destination", func(t *testing.T) {
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync"})
+		assert.ErrorContains(cmd.Execute(), "at least one of the flags in the group [local-path s3-bucket sftp-host dropbox-path gdrive-folder-id config] is required")
+	})
+
+	t.Run("it should sync binlog files to multiple local destinations", func(t *testing.T) {
+		mockBinlogInfo()
+
+		localPath := filepath.Join(t.TempDir(), "binlogs")
+		jobPath := filepath.Join(t.TempDir(), "job", "binlogs")
+
+		manifestFile := filepath.Join(t.TempDir(), binlog.ManifestFile)
+
+		configFile := filepath.Join(t.TempDir(), "onedump.yaml")
+		assert.NoError(os.WriteFile(configFile, []byte(`jobs:
+- name: db1
+  dbdriver: mysql
+  dbdsn: root@tcp(127.0.0.1)/
+  storage:
+    local:
+      - path: `+jobPath+`
+`), 0o644))
+
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync", "--local-path=" + localPath, "--config=" + configFile, "--job=db1", "--checksum=false", "--save-log=false", "--manifest-file=" + manifestFile})
+		assert.NoError(cmd.Execute())
+
+		assertSynced(localPath)
+		assertSynced(jobPath)
+		assert.FileExists(filepath.Join(localPath, binlog.ManifestFile))
+		assert.FileExists(manifestFile)
+	})
+
+	t.Run("it should return an error if the job is not found", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), "onedump.yaml")
+		assert.NoError(os.WriteFile(configFile, []byte("jobs: []\n"), 0o644))
+
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync", "--config=" + configFile, "--job=db1", "--local-path="})
+		assert.EqualError(cmd.Execute(), "job db1 is not found in "+configFile)
+	})
+
+	t.Run("it should reject an unsupported compression", func(t *testing.T) {
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync", "--local-path=" + t.TempDir(), "--compress=lz4"})
+		assert.EqualError(cmd.Execute(), "unsupported compression: lz4, support [gzip zstd]")
+	})
+
+	t.Run("it should require the encryption key to encrypt binlog files", func(t *testing.T) {
+		t.Setenv("BINLOG_ENCRYPTION_KEY", "")
+
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync", "--local-path=" + t.TempDir(), "--compress=", "--encrypt"})
+		assert.EqualError(cmd.Execute(), "missing required environment variable BINLOG_ENCRYPTION_KEY")
+	})
+
+	t.Run("it should compress and encrypt binlog files", func(t *testing.T) {
+		mockBinlogInfo()
+
+		key := bytes.Repeat([]byte("k"), 32)
+		t.Setenv("BINLOG_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(key))
+
+		localPath := t.TempDir()
+		manifestFile := filepath.Join(t.TempDir(), binlog.ManifestFile)
+
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync", "--local-path=" + localPath, "--config=", "--job=", "--manifest-file=" + manifestFile, "--compress=zstd", "--encrypt"})
+		assert.NoError(cmd.Execute())
+
+		assert.FileExists(filepath.Join(localPath, "mysql-bin.000001.zst.enc"))
+		assert.NoFileExists(filepath.Join(localPath, "mysql-bin.000001"))
+
+		assert.NoError(binlog.DecodeBinlogs(localPath, key))
+		assertSynced(localPath)
+	})
+
+	t.Run("it should report the expired binlog files in the dry run", func(t *testing.T) {
+		mockBinlogInfo()
+
+		localPath := t.TempDir()
+		manifestFile := filepath.Join(t.TempDir(), binlog.ManifestFile)
+
+		var output bytes.Buffer
+		cmd := cmd.RootCmd
+		cmd.SetOut(&output)
+		defer cmd.SetOut(nil)
+
+		cmd.SetArgs([]string{"binlog", "sync", "--local-path=" + localPath, "--manifest-file=" + manifestFile, "--compress=", "--encrypt=false", "--retention=1ns", "--retention-dry-run"})
+		assert.NoError(cmd.Execute())
+
+		assert.Equal("No binary logs would be purged on the server\nArchived binlog files would be deleted: mysql-bin.000001, mysql-bin.000002\n", output.String())
+		assertSynced(localPath)
+	})
+
+	t.Run("it should require --watch to flush binary logs", func(t *testing.T) {
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync", "--local-path=" + t.TempDir(), "--flush-interval=5m"})
+		assert.EqualError(cmd.Execute(), "--flush-interval option requires --watch")
+	})
+
+	t.Run("it should require the state directory to read binlog files over SFTP", func(t *testing.T) {
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync", "--local-path=" + t.TempDir(), "--flush-interval=0", "--source-ssh-host=127.0.0.1", "--source-ssh-user=root", "--source-ssh-key=key"})
+		assert.EqualError(cmd.Execute(), "--state-dir option is required with --source-ssh-host")
+	})
+
+	t.Run("it should not watch binlog files over SFTP", func(t *testing.T) {
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync", "--local-path=" + t.TempDir(), "--state-dir=" + t.TempDir(), "--watch"})
+		assert.EqualError(cmd.Execute(), "--watch option does not support --source-ssh-host")
+	})
+
+	// Flags stay changed after the command runs, so it runs last.
+	t.Run("it should require all the sftp options", func(t *testing.T) {
+		cmd := cmd.RootCmd
+		cmd.SetArgs([]string{"binlog", "sync", "--sftp-host=127.0.0.1"})
+		assert.ErrorContains(cmd.Execute(), "if any flags in the group [sftp-host sftp-user sftp-key sftp-path] are set they must all be set")
+	})
+}
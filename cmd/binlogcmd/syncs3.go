@@ -1,7 +1,6 @@
 package binlogcmd
 
 import (
-	"fmt"
 	"log/slog"
 
 	"github.com/liweiyi88/onedump/binlog"
@@ -12,8 +11,8 @@ import (
 )
 
 var (
-	checksumFile, logFile string
-	checksum, saveLog     bool
+	checksumFile, logFile, manifestFile, compression string
+	checksum, saveLog, skipActive, encrypt           bool
 )
 
 func init() {
@@ -26,11 +25,15 @@ func init() {
 	BinlogSyncS3Cmd.Flags().StringVar(&checksumFile, "checksum-file", "", "save checksum results in a specific file if --checksum=true, default: /path/to/sync/folder/checksum.onedump (optional)")
 	BinlogSyncS3Cmd.Flags().BoolVar(&saveLog, "save-log", false, "whether to save the sync results in a log file, default: false (optional)")
 	BinlogSyncS3Cmd.Flags().StringVar(&logFile, "log-file", "", "save result log in a specific file if --save-log=true. default: /path/to/binlogs/onedump-binlog-sync.log (optional)")
+	BinlogSyncS3Cmd.Flags().StringVar(&manifestFile, "manifest-file", "", "save the manifest of the uploaded binlog files in a specific file. default: /path/to/binlogs/onedump-binlog-manifest.json (optional)")
+	BinlogSyncS3Cmd.Flags().BoolVar(&skipActive, "skip-active", false, "whether to skip the binlog file that MySQL is writing, default: false (optional)")
+	BinlogSyncS3Cmd.Flags().StringVar(&compression, "compress", "", "compress the binlog files before uploading them, gzip or zstd. default: no compression (optional)")
+	BinlogSyncS3Cmd.Flags().BoolVar(&encrypt, "encrypt", false, "whether to encrypt the binlog files with the BINLOG_ENCRYPTION_KEY environment variable before uploading them, default: false (optional)")
 	BinlogSyncS3Cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "prints additional debug information (optional)")
 }
 
 var BinlogSyncS3Cmd = &cobra.Command{
-	Use:   "sync s3",
+	Use:   "s3",
 	Short: "Sync local MySQL binlog files to an AWS S3 bucket",
 	Long: `Sync local Mysql binlog files to an AWS S3 bucket.
 It requires the following environment variables:
@@ -40,6 +43,7 @@ It requires the following environment variables:
   - DATABASE_DSN // e.g. root@tcp(127.0.0.1)/
 
   AWS_SESSION_TOKEN is optional unless you use a temporary credentials
+  BINLOG_ENCRYPTION_KEY is required if --encrypt is set
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		envs, err := env.NewEnvResolver(
@@ -55,26 +59,9 @@ It requires the following environment variables:
 			slog.SetLogLoggerLevel(slog.LevelDebug)
 		}
 
-		db, err := OpenDB(envs.DatabaseDSN)
+		binlogInfo, err := getBinlogInfo(envs.DatabaseDSN)
 		if err != nil {
-			return fmt.Errorf("fail to open database, error: %v", err)
-		}
-
-		defer func() {
-			if err := db.Close(); err != nil {
-				slog.Error("fail to close DB", slog.Any("error", err))
-			}
-		}()
-
-		if err := db.Ping(); err != nil {
-			return fmt.Errorf("fail to connect to database, error: %v", err)
-		}
-
-		querier := binlog.NewBinlogQuerier(db)
-		binlogInfo, err := querier.GetBinlogInfo()
-
-		if err != nil {
-			return fmt.Errorf("fail to get binlog info, error: %v", err)
+			return err
 		}
 
 		credentials := envs.AWSCredentials
@@ -86,8 +73,13 @@ It requires the following environment variables:
 			credentials.SecretAccessKey,
 			credentials.SessionToken)
 
+		opts, err := getSyncerOptions()
+		if err != nil {
+			return err
+		}
+
 		fs := filesync.NewFileSync(checksum, checksumFile)
-		syncer := binlog.NewBinlogSyncer(s3Prefix, saveLog, logFile, fs, binlogInfo)
+		syncer := binlog.NewBinlogSyncer(s3Prefix, saveLog, logFile, fs, binlogInfo, opts...)
 		return syncer.Sync(s3)
 	},
 }
@@ -2,7 +2,9 @@ package downloadcmd
 
 import (
 	"context"
+	"os"
 
+	"github.com/liweiyi88/onedump/binlog"
 	"github.com/liweiyi88/onedump/env"
 	"github.com/liweiyi88/onedump/storage/s3"
 	"github.com/spf13/cobra"
@@ -31,6 +33,10 @@ It requires the following environment variables:
   - AWS_SECRET_ACCESS_KEY
 
 AWS_SESSION_TOKEN is optional unless you use a temporary credentials
+BINLOG_ENCRYPTION_KEY is required to decrypt the binlog files that are encrypted by binlog sync
+
+The binlog files that are compressed or encrypted by binlog sync are decoded after they are downloaded,
+only the files listed in the downloaded onedump-binlog-manifest.json are decoded.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		envs, err := env.NewEnvResolver(env.WithAWS()).Resolve()
@@ -38,14 +44,27 @@ AWS_SESSION_TOKEN is optional unless you use a temporary credentials
 			return err
 		}
 
+		var key []byte
+		if encoded := os.Getenv(env.BINLOG_ENCRYPTION_KEY); encoded != "" {
+			if key, err = binlog.ParseEncryptionKey(encoded); err != nil {
+				return err
+			}
+		}
+
 		credentials := envs.AWSCredentials
 
-		return s3.NewS3(
+		err = s3.NewS3(
 			bucket,
 			"",
 			credentials.Region,
 			credentials.AccessKeyID,
 			credentials.SecretAccessKey,
 			credentials.SessionToken).DownloadObjects(context.Background(), prefix, dir)
+
+		if err != nil {
+			return err
+		}
+
+		return binlog.DecodeBinlogs(dir, key)
 	},
 }
@@ -0,0 +1,228 @@
+package restorecmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/liweiyi88/onedump/config"
+	"github.com/liweiyi88/onedump/env"
+	"github.com/liweiyi88/onedump/restore"
+	"github.com/liweiyi88/onedump/storage"
+)
+
+var (
+	dumpFile, configFile, jobName, storageType, driver      string
+	verbose, singleTransaction, onErrorStop, createDatabase bool
+)
+
+var storageTypes = []string{"local", "s3", "gdrive", "dropbox", "sftp"}
+
+const (
+	mysqlDriver      = "mysql"
+	postgresqlDriver = "postgresql"
+)
+
+func init() {
+	RestoreCmd.Flags().StringVarP(&dumpFile, "file", "f", "", "the dump file path, it is the path (or key) in the storage when --job is specified. defaults to the storage's configured path (optional with --job)")
+	RestoreCmd.Flags().StringVarP(&configFile, "config", "c", "", "jobs yaml file path, read the dump from the job's storage (optional)")
+	RestoreCmd.Flags().StringVarP(&jobName, "job", "j", "", "the job name in the config file (required with --config)")
+	RestoreCmd.Flags().StringVarP(&storageType, "storage", "s", "", fmt.Sprintf("the job's storage to read the dump from: %s. it can be omitted if the job has only one storage (optional)", strings.Join(storageTypes, ", ")))
+	RestoreCmd.Flags().StringVarP(&driver, "driver", "d", "", "the target database: mysql or postgresql. defaults to the job's database if --job is specified, otherwise mysql (optional)")
+	RestoreCmd.Flags().BoolVar(&singleTransaction, "single-transaction", false, "postgresql only, restore in a single transaction, nothing is restored if any statement fails (optional)")
+	RestoreCmd.Flags().BoolVar(&onErrorStop, "on-error-stop", true, "postgresql only, stop at the first failing statement. set it to false to run all statements and report the failures at the end (optional)")
+	RestoreCmd.Flags().BoolVar(&createDatabase, "create-database", false, "postgresql only, create the database in DATABASE_DSN before restoring, it fails if the database exists (optional)")
+	RestoreCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "prints additional debug information (optional)")
+	RestoreCmd.MarkFlagsRequiredTogether("config", "job")
+	RestoreCmd.MarkFlagsOneRequired("file", "config")
+}
+
+var RestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a database dump to MySQL or PostgreSQL",
+	Long: `Restore a SQL dump to MySQL or PostgreSQL, the dump can be read from a local path or any storage of a job, gzipped dumps are decompressed automatically.
+It requires the following environment variables:
+  - DATABASE_DSN // e.g. root@tcp(127.0.0.1)/mydb or postgres://postgres@127.0.0.1:5432/mydb
+`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envs, err := env.NewEnvResolver(env.WithDatabaseDSN()).Resolve()
+		if err != nil {
+			return err
+		}
+
+		if verbose {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+		}
+
+		job, err := findJob(configFile, jobName)
+		if err != nil {
+			return err
+		}
+
+		restorer, err := getRestorer(cmd, job, envs.DatabaseDSN)
+		if err != nil {
+			return err
+		}
+
+		reader, size, err := openDump(job)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := reader.Close(); err != nil {
+				slog.Error("fail to close dump file", slog.Any("error", err))
+			}
+		}()
+
+		return restorer(restore.WithSize(size)).Restore(reader)
+	},
+}
+
+type restorerFunc func(opts ...restore.RestoreOption) restore.Restorer
+
+// Get the restorer of the target database, the driver defaults to the job's database.
+func getRestorer(cmd *cobra.Command, job *config.Job, dsn string) (restorerFunc, error) {
+	target := driver
+	if target == "" {
+		target = mysqlDriver
+
+		if job != nil && (job.DBDriver == "postgresql" || job.DBDriver == "pgdump") {
+			target = postgresqlDriver
+		}
+	}
+
+	switch target {
+	case mysqlDriver:
+		for _, flag := range []string{"single-transaction", "on-error-stop", "create-database"} {
+			if cmd.Flags().Changed(flag) {
+				return nil, fmt.Errorf("--%s option is only supported by the postgresql driver", flag)
+			}
+		}
+
+		return func(opts ...restore.RestoreOption) restore.Restorer {
+			return restore.NewMysqlRestorer(dsn, opts...)
+		}, nil
+	case postgresqlDriver:
+		return func(opts ...restore.RestoreOption) restore.Restorer {
+			opts = append(opts, restore.WithSingleTransaction(singleTransaction), restore.WithOnErrorStop(onErrorStop), restore.WithCreateDatabase(createDatabase))
+			return restore.NewPgRestorer(dsn, opts...)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported driver: %s, support [%s, %s]", target, mysqlDriver, postgresqlDriver)
+	}
+}
+
+// Open the dump from the local path or the job's storage, the size is 0 if it is unknown.
+func openDump(job *config.Job) (io.ReadCloser, int64, error) {
+	if job == nil {
+		file, err := os.Open(dumpFile)
+		if err != nil {
+			return nil, 0, fmt.Errorf("fail to open dump file: %s, error: %v", dumpFile, err)
+		}
+
+		var size int64
+		if info, err := file.Stat(); err == nil {
+			size = info.Size()
+		}
+
+		return file, size, nil
+	}
+
+	opener, err := getOpener(job, storageType)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reader, err := opener.Open(dumpFile)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fail to open dump from the storage of job %s, error: %v", job.Name, err)
+	}
+
+	return reader, 0, nil
+}
+
+// Find the job in the config file, it returns nil if the config file is not specified.
+func findJob(configFile, jobName string) (*config.Job, error) {
+	if configFile == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job file from %s, error: %v", configFile, err)
+	}
+
+	var oneDump config.Dump
+	if err := yaml.Unmarshal(content, &oneDump); err != nil {
+		return nil, fmt.Errorf("failed to read job content from %s, error: %v", configFile, err)
+	}
+
+	for _, job := range oneDump.Jobs {
+		if job.Name == jobName {
+			return job, nil
+		}
+	}
+
+	return nil, fmt.Errorf("job %s is not found in %s", jobName, configFile)
+}
+
+// Get the storage to read the dump from, the storage type is only required if the job has more than one storage.
+func getOpener(job *config.Job, storageType string) (storage.Opener, error) {
+	if storageType != "" && !slices.Contains(storageTypes, storageType) {
+		return nil, fmt.Errorf("unsupported storage type: %s, support [%s]", storageType, strings.Join(storageTypes, ", "))
+	}
+
+	openers := make(map[string][]storage.Opener)
+
+	for _, s := range job.Storage.Local {
+		openers["local"] = append(openers["local"], s)
+	}
+
+	for _, s := range job.Storage.S3 {
+		openers["s3"] = append(openers["s3"], s)
+	}
+
+	for _, s := range job.Storage.GDrive {
+		openers["gdrive"] = append(openers["gdrive"], s)
+	}
+
+	for _, s := range job.Storage.Dropbox {
+		openers["dropbox"] = append(openers["dropbox"], s)
+	}
+
+	for _, s := range job.Storage.Sftp {
+		openers["sftp"] = append(openers["sftp"], s)
+	}
+
+	if storageType == "" {
+		var all []storage.Opener
+		for _, t := range storageTypes {
+			all = append(all, openers[t]...)
+		}
+
+		if len(all) != 1 {
+			return nil, fmt.Errorf("job %s has %d storages, use --storage to specify one of them", job.Name, len(all))
+		}
+
+		return all[0], nil
+	}
+
+	selected, ok := openers[storageType]
+	if !ok {
+		return nil, fmt.Errorf("job %s has no %s storage", job.Name, storageType)
+	}
+
+	if len(selected) > 1 {
+		return nil, fmt.Errorf("job %s has %d %s storages, only one is supported", job.Name, len(selected), storageType)
+	}
+
+	return selected[0], nil
+}
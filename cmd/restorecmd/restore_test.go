@@ -0,0 +1,101 @@
+package restorecmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liweiyi88/onedump/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreMissingRequiredArgs(t *testing.T) {
+	assert := assert.New(t)
+	cmd := cmd.RootCmd
+
+	cmd.SetArgs([]string{"restore"})
+	err := cmd.Execute()
+
+	assert.Error(err)
+	assert.Equal("at least one of the flags in the group [file config] is required", err.Error())
+}
+
+func TestRestoreMissingRequiredEnvs(t *testing.T) {
+	assert := assert.New(t)
+	cmd := cmd.RootCmd
+
+	t.Setenv("DATABASE_DSN", "")
+
+	cmd.SetArgs([]string{"restore", "--file=dump.sql"})
+	err := cmd.Execute()
+
+	assert.Error(err)
+	assert.Equal("missing required environment variable DATABASE_DSN", err.Error())
+}
+
+func TestRestoreSelectStorage(t *testing.T) {
+	assert := assert.New(t)
+	cmd := cmd.RootCmd
+
+	t.Setenv("DATABASE_DSN", "root@tcp(127.0.0.1:3306)/test")
+
+	configFile := filepath.Join(t.TempDir(), "jobs.yaml")
+	config := `jobs:
+- name: local-and-s3
+  dbdriver: mysql
+  dbdsn: root@tcp(127.0.0.1:3306)/test
+  storage:
+    local:
+      - path: /tmp/dump.sql
+    s3:
+      - bucket: mybucket
+        key: dump.sql
+        region: ap-southeast-2
+- name: local-only
+  dbdriver: mysql
+  dbdsn: root@tcp(127.0.0.1:3306)/test
+  storage:
+    local:
+      - path: /non/existent/dump.sql
+`
+	assert.Nil(os.WriteFile(configFile, []byte(config), 0644))
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--job=unknown", "--storage="}, "job unknown is not found in " + configFile},
+		{[]string{"--job=local-and-s3", "--storage="}, "job local-and-s3 has 2 storages, use --storage to specify one of them"},
+		{[]string{"--job=local-and-s3", "--storage=ftp"}, "unsupported storage type: ftp, support [local, s3, gdrive, dropbox, sftp]"},
+		{[]string{"--job=local-and-s3", "--storage=sftp"}, "job local-and-s3 has no sftp storage"},
+		{[]string{"--job=local-only", "--storage="}, "fail to open dump from the storage of job local-only, error: failed to open local file: open /non/existent/dump.sql: no such file or directory"},
+	}
+
+	for _, tt := range tests {
+		cmd.SetArgs(append([]string{"restore", "--config=" + configFile, "--file="}, tt.args...))
+		err := cmd.Execute()
+
+		assert.Error(err)
+		assert.Equal(tt.err, err.Error(), tt.args)
+	}
+}
+
+func TestRestoreDriverOptions(t *testing.T) {
+	assert := assert.New(t)
+	cmd := cmd.RootCmd
+
+	t.Setenv("DATABASE_DSN", "root@tcp(127.0.0.1:3306)/test")
+
+	// Reset --config and --job, the flags keep their values from the previous tests.
+	cmd.SetArgs([]string{"restore", "--config=", "--job=", "--file=dump.sql", "--driver=oracle"})
+	err := cmd.Execute()
+	assert.EqualError(err, "unsupported driver: oracle, support [mysql, postgresql]")
+
+	cmd.SetArgs([]string{"restore", "--config=", "--job=", "--file=/non/existent/dump.sql", "--driver=postgresql", "--single-transaction"})
+	err = cmd.Execute()
+	assert.EqualError(err, "fail to open dump file: /non/existent/dump.sql, error: open /non/existent/dump.sql: no such file or directory")
+
+	cmd.SetArgs([]string{"restore", "--config=", "--job=", "--file=dump.sql", "--driver=mysql", "--single-transaction"})
+	err = cmd.Execute()
+	assert.EqualError(err, "--single-transaction option is only supported by the postgresql driver")
+}
@@ -15,8 +15,10 @@ import (
 
 	"github.com/liweiyi88/onedump/cmd/binlogcmd"
 	"github.com/liweiyi88/onedump/cmd/downloadcmd"
+	"github.com/liweiyi88/onedump/cmd/restorecmd"
 	"github.com/liweiyi88/onedump/cmd/slowcmd"
 	"github.com/liweiyi88/onedump/cmd/synccmd"
+	"github.com/liweiyi88/onedump/cmd/verifycmd"
 	"github.com/liweiyi88/onedump/config"
 	"github.com/liweiyi88/onedump/handler"
 	"github.com/liweiyi88/onedump/storage/s3"
@@ -133,4 +135,6 @@ func init() {
 	RootCmd.AddCommand(synccmd.SyncCmd)
 	RootCmd.AddCommand(binlogcmd.BinlogCmd)
 	RootCmd.AddCommand(downloadcmd.DownloadCmd)
+	RootCmd.AddCommand(restorecmd.RestoreCmd)
+	RootCmd.AddCommand(verifycmd.VerifyCmd)
 }
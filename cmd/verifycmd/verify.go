@@ -0,0 +1,114 @@
+package verifycmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/liweiyi88/onedump/config"
+	"github.com/liweiyi88/onedump/handler"
+)
+
+var (
+	configFile, jobNames, server string
+	skipChecksum, verbose        bool
+)
+
+func init() {
+	VerifyCmd.Flags().StringVarP(&configFile, "file", "f", "", "jobs yaml file path (required)")
+	VerifyCmd.Flags().StringVarP(&jobNames, "job", "j", "", "the jobs to verify, e.g. --job=job1,job2. defaults to all jobs in the file (optional)")
+	VerifyCmd.Flags().StringVar(&server, "server", "", "the dsn of the verification server, it overrides the dsn of the job's verify block, e.g. root@tcp(127.0.0.1:3307)/ (optional)")
+	VerifyCmd.Flags().BoolVar(&skipChecksum, "skip-checksum", false, "only compare row counts, e.g. if the verification server runs a different MySQL version (optional)")
+	VerifyCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "prints additional debug information (optional)")
+	VerifyCmd.MarkFlagRequired("file")
+}
+
+var VerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Run jobs and verify their dumps by restoring them into a scratch database",
+	Long: `Run jobs and verify their dumps: each dump is restored into a temporary database on the verification server,
+the row counts and checksums of the restored tables are compared with the source captured at dump time, then the temporary database is dropped.
+The results are reported by the notifiers of the jobs file.`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if verbose {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+		}
+
+		content, err := os.ReadFile(configFile)
+		if err != nil {
+			return fmt.Errorf("failed to read job file from %s, error: %v", configFile, err)
+		}
+
+		oneDump := config.Dump{
+			MaxJobs: config.DefaultMaxConcurrentJobs,
+		}
+
+		if err := yaml.Unmarshal(content, &oneDump); err != nil {
+			return fmt.Errorf("failed to read job content from %s, error: %v", configFile, err)
+		}
+
+		jobs, err := selectJobs(oneDump.Jobs)
+		if err != nil {
+			return err
+		}
+
+		oneDump.Jobs = jobs
+
+		if err := oneDump.Validate(); err != nil {
+			return fmt.Errorf("invalid job configuration, error: %v", err)
+		}
+
+		return handler.NewDumpHandler(&oneDump).Do()
+	},
+}
+
+// Select the jobs to verify and apply the command line options to their verify blocks.
+func selectJobs(jobs []*config.Job) ([]*config.Job, error) {
+	var names []string
+	if jobNames != "" {
+		names = strings.Split(jobNames, ",")
+	}
+
+	for _, name := range names {
+		if !slices.ContainsFunc(jobs, func(job *config.Job) bool { return job.Name == name }) {
+			return nil, fmt.Errorf("job %s is not found in %s", name, configFile)
+		}
+	}
+
+	var selected []*config.Job
+	for _, job := range jobs {
+		if len(names) > 0 && !slices.Contains(names, job.Name) {
+			continue
+		}
+
+		if job.Verify == nil {
+			if server == "" {
+				return nil, fmt.Errorf("job %s has no verify block, use --server to specify the verification server", job.Name)
+			}
+
+			job.Verify = &config.Verify{}
+		}
+
+		if server != "" {
+			job.Verify.DSN = server
+		}
+
+		if skipChecksum {
+			job.Verify.SkipChecksum = true
+		}
+
+		selected = append(selected, job)
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no job is defined in the file %s", configFile)
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,59 @@
+package verifycmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liweiyi88/onedump/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyMissingRequiredArgs(t *testing.T) {
+	assert := assert.New(t)
+	cmd := cmd.RootCmd
+
+	cmd.SetArgs([]string{"verify"})
+	err := cmd.Execute()
+
+	assert.Error(err)
+	assert.Equal(`required flag(s) "file" not set`, err.Error())
+}
+
+func TestVerifySelectJobs(t *testing.T) {
+	assert := assert.New(t)
+	cmd := cmd.RootCmd
+
+	configFile := filepath.Join(t.TempDir(), "jobs.yaml")
+	config := `jobs:
+- name: with-verify
+  dbdriver: mysql
+  dbdsn: root@tcp(127.0.0.1:3306)/test
+  verify:
+    dsn: root@tcp(127.0.0.1:3307)/
+- name: without-verify
+  dbdriver: mysql
+  dbdsn: root@tcp(127.0.0.1:3306)/test
+- name: postgresql
+  dbdriver: postgresql
+  dbdsn: postgres://postgres@127.0.0.1:5432/test
+`
+	assert.Nil(os.WriteFile(configFile, []byte(config), 0644))
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--job=unknown", "--server="}, "job unknown is not found in " + configFile},
+		{[]string{"--job=without-verify", "--server="}, "job without-verify has no verify block, use --server to specify the verification server"},
+		{[]string{"--job=postgresql", "--server=root@tcp(127.0.0.1:3307)/"}, "invalid job configuration, error: job postgresql: verify is only supported by the mysql driver, got postgresql"},
+	}
+
+	for _, tt := range tests {
+		cmd.SetArgs(append([]string{"verify", "--file=" + configFile}, tt.args...))
+		err := cmd.Execute()
+
+		assert.Error(err)
+		assert.Equal(tt.err, err.Error(), tt.args)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/liweiyi88/onedump/notifier/slack"
@@ -49,16 +50,18 @@ func (dump *Dump) Validate() error {
 }
 
 type Job struct {
-	Name         string   `yaml:"name"`
-	DBDriver     string   `yaml:"dbdriver"`
-	DBDriverPath string   `yaml:"driverpath"`
-	DBDsn        string   `yaml:"dbdsn"`
-	Gzip         bool     `yaml:"gzip"`
-	Unique       bool     `yaml:"unique"`
-	SshHost      string   `yaml:"sshhost"`
-	SshUser      string   `yaml:"sshuser"`
-	SshKey       string   `yaml:"sshkey"`
-	DumpOptions  []string `yaml:"options"`
+	Name         string      `yaml:"name"`
+	DBDriver     string      `yaml:"dbdriver"`
+	DBDriverPath string      `yaml:"driverpath"`
+	DBDsn        string      `yaml:"dbdsn"`
+	Gzip         bool        `yaml:"gzip"`
+	Unique       bool        `yaml:"unique"`
+	SshHost      string      `yaml:"sshhost"`
+	SshUser      string      `yaml:"sshuser"`
+	SshKey       string      `yaml:"sshkey"`
+	DumpOptions  []string    `yaml:"options"`
+	Tables       TableFilter `yaml:"tables"`
+	Verify       *Verify     `yaml:"verify"`
 	Storage      struct {
 		Local   []*local.Local     `yaml:"local"`
 		S3      []*s3.S3           `yaml:"s3"`
@@ -68,6 +71,84 @@ type Job struct {
 	} `yaml:"storage"`
 }
 
+// Select the tables to dump. Patterns are shell globs (e.g. log_*) matched against table names.
+type TableFilter struct {
+	Include    []string          `yaml:"include"`    // dump all tables if empty
+	Exclude    []string          `yaml:"exclude"`    // tables to skip, it takes precedence over include
+	SchemaOnly []string          `yaml:"schemaonly"` // tables to dump without data
+	Where      map[string]string `yaml:"where"`      // WHERE predicate by table name, e.g. events: created_at > NOW() - INTERVAL 90 DAY
+}
+
+// Verify the backup by restoring the dump into a scratch database and comparing it with the source.
+type Verify struct {
+	DSN          string `yaml:"dsn"`          // the verification server, e.g. root@tcp(127.0.0.1:3307)/
+	SkipChecksum bool   `yaml:"skipchecksum"` // only compare row counts, e.g. if the verification server runs a different MySQL version
+}
+
+func (verify Verify) validate(job Job) error {
+	if strings.TrimSpace(verify.DSN) == "" {
+		return fmt.Errorf("job %s: verify dsn is required", job.Name)
+	}
+
+	if job.DBDriver != "mysql" {
+		return fmt.Errorf("job %s: verify is only supported by the mysql driver, got %s", job.Name, job.DBDriver)
+	}
+
+	return nil
+}
+
+func (filter TableFilter) IsEmpty() bool {
+	return len(filter.Include) == 0 && len(filter.Exclude) == 0 && len(filter.SchemaOnly) == 0 && len(filter.Where) == 0
+}
+
+// Check if the table should be dumped.
+func (filter TableFilter) Match(table string) bool {
+	if len(filter.Include) > 0 && !matchAny(filter.Include, table) {
+		return false
+	}
+
+	return !matchAny(filter.Exclude, table)
+}
+
+// Check if only the table structure should be dumped.
+func (filter TableFilter) IsSchemaOnly(table string) bool {
+	return matchAny(filter.SchemaOnly, table)
+}
+
+func (filter TableFilter) validate() error {
+	for _, patterns := range [][]string{filter.Include, filter.Exclude, filter.SchemaOnly} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid table pattern: %q, error: %v", pattern, err)
+			}
+		}
+	}
+
+	for table, where := range filter.Where {
+		if strings.TrimSpace(where) == "" {
+			return fmt.Errorf("empty where predicate of table: %s", table)
+		}
+	}
+
+	return nil
+}
+
+// Check if the pattern contains any glob special characters.
+func IsTablePattern(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+func matchAny(patterns []string, table string) bool {
+	for _, pattern := range patterns {
+		// Patterns are validated when loading the config.
+		if matched, _ := path.Match(pattern, table); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
 type Option func(job *Job)
 
 func WithSshHost(sshHost string) Option {
@@ -94,6 +175,18 @@ func WithDumpOptions(dumpOptions ...string) Option {
 	}
 }
 
+func WithTables(tables TableFilter) Option {
+	return func(job *Job) {
+		job.Tables = tables
+	}
+}
+
+func WithVerify(verify *Verify) Option {
+	return func(job *Job) {
+		job.Verify = verify
+	}
+}
+
 func WithSshKey(sshKey string) Option {
 	return func(job *Job) {
 		job.SshKey = sshKey
@@ -127,6 +220,16 @@ func (job Job) validate() error {
 		return ErrMissingDBDriver
 	}
 
+	if err := job.Tables.validate(); err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+
+	if job.Verify != nil {
+		if err := job.Verify.validate(job); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
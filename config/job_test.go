@@ -103,3 +103,69 @@ func TestViaSsh(t *testing.T) {
 
 	assert.True(job.ViaSsh())
 }
+
+func TestTableFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	filter := TableFilter{}
+	assert.True(filter.IsEmpty())
+	assert.True(filter.Match("users"))
+	assert.False(filter.IsSchemaOnly("users"))
+
+	filter = TableFilter{
+		Include:    []string{"user*", "orders"},
+		Exclude:    []string{"user_logs"},
+		SchemaOnly: []string{"*_audit"},
+	}
+
+	assert.False(filter.IsEmpty())
+	assert.True(filter.Match("users"))
+	assert.True(filter.Match("orders"))
+	assert.False(filter.Match("user_logs"))
+	assert.False(filter.Match("products"))
+	assert.True(filter.IsSchemaOnly("user_audit"))
+	assert.False(filter.IsSchemaOnly("users"))
+
+	filter = TableFilter{Exclude: []string{"log_?"}}
+	assert.True(filter.Match("users"))
+	assert.False(filter.Match("log_1"))
+	assert.True(filter.Match("log_10"))
+}
+
+func TestValidateTableFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	job := NewJob("job", "mysql", testDBDsn, WithTables(TableFilter{Include: []string{"users["}}))
+	assert.ErrorContains(job.validate(), `job job: invalid table pattern: "users["`)
+
+	job = NewJob("job", "mysql", testDBDsn, WithTables(TableFilter{Where: map[string]string{"events": " "}}))
+	assert.EqualError(job.validate(), "job job: empty where predicate of table: events")
+
+	job = NewJob("job", "mysql", testDBDsn, WithTables(TableFilter{
+		Include: []string{"events", "users_*"},
+		Where:   map[string]string{"events": "created_at > NOW() - INTERVAL 90 DAY"},
+	}))
+	assert.Nil(job.validate())
+}
+
+func TestIsTablePattern(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(IsTablePattern("users"))
+	assert.True(IsTablePattern("users_*"))
+	assert.True(IsTablePattern("log_?"))
+	assert.True(IsTablePattern("log_[0-9]"))
+}
+
+func TestValidateVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	job := NewJob("job", "mysql", testDBDsn, WithVerify(&Verify{DSN: "root@tcp(127.0.0.1:3307)/"}))
+	assert.Nil(job.validate())
+
+	job = NewJob("job", "mysql", testDBDsn, WithVerify(&Verify{}))
+	assert.EqualError(job.validate(), "job job: verify dsn is required")
+
+	job = NewJob("job", "mysqldump", testDBDsn, WithVerify(&Verify{DSN: "root@tcp(127.0.0.1:3307)/"}))
+	assert.EqualError(job.validate(), "job job: verify is only supported by the mysql driver, got mysqldump")
+}
@@ -1,7 +1,10 @@
 package dumper
 
 import (
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 )
 
 type DBConfig struct {
@@ -22,8 +25,8 @@ func NewDBConfig(dbName, user, password, host string, port int) *DBConfig {
 	}
 }
 
-// Dump options
-type Options map[string]bool
+// Dump options, an option is either a flag (e.g. --skip-add-locks) or carries a value (e.g. --net-buffer-length=16384).
+type Options map[string]string
 
 func newOptions(opts ...string) Options {
 	options := make(Options, len(opts))
@@ -33,18 +36,35 @@ func newOptions(opts ...string) Options {
 }
 
 func (options Options) isEnabled(option string) bool {
+	_, ok := options[option]
+	return ok
+}
+
+func (options Options) enable(opts ...string) {
+	for _, opt := range opts {
+		name, value, _ := strings.Cut(opt, "=")
+		options[name] = value
+	}
+}
+
+// Get the integer value of an option, return the default value if the option is not set.
+func (options Options) intValue(option string, defaultValue int) (int, error) {
 	val, ok := options[option]
 	if !ok {
-		return false
+		return defaultValue, nil
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value of %s option: %q, expect an integer", option, val)
 	}
 
-	return val
+	return n, nil
 }
 
-func (options Options) enable(opts ...string) {
-	for _, opt := range opts {
-		options[opt] = true
-	}
+// Quote a command argument for the remote shell, it is used by ssh dumps as the command is run as a single string.
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
 }
 
 type Dumper interface {
@@ -13,3 +13,19 @@ func TestOptions(t *testing.T) {
 	assert.True(t, options.isEnabled("--skip-add-drop-table"))
 	assert.False(t, options.isEnabled("--some-other-option"))
 }
+
+func TestOptionIntValue(t *testing.T) {
+	assert := assert.New(t)
+	options := newOptions("--net-buffer-length=1024", "--rows-per-insert=abc")
+
+	n, err := options.intValue("--net-buffer-length", 10)
+	assert.NoError(err)
+	assert.Equal(1024, n)
+
+	n, err = options.intValue("--not-set", 10)
+	assert.NoError(err)
+	assert.Equal(10, n)
+
+	_, err = options.intValue("--rows-per-insert", 10)
+	assert.EqualError(err, `invalid value of --rows-per-insert option: "abc", expect an integer`)
+}
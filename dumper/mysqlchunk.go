@@ -0,0 +1,212 @@
+package dumper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+// The max number of rows per SELECT, e.g. --chunk-size=10000.
+// Table contents are paginated by the primary key (or a unique key), so the server never holds a long running query of a huge table.
+const chunkSize = "--chunk-size"
+
+type tableKey struct {
+	name    string
+	columns []string
+}
+
+// Get the columns to dump, generated columns are skipped as they can not be inserted.
+func (m *MysqlNativeDump) getTableColumns(table string) ([]string, map[string]bool, error) {
+	query := "SELECT COLUMN_NAME, EXTRA FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION"
+	rows, err := m.db.QueryContext(context.Background(), query, m.database, table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to query columns of table: %s, error: %v", table, err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.Error("failed to close rows", slog.Any("error", err))
+		}
+	}()
+
+	var columns []string
+	generated := make(map[string]bool)
+
+	for rows.Next() {
+		var name, extra string
+		if err := rows.Scan(&name, &extra); err != nil {
+			return nil, nil, fmt.Errorf("fail to scan columns of table: %s, error: %v", table, err)
+		}
+
+		if strings.Contains(strings.ToUpper(extra), "GENERATED") {
+			generated[name] = true
+			continue
+		}
+
+		columns = append(columns, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("fail to read columns of table: %s, error: %v", table, err)
+	}
+
+	return columns, generated, nil
+}
+
+// Find the key to paginate the table by, the primary key is preferred over other unique keys.
+// Keys with nullable, generated or functional parts are skipped, as they can not be compared by row constructors.
+// It returns nil if the table has no usable key.
+func (m *MysqlNativeDump) getTableKey(table string, generated map[string]bool) (*tableKey, error) {
+	query := "SELECT INDEX_NAME, COLUMN_NAME, NULLABLE FROM information_schema.STATISTICS " +
+		"WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND NON_UNIQUE = 0 " +
+		"ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX"
+
+	results, err := m.queryColumns(query, m.database, table)
+	if err != nil {
+		return nil, fmt.Errorf("fail to query keys of table: %s, error: %v", table, err)
+	}
+
+	var keys []*tableKey
+	unusable := make(map[string]bool)
+
+	for _, result := range results {
+		name := result["INDEX_NAME"].String
+		column := result["COLUMN_NAME"]
+
+		if !column.Valid || generated[column.String] || result["NULLABLE"].String == "YES" {
+			unusable[name] = true
+		}
+
+		if len(keys) == 0 || keys[len(keys)-1].name != name {
+			keys = append(keys, &tableKey{name: name})
+		}
+
+		key := keys[len(keys)-1]
+		key.columns = append(key.columns, column.String)
+	}
+
+	for _, key := range keys {
+		if !unusable[key.name] {
+			return key, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Write the table contents by SELECT ... WHERE (key) > (last key) ORDER BY key LIMIT chunk size,
+// all chunks are written as if they were from a single query.
+func (m *MysqlNativeDump) writeTableContentInChunks(buf *bufio.Writer, table string) error {
+	columns, generated, err := m.getTableColumns(table)
+	if err != nil {
+		return err
+	}
+
+	if len(columns) == 0 {
+		return fmt.Errorf("no columns found for table: %s, check if the user has enough privileges", table)
+	}
+
+	key, err := m.getTableKey(table, generated)
+	if err != nil {
+		return err
+	}
+
+	quotedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		quotedColumns[i] = sqlutil.QuoteIdentifier(column)
+	}
+
+	selectColumns := strings.Join(quotedColumns, ", ")
+	where, hasWhere := m.tables.Where[table]
+
+	// The column names are required if any generated column is skipped.
+	w := &insertWriter{m: m, buf: buf, table: table, columnList: m.isCompleteInsert() || len(generated) > 0}
+
+	if key == nil {
+		slog.Debug("no primary or unique key found, dump table without chunks", slog.String("table", table))
+
+		query := fmt.Sprintf("SELECT %s FROM %s;", selectColumns, m.qualify(table))
+		if hasWhere {
+			query = fmt.Sprintf("SELECT %s FROM %s WHERE %s;", selectColumns, m.qualify(table), where)
+		}
+
+		if _, err := w.writeQueryRows(query); err != nil {
+			return err
+		}
+
+		return m.closeTableContent(w)
+	}
+
+	keyIndexes := make([]int, len(key.columns))
+	quotedKeys := make([]string, len(key.columns))
+	placeholders := make([]string, len(key.columns))
+
+	for i, column := range key.columns {
+		for j, c := range columns {
+			if c == column {
+				keyIndexes[i] = j
+				break
+			}
+		}
+
+		quotedKeys[i] = sqlutil.QuoteIdentifier(column)
+		placeholders[i] = "?"
+	}
+
+	keyList := strings.Join(quotedKeys, ", ")
+	orderBy := fmt.Sprintf(" ORDER BY %s LIMIT %d;", keyList, m.chunkSize)
+	keyCondition := fmt.Sprintf("(%s) > (%s)", keyList, strings.Join(placeholders, ", "))
+
+	firstQuery := fmt.Sprintf("SELECT %s FROM %s", selectColumns, m.qualify(table))
+	nextQuery := firstQuery + " WHERE " + keyCondition
+
+	if hasWhere {
+		firstQuery += " WHERE (" + where + ")"
+		nextQuery = firstQuery + " AND " + keyCondition
+	}
+
+	query := firstQuery + orderBy
+	var args []any
+
+	for chunk := 1; ; chunk++ {
+		n, err := w.writeQueryRows(query, args...)
+		if err != nil {
+			return err
+		}
+
+		slog.Debug("dumped table chunk", slog.String("table", table), slog.Int("chunk", chunk), slog.Int("rows", w.totalRows))
+
+		if n < m.chunkSize {
+			break
+		}
+
+		args = make([]any, len(keyIndexes))
+		for i, index := range keyIndexes {
+			args[i] = keyValue(w.lastRow[index], w.typeNames[index])
+		}
+
+		query = nextQuery + orderBy
+	}
+
+	return m.closeTableContent(w)
+}
+
+// Pass non-binary key values back as strings, so they are compared by the collation of the column, not as binary strings.
+func keyValue(value any, typeName string) any {
+	v, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+
+	switch typeName {
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT":
+		return v
+	default:
+		return string(v)
+	}
+}
@@ -0,0 +1,169 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/config"
+)
+
+const (
+	testColumnsQuery = "SELECT COLUMN_NAME, EXTRA FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION"
+	testKeysQuery    = "SELECT INDEX_NAME, COLUMN_NAME, NULLABLE FROM information_schema.STATISTICS " +
+		"WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND NON_UNIQUE = 0 " +
+		"ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX"
+)
+
+func TestNewMysqlNativeDumpChunkSize(t *testing.T) {
+	assert, _, _ := initTest(t)
+
+	mysql, err := NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn))
+	assert.Nil(err)
+	assert.Equal(0, mysql.chunkSize)
+
+	mysql, err = NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--chunk-size=1000")))
+	assert.Nil(err)
+	assert.Equal(1000, mysql.chunkSize)
+
+	_, err = NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--chunk-size=-1")))
+	assert.EqualError(err, "--chunk-size should not be negative, got -1")
+
+	_, err = NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--chunk-size=abc")))
+	assert.EqualError(err, `invalid value of --chunk-size option: "abc", expect an integer`)
+}
+
+func expectChunkColumns(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(testColumnsQuery).WithArgs("dump_test", "onedump").WillReturnRows(
+		sqlmock.NewRows([]string{"COLUMN_NAME", "EXTRA"}).
+			AddRow("id", "auto_increment").
+			AddRow("name", "").
+			AddRow("upper_name", "VIRTUAL GENERATED"),
+	)
+}
+
+func chunkRows() *sqlmock.Rows {
+	return sqlmock.NewRowsWithColumnDefinition(
+		sqlmock.NewColumn("id").OfType("INT", 1),
+		sqlmock.NewColumn("name").OfType("VARCHAR", ""),
+	)
+}
+
+func TestWriteTableContentInChunks(t *testing.T) {
+	t.Run("it should paginate table contents by the primary key", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+		mysql.chunkSize = 2
+
+		expectChunkColumns(mock)
+		mock.ExpectQuery(testKeysQuery).WithArgs("dump_test", "onedump").WillReturnRows(
+			sqlmock.NewRows([]string{"INDEX_NAME", "COLUMN_NAME", "NULLABLE"}).
+				AddRow("PRIMARY", "id", "").
+				AddRow("name", "name", ""),
+		)
+
+		mock.ExpectQuery("SELECT `id`, `name` FROM `dump_test`.`onedump` ORDER BY `id` LIMIT 2;").
+			WillReturnRows(chunkRows().AddRow(1, []byte("a")).AddRow(2, []byte("b")))
+		mock.ExpectQuery("SELECT `id`, `name` FROM `dump_test`.`onedump` WHERE (`id`) > (?) ORDER BY `id` LIMIT 2;").
+			WithArgs(2).
+			WillReturnRows(chunkRows().AddRow(3, []byte("c")))
+
+		var buffer bytes.Buffer
+		buf := bufio.NewWriter(&buffer)
+
+		assert.Nil(mysql.writeTableContent(buf, "onedump"))
+		assert.Nil(buf.Flush())
+
+		expected := "LOCK TABLES `onedump` WRITE;\n" +
+			"/*!40000 ALTER TABLE `onedump` DISABLE KEYS */;\n" +
+			"INSERT INTO `onedump` (`id`, `name`) VALUES (1,'a'),(2,'b'),(3,'c');\n" +
+			"/*!40000 ALTER TABLE `onedump` ENABLE KEYS */;\n" +
+			"UNLOCK TABLES;\n\n"
+
+		assert.Equal(expected, buffer.String())
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should skip nullable keys and pass key values as strings", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+		mysql.chunkSize = 1
+		mysql.tables = config.TableFilter{Where: map[string]string{"onedump": "id > 0"}}
+
+		expectChunkColumns(mock)
+		mock.ExpectQuery(testKeysQuery).WithArgs("dump_test", "onedump").WillReturnRows(
+			sqlmock.NewRows([]string{"INDEX_NAME", "COLUMN_NAME", "NULLABLE"}).
+				AddRow("a_nullable", "id", "YES").
+				AddRow("b_unique", "name", "").
+				AddRow("b_unique", "id", ""),
+		)
+
+		mock.ExpectQuery("SELECT `id`, `name` FROM `dump_test`.`onedump` WHERE (id > 0) ORDER BY `name`, `id` LIMIT 1;").
+			WillReturnRows(chunkRows().AddRow(1, []byte("a")))
+		mock.ExpectQuery("SELECT `id`, `name` FROM `dump_test`.`onedump` WHERE (id > 0) AND (`name`, `id`) > (?, ?) ORDER BY `name`, `id` LIMIT 1;").
+			WithArgs("a", 1).
+			WillReturnRows(chunkRows())
+
+		var buffer bytes.Buffer
+		buf := bufio.NewWriter(&buffer)
+
+		assert.Nil(mysql.writeTableContent(buf, "onedump"))
+		assert.Nil(buf.Flush())
+
+		assert.Contains(buffer.String(), "INSERT INTO `onedump` (`id`, `name`) VALUES (1,'a');\n")
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should dump the table without chunks if there is no usable key", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+		mysql.chunkSize = 1
+
+		expectChunkColumns(mock)
+		mock.ExpectQuery(testKeysQuery).WithArgs("dump_test", "onedump").WillReturnRows(
+			sqlmock.NewRows([]string{"INDEX_NAME", "COLUMN_NAME", "NULLABLE"}).
+				AddRow("upper_name", "upper_name", ""),
+		)
+
+		mock.ExpectQuery("SELECT `id`, `name` FROM `dump_test`.`onedump`;").
+			WillReturnRows(chunkRows().AddRow(1, []byte("a")).AddRow(2, []byte("b")))
+
+		var buffer bytes.Buffer
+		buf := bufio.NewWriter(&buffer)
+
+		assert.Nil(mysql.writeTableContent(buf, "onedump"))
+		assert.Nil(buf.Flush())
+
+		assert.Contains(buffer.String(), "INSERT INTO `onedump` (`id`, `name`) VALUES (1,'a'),(2,'b');\n")
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should write nothing for an empty table", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+		mysql.chunkSize = 10
+
+		expectChunkColumns(mock)
+		mock.ExpectQuery(testKeysQuery).WithArgs("dump_test", "onedump").WillReturnRows(
+			sqlmock.NewRows([]string{"INDEX_NAME", "COLUMN_NAME", "NULLABLE"}).AddRow("PRIMARY", "id", ""),
+		)
+		mock.ExpectQuery("SELECT `id`, `name` FROM `dump_test`.`onedump` ORDER BY `id` LIMIT 10;").WillReturnRows(chunkRows())
+
+		var buffer bytes.Buffer
+		buf := bufio.NewWriter(&buffer)
+
+		assert.Nil(mysql.writeTableContent(buf, "onedump"))
+		assert.Nil(buf.Flush())
+		assert.Equal("", buffer.String())
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+}
+
+func TestKeyValue(t *testing.T) {
+	assert, _, _ := initTest(t)
+
+	assert.Equal("abc", keyValue([]byte("abc"), "VARCHAR"))
+	assert.Equal([]byte{0x01, 0x00}, keyValue([]byte{0x01, 0x00}, "VARBINARY"))
+	assert.Equal(int64(1), keyValue(int64(1), "INT"))
+}
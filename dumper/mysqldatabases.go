@@ -0,0 +1,100 @@
+package dumper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+const (
+	allDatabases = "--all-databases"
+	databases    = "--databases" // a comma separated list, e.g. --databases=db1,db2
+)
+
+// Schemas that are skipped by --all-databases.
+var systemDatabases = []string{"information_schema", "mysql", "performance_schema", "sys"}
+
+// Parse the databases to dump from the options, fall back to the database of the DSN.
+// The databases of --all-databases are only known after connecting to the server.
+func parseDatabases(options Options, dbName string) ([]string, error) {
+	if options.isEnabled(allDatabases) {
+		if options.isEnabled(databases) {
+			return nil, fmt.Errorf("%s and %s options can not be used together", allDatabases, databases)
+		}
+
+		return nil, nil
+	}
+
+	if !options.isEnabled(databases) {
+		if dbName == "" {
+			return nil, fmt.Errorf("database name is required in the dsn, or use %s or %s option", databases, allDatabases)
+		}
+
+		return []string{dbName}, nil
+	}
+
+	var names []string
+	for name := range strings.SplitSeq(options[databases], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s option requires a comma separated list of databases", databases)
+	}
+
+	return names, nil
+}
+
+// Check if the dump contains CREATE DATABASE and USE statements, the same as mysqldump --databases.
+func (m *MysqlNativeDump) isMultiDatabase() bool {
+	return m.options.isEnabled(allDatabases) || m.options.isEnabled(databases)
+}
+
+// Quote a name qualified by the database being dumped.
+// Queries are always qualified, so they do not depend on the default database of the connection.
+func (m *MysqlNativeDump) qualify(name string) string {
+	return sqlutil.QuoteIdentifier(m.database) + "." + sqlutil.QuoteIdentifier(name)
+}
+
+// Get all non-system databases of the server.
+func (m *MysqlNativeDump) getAllDatabases() ([]string, error) {
+	names, err := m.queryNames("SHOW DATABASES")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []string
+	for _, name := range names {
+		if !slices.Contains(systemDatabases, strings.ToLower(name)) {
+			results = append(results, name)
+		}
+	}
+
+	return results, nil
+}
+
+func (m *MysqlNativeDump) writeDatabaseStructure(buf *bufio.Writer) error {
+	var name, createDatabase string
+
+	row := m.db.QueryRowContext(context.Background(), "SHOW CREATE DATABASE IF NOT EXISTS "+sqlutil.QuoteIdentifier(m.database))
+	if err := row.Scan(&name, &createDatabase); err != nil {
+		return fmt.Errorf("fail to scan create database structure for database: %s, error: %v", m.database, err)
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("--\n")
+	sb.WriteString("-- Current Database: " + sqlutil.QuoteIdentifier(m.database) + "\n")
+	sb.WriteString("--\n\n")
+	sb.WriteString(createDatabase + ";\n\n")
+	sb.WriteString("USE " + sqlutil.QuoteIdentifier(m.database) + ";\n\n")
+
+	_, err := buf.WriteString(sb.String())
+	return err
+}
@@ -0,0 +1,87 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/liweiyi88/onedump/config"
+)
+
+func TestParseDatabases(t *testing.T) {
+	assert, _, _ := initTest(t)
+
+	names, err := parseDatabases(newOptions(), "dump_test")
+	assert.Nil(err)
+	assert.Equal([]string{"dump_test"}, names)
+
+	names, err = parseDatabases(newOptions("--databases=db1, db2,"), "dump_test")
+	assert.Nil(err)
+	assert.Equal([]string{"db1", "db2"}, names)
+
+	names, err = parseDatabases(newOptions("--all-databases"), "")
+	assert.Nil(err)
+	assert.Nil(names)
+
+	_, err = parseDatabases(newOptions(), "")
+	assert.EqualError(err, "database name is required in the dsn, or use --databases or --all-databases option")
+
+	_, err = parseDatabases(newOptions("--databases"), "dump_test")
+	assert.EqualError(err, "--databases option requires a comma separated list of databases")
+
+	_, err = parseDatabases(newOptions("--all-databases", "--databases=db1"), "dump_test")
+	assert.EqualError(err, "--all-databases and --databases options can not be used together")
+}
+
+func TestGetAllDatabases(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+
+	rows := mock.NewRows([]string{"Database"}).
+		AddRow("information_schema").
+		AddRow("app").
+		AddRow("mysql").
+		AddRow("performance_schema").
+		AddRow("shop").
+		AddRow("sys")
+
+	mock.ExpectQuery("SHOW DATABASES").WillReturnRows(rows)
+
+	names, err := mysql.getAllDatabases()
+	assert.Nil(err)
+	assert.Equal([]string{"app", "shop"}, names)
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestWriteDatabaseStructure(t *testing.T) {
+	assert, db, mock := initTest(t)
+
+	job := config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--databases=dump_test,shop"))
+	mysql, err := NewMysqlNativeDump(job)
+	assert.Nil(err)
+	assert.True(mysql.isMultiDatabase())
+
+	mysql.db = db
+	mysql.database = "shop"
+	assert.Equal("`shop`.`orders`", mysql.qualify("orders"))
+
+	rows := mock.NewRows([]string{"Database", "Create Database"}).
+		AddRow("shop", "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci */")
+
+	mock.ExpectQuery("SHOW CREATE DATABASE IF NOT EXISTS `shop`").WillReturnRows(rows)
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+
+	assert.Nil(mysql.writeDatabaseStructure(buf))
+	assert.Nil(buf.Flush())
+
+	expected := "--\n" +
+		"-- Current Database: `shop`\n" +
+		"--\n\n" +
+		"CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci */;\n\n" +
+		"USE `shop`;\n\n"
+
+	assert.Equal(expected, b.String())
+	assert.Nil(mock.ExpectationsWereMet())
+}
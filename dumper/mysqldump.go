@@ -23,6 +23,8 @@ type MysqlDump struct {
 	credentialFiles []string
 	path            string
 	options         []string
+	tableOptions    []string // --ignore-table and --where options translated from the table filter
+	tables          []string // tables to dump, all tables if empty
 	viaSsh          bool
 	sshHost         string
 	sshUser         string
@@ -60,17 +62,85 @@ func NewMysqlDump(job *config.Job) (*MysqlDump, error) {
 		path = job.DBDriverPath
 	}
 
+	tableOptions, tables, err := mysqlDumpTableArgs(job.Tables, config.DBName, commandOptions, job.ViaSsh())
+	if err != nil {
+		return nil, err
+	}
+
 	return &MysqlDump{
-		path:     path,
-		options:  commandOptions,
-		viaSsh:   job.ViaSsh(),
-		sshHost:  job.SshHost,
-		sshUser:  job.SshUser,
-		sshKey:   job.SshKey,
-		DBConfig: NewDBConfig(config.DBName, config.User, config.Passwd, host, dbPort),
+		path:         path,
+		options:      commandOptions,
+		tableOptions: tableOptions,
+		tables:       tables,
+		viaSsh:       job.ViaSsh(),
+		sshHost:      job.SshHost,
+		sshUser:      job.SshUser,
+		sshKey:       job.SshKey,
+		DBConfig:     NewDBConfig(config.DBName, config.User, config.Passwd, host, dbPort),
 	}, nil
 }
 
+// Translate the table filter to mysqldump options and table arguments.
+// mysqldump only accepts exact table names and applies --where to every table it dumps,
+// so patterns, schema only tables and different predicates per table are not supported.
+func mysqlDumpTableArgs(filter config.TableFilter, dbName string, options []string, viaSsh bool) ([]string, []string, error) {
+	if filter.IsEmpty() {
+		return nil, nil, nil
+	}
+
+	if slices.Contains(options, "--all-databases") || slices.Contains(options, "--databases") {
+		return nil, nil, errors.New("mysqldump does not support table filters with --all-databases or --databases option")
+	}
+
+	if len(filter.SchemaOnly) > 0 {
+		return nil, nil, errors.New("mysqldump does not support schema only tables, use the mysql driver instead")
+	}
+
+	for _, pattern := range slices.Concat(filter.Include, filter.Exclude) {
+		if config.IsTablePattern(pattern) {
+			return nil, nil, fmt.Errorf("mysqldump does not support table pattern: %s, use the mysql driver instead", pattern)
+		}
+	}
+
+	quote := func(arg string) string {
+		if viaSsh {
+			return shellQuote(arg)
+		}
+
+		return arg
+	}
+
+	var tableOptions []string
+	for _, table := range filter.Exclude {
+		tableOptions = append(tableOptions, "--ignore-table="+quote(dbName+"."+table))
+	}
+
+	if len(filter.Where) > 0 {
+		var where string
+		for _, table := range filter.Include {
+			predicate, ok := filter.Where[table]
+			if !ok || (where != "" && predicate != where) {
+				return nil, nil, errors.New("mysqldump applies the where predicate to all tables, every included table should have the same where predicate")
+			}
+
+			where = predicate
+		}
+
+		if where == "" {
+			return nil, nil, errors.New("mysqldump applies the where predicate to all tables, include the tables of the where predicate explicitly")
+		}
+
+		tableOptions = append(tableOptions, "--where="+quote(where))
+	}
+
+	var tables []string
+	for _, table := range filter.Include {
+		tables = append(tables, quote(table))
+	}
+
+	return tableOptions, tables, nil
+}
+
 // Get the exec dump command.
 func (mysql *MysqlDump) getExecDumpCommand() (string, []string, error) {
 	args, err := mysql.getDumpCommandArgs()
@@ -113,9 +183,11 @@ func (mysql *MysqlDump) getDumpCommandArgs() ([]string, error) {
 	}
 
 	args = append(args, mysql.options...)
+	args = append(args, mysql.tableOptions...)
 
 	if !slices.Contains(mysql.options, "--all-databases") {
 		args = append(args, mysql.DBName)
+		args = append(args, mysql.tables...)
 	}
 
 	return args, nil
@@ -164,3 +164,54 @@ func TestCloseMysql(t *testing.T) {
 	mysql.credentialFiles = append(mysql.credentialFiles, "wrong file")
 	assert.NotNil(mysql.close())
 }
+
+func TestMysqlDumpTableArgs(t *testing.T) {
+	t.Run("it should translate the table filter to mysqldump args", func(t *testing.T) {
+		assert := assert.New(t)
+		job := config.NewJob("test", "mysql", testDBDsn, config.WithSshHost("ssh"), config.WithSshKey("key"), config.WithSshUser("user"), config.WithTables(config.TableFilter{
+			Include: []string{"events"},
+			Exclude: []string{"audit_logs"},
+			Where:   map[string]string{"events": "created_at > '2024-01-01'"},
+		}))
+
+		mysql, err := NewMysqlDump(job)
+		assert.Nil(err)
+
+		args, err := mysql.getDumpCommandArgs()
+		assert.Nil(err)
+
+		expect := `--host 127.0.0.1 --port 3306 -u admin -pmy_password --skip-comments --extended-insert --ignore-table='dump_test.audit_logs' --where='created_at > '\''2024-01-01'\''' dump_test 'events'`
+		assert.Equal(expect, strings.Join(args, " "))
+	})
+
+	t.Run("it should not quote args when it does not run via ssh", func(t *testing.T) {
+		assert := assert.New(t)
+
+		options, tables, err := mysqlDumpTableArgs(config.TableFilter{Include: []string{"users", "orders"}, Exclude: []string{"logs"}}, "dump_test", nil, false)
+		assert.Nil(err)
+		assert.Equal([]string{"--ignore-table=dump_test.logs"}, options)
+		assert.Equal([]string{"users", "orders"}, tables)
+	})
+
+	t.Run("it should return errors for the filters that mysqldump does not support", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, _, err := mysqlDumpTableArgs(config.TableFilter{Include: []string{"users"}}, "dump_test", []string{"--all-databases"}, false)
+		assert.EqualError(err, "mysqldump does not support table filters with --all-databases or --databases option")
+
+		_, _, err = mysqlDumpTableArgs(config.TableFilter{SchemaOnly: []string{"logs"}}, "dump_test", nil, false)
+		assert.EqualError(err, "mysqldump does not support schema only tables, use the mysql driver instead")
+
+		_, _, err = mysqlDumpTableArgs(config.TableFilter{Exclude: []string{"log_*"}}, "dump_test", nil, false)
+		assert.EqualError(err, "mysqldump does not support table pattern: log_*, use the mysql driver instead")
+
+		_, _, err = mysqlDumpTableArgs(config.TableFilter{Where: map[string]string{"events": "id > 1"}}, "dump_test", nil, false)
+		assert.EqualError(err, "mysqldump applies the where predicate to all tables, include the tables of the where predicate explicitly")
+
+		_, _, err = mysqlDumpTableArgs(config.TableFilter{Include: []string{"events", "users"}, Where: map[string]string{"events": "id > 1"}}, "dump_test", nil, false)
+		assert.EqualError(err, "mysqldump applies the where predicate to all tables, every included table should have the same where predicate")
+
+		_, err = NewMysqlDump(config.NewJob("test", "mysql", testDBDsn, config.WithTables(config.TableFilter{SchemaOnly: []string{"logs"}})))
+		assert.NotNil(err)
+	})
+}
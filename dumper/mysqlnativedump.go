@@ -2,7 +2,6 @@ package dumper
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
@@ -15,21 +14,49 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"github.com/liweiyi88/onedump/config"
 	"github.com/liweiyi88/onedump/dumper/dialer"
+	"github.com/liweiyi88/onedump/sqlutil"
 )
 
 const (
 	skipAddDropTable = "--skip-add-drop-table"
 	skipAddLocks     = "--skip-add-locks"
+	netBufferLength  = "--net-buffer-length"
+	rowsPerInsert    = "--rows-per-insert"
+)
+
+const (
+	// The same default value as mysqldump, it is the max size of a single extended INSERT statement.
+	DefaultNetBufferLength = 1046528
+	// No limits of rows per INSERT statement by default.
+	DefaultRowsPerInsert = 0
 )
 
 type MysqlNativeDump struct {
-	options  Options
-	viaSsh   bool
-	sshHost  string
-	sshUser  string
-	sshKey   string
-	DBConfig *mysql.Config
-	db       *sql.DB
+	options            Options
+	sourceData         string // "1" or "2" when --source-data is enabled
+	netBufferLength    int
+	rowsPerInsert      int
+	parallel           int
+	chunkSize          int // rows per SELECT when paginating table contents by key, 0 means disabled
+	viaSsh             bool
+	sshHost            string
+	sshUser            string
+	sshKey             string
+	tables             config.TableFilter
+	databaseNames      []string             // databases to dump, resolved after connecting to the server for --all-databases
+	database           string               // the database being dumped
+	noBackslashEscapes bool                 // the server has NO_BACKSLASH_ESCAPES in its sql_mode
+	stats              *tableStatsCollector // nil unless table stats are enabled for backup verification
+	DBConfig           *mysql.Config
+	db                 queryer
+}
+
+// The subset of *sql.DB, *sql.Conn and *sql.Tx methods used by the dumper.
+// The dumper runs all queries on a single connection, so they can share the same transaction snapshot.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
 func NewMysqlNativeDump(job *config.Job) (*MysqlNativeDump, error) {
@@ -40,21 +67,93 @@ func NewMysqlNativeDump(job *config.Job) (*MysqlNativeDump, error) {
 		return nil, err
 	}
 
+	options := newOptions(job.DumpOptions...)
+
+	if err := validateMysqlNativeOptions(options); err != nil {
+		return nil, err
+	}
+
+	bufferLength, err := options.intValue(netBufferLength, DefaultNetBufferLength)
+	if err != nil {
+		return nil, err
+	}
+
+	if bufferLength <= 0 {
+		return nil, fmt.Errorf("%s should be greater than 0, got %d", netBufferLength, bufferLength)
+	}
+
+	maxRows, err := options.intValue(rowsPerInsert, DefaultRowsPerInsert)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxRows < 0 {
+		return nil, fmt.Errorf("%s should not be negative, got %d", rowsPerInsert, maxRows)
+	}
+
+	workers, err := options.intValue(parallel, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if workers <= 0 {
+		return nil, fmt.Errorf("%s should be greater than 0, got %d", parallel, workers)
+	}
+
+	rowsPerChunk, err := options.intValue(chunkSize, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsPerChunk < 0 {
+		return nil, fmt.Errorf("%s should not be negative, got %d", chunkSize, rowsPerChunk)
+	}
+
+	databaseNames, err := parseDatabases(options, config.DBName)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceData, err := parseSourceData(options)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MysqlNativeDump{
-		options:  newOptions(job.DumpOptions...),
-		viaSsh:   job.ViaSsh(),
-		sshHost:  job.SshHost,
-		sshUser:  job.SshUser,
-		sshKey:   job.SshKey,
-		DBConfig: config,
+		options:         options,
+		sourceData:      sourceData,
+		netBufferLength: bufferLength,
+		rowsPerInsert:   maxRows,
+		parallel:        workers,
+		chunkSize:       rowsPerChunk,
+		viaSsh:          job.ViaSsh(),
+		sshHost:         job.SshHost,
+		sshUser:         job.SshUser,
+		sshKey:          job.SshKey,
+		tables:          job.Tables,
+		databaseNames:   databaseNames,
+		database:        config.DBName,
+		DBConfig:        config,
 	}, nil
 }
 
+// Get the sql_mode of the dump session, string literals must be escaped in the same way as the server parses them.
+func (m *MysqlNativeDump) getSqlMode() (string, error) {
+	var sqlMode string
+
+	if err := m.db.QueryRowContext(context.Background(), "SELECT @@SESSION.sql_mode").Scan(&sqlMode); err != nil {
+		return "", err
+	}
+
+	return sqlMode, nil
+}
+
+// Get the character set of the text values returned by the server, the dump sets the same names to restore them byte for byte.
 func (m *MysqlNativeDump) getCharacterSet() (string, error) {
 	var variableName string
 	var characterSet string
 
-	row := m.db.QueryRow("SHOW VARIABLES LIKE 'character_set_database'")
+	row := m.db.QueryRowContext(context.Background(), "SHOW VARIABLES LIKE 'character_set_results'")
 	err := row.Scan(&variableName, &characterSet)
 
 	if err != nil {
@@ -64,10 +163,11 @@ func (m *MysqlNativeDump) getCharacterSet() (string, error) {
 	return characterSet, nil
 }
 
-func (m *MysqlNativeDump) getTables() ([]string, error) {
-	rows, err := m.db.Query("SHOW TABLES")
+// Get base tables and views of the database that match the table filter.
+func (m *MysqlNativeDump) getTables() ([]string, []string, error) {
+	rows, err := m.db.QueryContext(context.Background(), "SHOW FULL TABLES FROM "+sqlutil.QuoteIdentifier(m.database))
 	if err != nil {
-		return nil, fmt.Errorf("failed to query all tables, err: %v", err)
+		return nil, nil, fmt.Errorf("failed to query all tables, err: %v", err)
 	}
 
 	defer func() {
@@ -77,30 +177,77 @@ func (m *MysqlNativeDump) getTables() ([]string, error) {
 		}
 	}()
 
-	var tables []string
+	var tables, views []string
 
 	for rows.Next() {
-		var table string
+		var table, tableType string
+
+		if err := rows.Scan(&table, &tableType); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan tables, err: %v", err)
+		}
 
-		if err := rows.Scan(&table); err != nil {
-			return nil, fmt.Errorf("failed to scan tables, err: %v", err)
+		if !m.tables.Match(table) {
+			continue
 		}
 
-		tables = append(tables, table)
+		if tableType == "VIEW" {
+			views = append(views, table)
+		} else {
+			tables = append(tables, table)
+		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to get all tables, err: %v", err)
+		return nil, nil, fmt.Errorf("failed to get all tables, err: %v", err)
 	}
 
-	return tables, nil
+	return tables, views, nil
 }
 
+// Stream table rows to the buffer as extended INSERT statements.
+// Rows are never buffered in memory, a new INSERT statement is started
+// once the current one reaches the net buffer length or the rows per insert limit.
 func (m *MysqlNativeDump) writeTableContent(buf *bufio.Writer, table string) error {
-	results, err := m.db.Query(fmt.Sprintf("SELECT * FROM `%s`;", table))
+	if m.chunkSize > 0 {
+		return m.writeTableContentInChunks(buf, table)
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s;", m.qualify(table))
+	if where, ok := m.tables.Where[table]; ok {
+		query = fmt.Sprintf("SELECT * FROM %s WHERE %s;", m.qualify(table), where)
+	}
+
+	w := &insertWriter{m: m, buf: buf, table: table, columnList: m.isCompleteInsert()}
+
+	if _, err := w.writeQueryRows(query); err != nil {
+		return err
+	}
+
+	return m.closeTableContent(w)
+}
+
+// Write rows of a table as extended INSERT statements, the rows may come from multiple queries.
+type insertWriter struct {
+	m            *MysqlNativeDump
+	buf          *bufio.Writer
+	table        string
+	columnList   bool // write the column names in the INSERT statements
+	insertPrefix string
+	typeNames    []string
+	lastRow      []any // the last row written, used by keyset pagination
+
+	sb            strings.Builder
+	statementSize int
+	statementRows int
+	totalRows     int
+}
+
+// Run the query and write all its rows, return the number of rows written.
+func (w *insertWriter) writeQueryRows(query string, args ...any) (int, error) {
+	results, err := w.m.db.QueryContext(context.Background(), query, args...)
 
 	if err != nil {
-		return fmt.Errorf("failed to query table: %s, err: %v", table, err)
+		return 0, fmt.Errorf("failed to query table: %s, err: %v", w.table, err)
 	}
 
 	defer func() {
@@ -113,194 +260,139 @@ func (m *MysqlNativeDump) writeTableContent(buf *bufio.Writer, table string) err
 	columns, err := results.Columns()
 
 	if err != nil {
-		return fmt.Errorf("could not get columns: %v", err)
+		return 0, fmt.Errorf("could not get columns: %v", err)
 	}
 
 	columnTypes, err := results.ColumnTypes()
 	if err != nil {
-		return fmt.Errorf("could not get column types: %v", err)
+		return 0, fmt.Errorf("could not get column types: %v", err)
 	}
 
-	var rows [][]any
-	for results.Next() {
-		row := make([]any, len(columns))
-		dest := make([]any, len(columns))
+	w.typeNames = make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		w.typeNames[i] = columnType.DatabaseTypeName()
+	}
 
-		for i := range row {
-			dest[i] = &row[i]
+	if w.insertPrefix == "" {
+		var prefix strings.Builder
+		prefix.WriteString(w.m.insertStatement() + " INTO " + sqlutil.QuoteIdentifier(w.table))
+		if w.columnList {
+			prefix.WriteString(" (")
+			for i, col := range columns {
+				if i < len(columns)-1 {
+					prefix.WriteString(sqlutil.QuoteIdentifier(col) + ", ")
+				} else {
+					prefix.WriteString(sqlutil.QuoteIdentifier(col) + ")")
+				}
+			}
 		}
+		prefix.WriteString(" VALUES ")
+		w.insertPrefix = prefix.String()
+	}
+
+	row := make([]any, len(columns))
+	dest := make([]any, len(columns))
 
+	for i := range row {
+		dest[i] = &row[i]
+	}
+
+	var n int
+
+	for results.Next() {
 		err = results.Scan(dest...)
 		if err != nil {
-			return fmt.Errorf("failed to scan row to dest, %v", err)
+			return n, fmt.Errorf("failed to scan row to dest, %v", err)
+		}
+
+		if err := w.writeRow(row); err != nil {
+			return n, err
 		}
 
-		rows = append(rows, row)
+		n++
 	}
 
-	var sb strings.Builder
+	if err := results.Err(); err != nil {
+		return n, fmt.Errorf("failed to read rows of table: %s, error: %v", w.table, err)
+	}
+
+	if n > 0 {
+		w.lastRow = row
+	}
+
+	return n, nil
+}
+
+func (w *insertWriter) writeRow(row []any) error {
+	w.sb.Reset()
+	w.sb.WriteString("(")
 
-	if len(rows) > 0 {
-		if !m.options.isEnabled(skipAddLocks) {
-			sb.WriteString("LOCK TABLES `" + table + "` WRITE;\n")
+	for colIndex, value := range row {
+		if err := writeValue(&w.sb, value, w.typeNames[colIndex], w.m.noBackslashEscapes); err != nil {
+			return err
 		}
 
-		sb.WriteString("/*!40000 ALTER TABLE `" + table + "` DISABLE KEYS */;\n")
-		sb.WriteString("INSERT INTO `" + table + "` (")
-		for i, col := range columns {
-			if i < len(columns)-1 {
-				sb.WriteString("`" + col + "`, ")
-			} else {
-				sb.WriteString("`" + col + "`)")
-			}
+		if colIndex < len(row)-1 {
+			w.sb.WriteString(",")
 		}
+	}
 
-		sb.WriteString(" VALUES ")
-	}
-
-	for rowIndex, row := range rows {
-		sb.WriteString("(")
-
-		for colIndex, value := range row {
-			if value == nil {
-				sb.WriteString("NULL")
-			} else {
-				typeName := columnTypes[colIndex].DatabaseTypeName()
-
-				switch typeName {
-				case "TINYINT",
-					"SMALLINT",
-					"MEDIUMINT",
-					"INT",
-					"INTEGER",
-					"BIGINT",
-					"UNSIGNED TINYINT",
-					"UNSIGNED SMALLINT",
-					"UNSIGNED MEDIUMINT",
-					"UNSIGNED INT",
-					"UNSIGNED BIGINT":
-					if v, ok := value.([]byte); ok {
-						sb.WriteString(string(v))
-					} else {
-						sb.WriteString(fmt.Sprintf("%d", value))
-					}
-				case "FLOAT", "DOUBLE":
-					if v, ok := value.([]byte); ok {
-						sb.WriteString(string(v))
-					} else {
-						sb.WriteString(fmt.Sprintf("%f", value))
-					}
-				case "DECIMAL", "DEC":
-					sb.WriteString(fmt.Sprintf("%s", value))
-				case "DATE":
-					v, ok := value.([]uint8)
-
-					if !ok {
-						return fmt.Errorf("could not parse DATE type, expect []uint8, got %T", value)
-					}
-
-					sb.WriteString(fmt.Sprintf("'%s'", v))
-				case "DATETIME":
-					v, ok := value.([]byte)
-					if !ok {
-						return fmt.Errorf("could not parse DATETIME type, expect []byte, got %T", value)
-					}
-
-					sb.WriteString(fmt.Sprintf("'%s'", string(v)))
-				case "TIMESTAMP":
-					v, ok := value.([]byte)
-					if !ok {
-						return fmt.Errorf("could not parse TIMESTAMP type, expect []byte, got %T", value)
-					}
-					sb.WriteString(fmt.Sprintf("'%s'", string(v)))
-				case "TIME":
-					v, ok := value.([]byte)
-					if !ok {
-						return fmt.Errorf("could not parse TIME type, expect []byte, got %T", value)
-					}
-					sb.WriteString(fmt.Sprintf("'%s'", string(v)))
-				case "YEAR":
-					v, ok := value.(int64)
-					if !ok {
-						return fmt.Errorf("could not parse YEAR type, expect int64, got %T", value)
-					}
-					sb.WriteString(fmt.Sprintf("'%d'", v))
-				case "CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT":
-					sb.WriteString(fmt.Sprintf("'%s'", strings.Replace(fmt.Sprintf("%s", value), "'", "''", -1)))
-				case "BINARY":
-					v, ok := value.([]uint8)
-					if !ok {
-						return fmt.Errorf("could not parse BINARY type, expect []uint8, got %T", value)
-					}
-
-					v = bytes.TrimRight(v, "\x00") // skip trailing null value
-					sb.WriteString(fmt.Sprintf("'%s'", v))
-				case "VARBINARY":
-					sb.WriteString(fmt.Sprintf("'%s'", value))
-				case "BIT":
-					v, ok := value.([]uint8)
-					if !ok {
-						return fmt.Errorf("cloud not parse BIT type, expect []uint8, got %T", value)
-					}
-
-					if len(v) > 1 {
-						return fmt.Errorf("failed to parse BIT type, expected length 1, got %d", len(v))
-					}
-
-					sb.WriteString(fmt.Sprintf("b'%d'", v[0]))
-				case "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "ENUM", "SET":
-					sb.WriteString(fmt.Sprintf("'%s'", value))
-				case "BOOL", "BOOLEAN":
-					if value.(bool) {
-						sb.WriteString("true")
-					} else {
-						sb.WriteString("false")
-					}
-				case "JSON":
-					v, ok := value.([]uint8)
-					if !ok {
-						return fmt.Errorf("cloud not parse JSON type, expect []unint8, got %T", value)
-					}
-
-					json := string(v)
-
-					json = strings.ReplaceAll(json, `"`, `\"`)
-					sb.WriteString(fmt.Sprintf("'%s'", json))
-				default:
-					return fmt.Errorf("unsupported database type: %s", typeName)
-				}
-			}
+	w.sb.WriteString(")")
 
-			if colIndex < len(row)-1 {
-				sb.WriteString(",")
-			}
+	if w.totalRows == 0 {
+		if !w.m.options.isEnabled(skipAddLocks) {
+			w.buf.WriteString("LOCK TABLES " + sqlutil.QuoteIdentifier(w.table) + " WRITE;\n")
 		}
 
-		sb.WriteString(")")
+		w.buf.WriteString("/*!40000 ALTER TABLE " + sqlutil.QuoteIdentifier(w.table) + " DISABLE KEYS */;\n")
+	}
 
-		if rowIndex < len(rows)-1 {
-			sb.WriteString(",")
-		} else {
-			sb.WriteString(";")
-		}
+	// Close the current statement if the next row would exceed the limits.
+	if w.statementRows > 0 &&
+		(w.statementSize+w.sb.Len()+1 > w.m.netBufferLength || (w.m.rowsPerInsert > 0 && w.statementRows >= w.m.rowsPerInsert)) {
+		w.buf.WriteString(";\n")
+		w.statementRows = 0
 	}
 
-	sb.WriteString("\n/*!40000 ALTER TABLE `" + table + "` ENABLE KEYS */;\n")
-	if !m.options.isEnabled(skipAddLocks) {
-		sb.WriteString("UNLOCK TABLES;")
+	if w.statementRows == 0 {
+		w.buf.WriteString(w.insertPrefix)
+		w.statementSize = len(w.insertPrefix)
+	} else {
+		w.buf.WriteString(",")
+		w.statementSize++
 	}
 
-	sb.WriteString("\n\n")
+	if _, err := w.buf.WriteString(w.sb.String()); err != nil {
+		return fmt.Errorf("failed to write insert statement for table: %s, error: %v", w.table, err)
+	}
 
-	_, err = buf.WriteString(sb.String())
+	w.statementSize += w.sb.Len()
+	w.statementRows++
+	w.totalRows++
+
+	return nil
+}
+
+// Close the last INSERT statement, nothing is written for an empty table.
+func (w *insertWriter) close() error {
+	if w.totalRows == 0 {
+		return nil
+	}
+
+	w.buf.WriteString(";\n/*!40000 ALTER TABLE " + sqlutil.QuoteIdentifier(w.table) + " ENABLE KEYS */;\n")
+	if !w.m.options.isEnabled(skipAddLocks) {
+		w.buf.WriteString("UNLOCK TABLES;")
+	}
+
+	_, err := w.buf.WriteString("\n\n")
 	if err != nil {
-		return fmt.Errorf("failed to write insert statement: %s, error: %v", sb.String(), err)
+		return fmt.Errorf("failed to write table content for table: %s, error: %v", w.table, err)
 	}
 
 	return nil
 }
 
-func (m *MysqlNativeDump) writeHeader(buf *bufio.Writer) error {
+func (m *MysqlNativeDump) writeHeader(buf *bufio.Writer, coordinates *binlogCoordinates) error {
 	charSet, err := m.getCharacterSet()
 	if err != nil {
 		return err
@@ -313,7 +405,7 @@ func (m *MysqlNativeDump) writeHeader(buf *bufio.Writer) error {
 	sb.WriteString("--\n")
 	sb.WriteString("-- https://github.com/liweiyi88/onedump\n")
 	sb.WriteString("--\n")
-	sb.WriteString("-- Database: " + m.DBConfig.DBName + "\n")
+	sb.WriteString("-- Database: " + strings.Join(m.databaseNames, ", ") + "\n")
 	sb.WriteString("\n\n")
 	sb.WriteString("/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;\n")
 	sb.WriteString("/*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS */;\n")
@@ -321,10 +413,19 @@ func (m *MysqlNativeDump) writeHeader(buf *bufio.Writer) error {
 	sb.WriteString("/*!40101 SET NAMES " + charSet + " */;\n")
 	sb.WriteString("/*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHECKS=0 */;\n")
 	sb.WriteString("/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;\n")
-	sb.WriteString("/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;\n")
+	if m.noBackslashEscapes {
+		sb.WriteString("/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO," + noBackslashEscapesMode + "' */;\n")
+	} else {
+		sb.WriteString("/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;\n")
+	}
+
 	sb.WriteString("/*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;\n")
 	sb.WriteString("\n\n")
 
+	if coordinates != nil {
+		sb.WriteString(m.binlogCoordinatesComment(coordinates))
+	}
+
 	_, err = buf.WriteString(sb.String())
 	return err
 }
@@ -349,13 +450,13 @@ func (m *MysqlNativeDump) writeTableStructure(buf *bufio.Writer, table string) e
 	var sb strings.Builder
 
 	if !m.options.isEnabled(skipAddDropTable) {
-		sb.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", table))
+		sb.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", sqlutil.QuoteIdentifier(table)))
 	}
 
 	var name string
 	var createTable string
 
-	row := m.db.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", table))
+	row := m.db.QueryRowContext(context.Background(), "SHOW CREATE TABLE "+m.qualify(table))
 	err := row.Scan(&name, &createTable)
 
 	if err != nil {
@@ -401,6 +502,13 @@ func (m *MysqlNativeDump) Dump(storage io.Writer) error {
 		return fmt.Errorf("fail to open database, error: %v", err)
 	}
 
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			slog.Error("failed to close db", slog.Any("error", err))
+		}
+	}()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	pingErr := db.PingContext(ctx)
@@ -409,47 +517,163 @@ func (m *MysqlNativeDump) Dump(storage io.Writer) error {
 		return pingErr
 	}
 
-	m.db = db
-	slog.Debug("database connected.")
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("fail to get database connection, error: %v", err)
+	}
 
 	defer func() {
-		err := m.db.Close()
+		err := conn.Close()
 		if err != nil {
-			slog.Error("failed to close db", slog.Any("error", err))
+			slog.Error("failed to close db connection", slog.Any("error", err))
 		}
 	}()
 
-	tables, err := m.getTables()
+	m.db = conn
+	slog.Debug("database connected.")
+
+	sqlMode, err := m.getSqlMode()
+	if err != nil {
+		return fmt.Errorf("failed to get sql_mode, error: %v", err)
+	}
+
+	m.noBackslashEscapes = hasNoBackslashEscapes(sqlMode)
+
+	workerConns, err := openWorkerConns(db, m.parallel-1)
 	if err != nil {
 		return err
 	}
 
+	defer closeWorkerConns(workerConns)
+
+	workers := []queryer{conn}
+	for _, workerConn := range workerConns {
+		workers = append(workers, workerConn)
+	}
+
+	var coordinates *binlogCoordinates
+	if m.isSnapshotEnabled() {
+		coordinates, err = m.startSnapshot(workers[1:]...)
+		if err != nil {
+			return fmt.Errorf("failed to start consistent snapshot, error: %v", err)
+		}
+	}
+
+	if m.options.isEnabled(allDatabases) {
+		m.databaseNames, err = m.getAllDatabases()
+		if err != nil {
+			return fmt.Errorf("failed to get all databases, error: %v", err)
+		}
+	}
+
 	buf := bufio.NewWriter(storage)
 	defer buf.Flush()
 
-	err = m.writeHeader(buf)
+	err = m.writeHeader(buf, coordinates)
 	if err != nil {
 		return fmt.Errorf("failed to write dump header, error: %v", err)
 	}
 
-	for _, table := range tables {
-		err := m.writeTableStructure(buf, table)
-		if err != nil {
-			return fmt.Errorf("failed to write table structure, table: %s, error: %v", table, err)
+	for _, database := range m.databaseNames {
+		m.database = database
+
+		if err := m.dumpDatabase(buf, workers); err != nil {
+			return err
 		}
 	}
 
-	for _, table := range tables {
-		err := m.writeTableContent(buf, table)
+	err = m.writeFooter(buf)
+	if err != nil {
+		return fmt.Errorf("failed to write dump footer, error: %v", err)
+	}
 
-		if err != nil {
-			return fmt.Errorf("failed to write table content, table: %s, error: %v", table, err)
+	return nil
+}
+
+// Dump tables, views, triggers, events and routines of the current database.
+func (m *MysqlNativeDump) dumpDatabase(buf *bufio.Writer, workers []queryer) error {
+	if m.isMultiDatabase() {
+		if err := m.writeDatabaseStructure(buf); err != nil {
+			return fmt.Errorf("failed to write database structure, database: %s, error: %v", m.database, err)
 		}
 	}
 
-	err = m.writeFooter(buf)
+	tables, views, err := m.getTables()
 	if err != nil {
-		return fmt.Errorf("failed to write dump footer, error: %v", err)
+		return err
+	}
+
+	// Like mysqldump, --no-create-info skips views as well.
+	createInfo := !m.options.isEnabled(noCreateInfo)
+
+	if createInfo {
+		for _, table := range tables {
+			err := m.writeTableStructure(buf, table)
+			if err != nil {
+				return fmt.Errorf("failed to write table structure, table: %s, error: %v", table, err)
+			}
+		}
+
+		// Views may depend on other views, so create placeholders first and replace them once all views are known.
+		for _, view := range views {
+			err := m.writeViewPlaceholder(buf, view)
+			if err != nil {
+				return fmt.Errorf("failed to write view placeholder, view: %s, error: %v", view, err)
+			}
+		}
+	}
+
+	var dataTables []string
+	if !m.options.isEnabled(noData) {
+		for _, table := range tables {
+			if !m.tables.IsSchemaOnly(table) {
+				dataTables = append(dataTables, table)
+			}
+		}
+	}
+
+	if len(workers) > 1 {
+		if err := m.writeTableContentsParallel(buf, dataTables, workers); err != nil {
+			return err
+		}
+	} else {
+		for _, table := range dataTables {
+			err := m.writeTableContent(buf, table)
+
+			if err != nil {
+				return fmt.Errorf("failed to write table content, table: %s, error: %v", table, err)
+			}
+		}
+	}
+
+	if m.isTriggersEnabled() {
+		for _, table := range tables {
+			err := m.writeTriggers(buf, table)
+			if err != nil {
+				return fmt.Errorf("failed to write triggers, table: %s, error: %v", table, err)
+			}
+		}
+	}
+
+	if m.options.isEnabled(events) {
+		if err := m.writeEvents(buf); err != nil {
+			return fmt.Errorf("failed to write events, error: %v", err)
+		}
+	}
+
+	if m.options.isEnabled(routines) {
+		if err := m.writeRoutines(buf); err != nil {
+			return fmt.Errorf("failed to write routines, error: %v", err)
+		}
+	}
+
+	if createInfo {
+		for _, view := range views {
+			err := m.writeViewStructure(buf, view)
+			if err != nil {
+				return fmt.Errorf("failed to write view structure, view: %s, error: %v", view, err)
+			}
+		}
 	}
 
 	return nil
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 	"unsafe"
@@ -41,19 +42,27 @@ func TestNewMysqlDump(t *testing.T) {
 	_, err := NewMysqlNativeDump(job)
 
 	assert.Nil(err)
+
+	job = config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--net-buffer-length=0"))
+	_, err = NewMysqlNativeDump(job)
+	assert.EqualError(err, "--net-buffer-length should be greater than 0, got 0")
+
+	job = config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--rows-per-insert=-1"))
+	_, err = NewMysqlNativeDump(job)
+	assert.EqualError(err, "--rows-per-insert should not be negative, got -1")
 }
 
 func TestGetCharacterSet(t *testing.T) {
 	assert, db, mock := initTest(t)
 	mysql := createTestMysqlNativeDump(db)
 
-	mock.ExpectQuery("SHOW VARIABLES LIKE 'character_set_database'").WillReturnError(errors.New("db err"))
+	mock.ExpectQuery("SHOW VARIABLES LIKE 'character_set_results'").WillReturnError(errors.New("db err"))
 	_, err := mysql.getCharacterSet()
 	assert.NotNil(err)
 
-	rows := mock.NewRows([]string{"variableName", "characterSet"}).AddRow("character_set_database", "utf8")
+	rows := mock.NewRows([]string{"variableName", "characterSet"}).AddRow("character_set_results", "utf8")
 
-	mock.ExpectQuery("SHOW VARIABLES LIKE 'character_set_database'").WillReturnRows(rows)
+	mock.ExpectQuery("SHOW VARIABLES LIKE 'character_set_results'").WillReturnRows(rows)
 	charset, err := mysql.getCharacterSet()
 	assert.Nil(err)
 	assert.Equal("utf8", charset)
@@ -63,24 +72,28 @@ func TestGetTables(t *testing.T) {
 	assert, db, mock := initTest(t)
 	mysql := createTestMysqlNativeDump(db)
 
-	mock.ExpectQuery("SHOW TABLES").WillReturnError(errors.New("db err"))
-	_, err := mysql.getTables()
+	mock.ExpectQuery("SHOW FULL TABLES FROM `dump_test`").WillReturnError(errors.New("db err"))
+	_, _, err := mysql.getTables()
 	assert.NotNil(err)
 
-	rows := mock.NewRows([]string{"tables"}).AddRow("onedump").AddRow("users")
+	rows := mock.NewRows([]string{"Tables_in_dump_test", "Table_type"}).
+		AddRow("onedump", "BASE TABLE").
+		AddRow("user_view", "VIEW").
+		AddRow("users", "BASE TABLE")
 
-	mock.ExpectQuery("SHOW TABLES").WillReturnRows(rows)
+	mock.ExpectQuery("SHOW FULL TABLES FROM `dump_test`").WillReturnRows(rows)
 
-	tables, err := mysql.getTables()
+	tables, views, err := mysql.getTables()
 	assert.Nil(err)
 	assert.Equal([]string{"onedump", "users"}, tables)
+	assert.Equal([]string{"user_view"}, views)
 }
 
 func TestWriteTableContentOK(t *testing.T) {
 	assert, db, mock := initTest(t)
 	mysql := createTestMysqlNativeDump(db)
 
-	mock.ExpectQuery("SELECT * FROM `onedump`;").WillReturnError(errors.New("failed to query table"))
+	mock.ExpectQuery("SELECT * FROM `dump_test`.`onedump`;").WillReturnError(errors.New("failed to query table"))
 
 	var b bytes.Buffer
 	buf := bufio.NewWriter(&b)
@@ -122,7 +135,7 @@ func TestWriteTableContentOK(t *testing.T) {
 		Elem().
 		Set(reflect.ValueOf(newColumns))
 
-	mock.ExpectQuery("SELECT * FROM `onedump`;").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT * FROM `dump_test`.`onedump`;").WillReturnRows(rows)
 
 	err = mysql.writeTableContent(buf, "onedump")
 
@@ -131,11 +144,86 @@ func TestWriteTableContentOK(t *testing.T) {
 	}
 }
 
+func TestWriteTableContentSplitInserts(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+
+	newRows := func() *sqlmock.Rows {
+		return mock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("INT", int64(0)),
+			sqlmock.NewColumn("name").OfType("VARCHAR", ""),
+		).
+			AddRow(int64(1), "a").
+			AddRow(int64(2), "b").
+			AddRow(int64(3), "c")
+	}
+
+	t.Run("it should split INSERT statements by rows per insert", func(t *testing.T) {
+		mysql.rowsPerInsert = 2
+		mysql.netBufferLength = DefaultNetBufferLength
+		mock.ExpectQuery("SELECT * FROM `dump_test`.`onedump`;").WillReturnRows(newRows())
+
+		var b bytes.Buffer
+		buf := bufio.NewWriter(&b)
+		assert.NoError(mysql.writeTableContent(buf, "onedump"))
+		assert.NoError(buf.Flush())
+
+		expected := "LOCK TABLES `onedump` WRITE;\n" +
+			"/*!40000 ALTER TABLE `onedump` DISABLE KEYS */;\n" +
+			"INSERT INTO `onedump` (`id`, `name`) VALUES (1,'a'),(2,'b');\n" +
+			"INSERT INTO `onedump` (`id`, `name`) VALUES (3,'c');\n" +
+			"/*!40000 ALTER TABLE `onedump` ENABLE KEYS */;\n" +
+			"UNLOCK TABLES;\n\n"
+		assert.Equal(expected, b.String())
+	})
+
+	t.Run("it should split INSERT statements by net buffer length", func(t *testing.T) {
+		mysql.rowsPerInsert = 0
+		// The INSERT prefix is 44 bytes and each row is 7 bytes, so only one row fits in a statement.
+		mysql.netBufferLength = 55
+		mock.ExpectQuery("SELECT * FROM `dump_test`.`onedump`;").WillReturnRows(newRows())
+
+		var b bytes.Buffer
+		buf := bufio.NewWriter(&b)
+		assert.NoError(mysql.writeTableContent(buf, "onedump"))
+		assert.NoError(buf.Flush())
+
+		assert.Equal(3, strings.Count(b.String(), "INSERT INTO"))
+	})
+
+	t.Run("it should escape the backticks of the table and column names", func(t *testing.T) {
+		mysql.rowsPerInsert = 0
+		mysql.netBufferLength = DefaultNetBufferLength
+		rows := mock.NewRowsWithColumnDefinition(sqlmock.NewColumn("na`me").OfType("VARCHAR", "")).AddRow("a")
+		mock.ExpectQuery("SELECT * FROM `dump_test`.`one``dump`;").WillReturnRows(rows)
+
+		var b bytes.Buffer
+		buf := bufio.NewWriter(&b)
+		assert.NoError(mysql.writeTableContent(buf, "one`dump"))
+		assert.NoError(buf.Flush())
+
+		assert.Contains(b.String(), "LOCK TABLES `one``dump` WRITE;\n")
+		assert.Contains(b.String(), "INSERT INTO `one``dump` (`na``me`) VALUES ('a');\n")
+		assert.Contains(b.String(), "/*!40000 ALTER TABLE `one``dump` ENABLE KEYS */;\n")
+	})
+
+	t.Run("it should not write anything for an empty table", func(t *testing.T) {
+		rows := mock.NewRowsWithColumnDefinition(sqlmock.NewColumn("id").OfType("INT", int64(0)))
+		mock.ExpectQuery("SELECT * FROM `dump_test`.`onedump`;").WillReturnRows(rows)
+
+		var b bytes.Buffer
+		buf := bufio.NewWriter(&b)
+		assert.NoError(mysql.writeTableContent(buf, "onedump"))
+		assert.NoError(buf.Flush())
+		assert.Empty(b.String())
+	})
+}
+
 func TestWriteTableContentInvalidColumnType(t *testing.T) {
 	assert, db, mock := initTest(t)
 	mysql := createTestMysqlNativeDump(db)
 
-	mock.ExpectQuery("SELECT * FROM `onedump`;").WillReturnError(errors.New("failed to query table"))
+	mock.ExpectQuery("SELECT * FROM `dump_test`.`onedump`;").WillReturnError(errors.New("failed to query table"))
 
 	var b bytes.Buffer
 	buf := bufio.NewWriter(&b)
@@ -150,7 +238,7 @@ func TestWriteTableContentInvalidColumnType(t *testing.T) {
 	testRows = append(testRows, mock.NewRows([]string{"year"}).AddRow("1"))
 	testRows = append(testRows, mock.NewRows([]string{"binary"}).AddRow("1"))
 	testRows = append(testRows, mock.NewRows([]string{"bit"}).AddRow("1"))
-	testRows = append(testRows, mock.NewRows([]string{"bit"}).AddRow([]uint8{1, 2}))
+	testRows = append(testRows, mock.NewRows([]string{"bit"}).AddRow([]uint8{1, 2, 3, 4, 5, 6, 7, 8, 9}))
 	testRows = append(testRows, mock.NewRows([]string{"json"}).AddRow("1"))
 	testRows = append(testRows, mock.NewRows([]string{"unsupport"}).AddRow("1"))
 
@@ -202,7 +290,7 @@ func TestWriteTableContentInvalidColumnType(t *testing.T) {
 		"could not parse YEAR type, expect int64, got string",
 		"could not parse BINARY type, expect []uint8, got string",
 		"cloud not parse BIT type, expect []uint8, got string",
-		"failed to parse BIT type, expected length 1, got 2",
+		"failed to parse BIT type, expected length between 1 and 8, got 9",
 		"cloud not parse JSON type, expect []unint8, got string",
 		"unsupported database type: UNSUPPORT",
 	}
@@ -215,7 +303,7 @@ func TestWriteTableContentInvalidColumnType(t *testing.T) {
 			Elem().
 			Set(reflect.ValueOf(testColumns[i]))
 
-		mock.ExpectQuery("SELECT * FROM `onedump`;").WillReturnRows(rows)
+		mock.ExpectQuery("SELECT * FROM `dump_test`.`onedump`;").WillReturnRows(rows)
 
 		err = mysql.writeTableContent(buf, "onedump")
 
@@ -230,12 +318,12 @@ func TestWriteHeader(t *testing.T) {
 	mysql := createTestMysqlNativeDump(db)
 
 	rows := mock.NewRows([]string{"variableName", "characterSet"}).AddRow("chartset", "utf8")
-	mock.ExpectQuery("SHOW VARIABLES LIKE 'character_set_database'").WillReturnRows(rows)
+	mock.ExpectQuery("SHOW VARIABLES LIKE 'character_set_results'").WillReturnRows(rows)
 
 	var b bytes.Buffer
 	buf := bufio.NewWriter(&b)
 
-	err := mysql.writeHeader(buf)
+	err := mysql.writeHeader(buf, nil)
 	assert.Nil(err)
 }
 
@@ -255,11 +343,47 @@ func TestWriteTableStructure(t *testing.T) {
 	mysql := createTestMysqlNativeDump(db)
 
 	rows := mock.NewRows([]string{"name", "createTable"}).AddRow("name", "table_structure")
-	mock.ExpectQuery("SHOW CREATE TABLE `onedump`").WillReturnRows(rows)
+	mock.ExpectQuery("SHOW CREATE TABLE `dump_test`.`onedump`").WillReturnRows(rows)
 
 	var b bytes.Buffer
 	buf := bufio.NewWriter(&b)
 
 	err := mysql.writeTableStructure(buf, "onedump")
 	assert.Nil(err)
+
+	rows = mock.NewRows([]string{"name", "createTable"}).AddRow("one`dump", "table_structure")
+	mock.ExpectQuery("SHOW CREATE TABLE `dump_test`.`one``dump`").WillReturnRows(rows)
+
+	assert.NoError(mysql.writeTableStructure(buf, "one`dump"))
+	assert.NoError(buf.Flush())
+	assert.Contains(b.String(), "DROP TABLE IF EXISTS `one``dump`;\ntable_structure;\n\n")
+}
+
+func TestTableFilter(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+	mysql.tables = config.TableFilter{
+		Exclude: []string{"*_logs"},
+		Where:   map[string]string{"events": "created_at > '2024-01-01'"},
+	}
+
+	rows := mock.NewRows([]string{"Tables_in_dump_test", "Table_type"}).
+		AddRow("audit_logs", "BASE TABLE").
+		AddRow("events", "BASE TABLE").
+		AddRow("event_logs", "VIEW")
+
+	mock.ExpectQuery("SHOW FULL TABLES FROM `dump_test`").WillReturnRows(rows)
+
+	tables, views, err := mysql.getTables()
+	assert.Nil(err)
+	assert.Equal([]string{"events"}, tables)
+	assert.Empty(views)
+
+	mock.ExpectQuery("SELECT * FROM `dump_test`.`events` WHERE created_at > '2024-01-01';").
+		WillReturnRows(mock.NewRows([]string{"id"}))
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+	assert.Nil(mysql.writeTableContent(buf, "events"))
+	assert.Nil(mock.ExpectationsWereMet())
 }
@@ -0,0 +1,285 @@
+package dumper
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+const (
+	routines     = "--routines"
+	events       = "--events"
+	triggers     = "--triggers" // enabled by default, the same as mysqldump
+	skipTriggers = "--skip-triggers"
+)
+
+func (m *MysqlNativeDump) isTriggersEnabled() bool {
+	return !m.options.isEnabled(skipTriggers)
+}
+
+// Run a query and return all rows with values mapped by column names.
+// SHOW statements return different columns across object types and server versions, so we do not scan them by position.
+func (m *MysqlNativeDump) queryColumns(query string, args ...any) ([]map[string]sql.NullString, error) {
+	rows, err := m.db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("fail to run query %s, error: %v", query, err)
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("fail to close database rows", slog.Any("error", err), slog.Any("query", query))
+		}
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("fail to get columns of query %s, error: %v", query, err)
+	}
+
+	var results []map[string]sql.NullString
+
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("fail to scan database rows, query: %s, error: %v", query, err)
+		}
+
+		result := make(map[string]sql.NullString, len(columns))
+		for i, column := range columns {
+			result[column] = values[i]
+		}
+
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fail to read rows of query %s, error: %v", query, err)
+	}
+
+	return results, nil
+}
+
+// Run a SHOW CREATE statement and return the single result row.
+// The create statement column is NULL when the user does not have enough privileges to see the definition.
+func (m *MysqlNativeDump) showCreate(query string, createColumn string) (map[string]sql.NullString, error) {
+	results, err := m.queryColumns(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no result returned by query %s", query)
+	}
+
+	if !results[0][createColumn].Valid {
+		return nil, fmt.Errorf("%s is empty from query %s, check if the user has enough privileges", createColumn, query)
+	}
+
+	return results[0], nil
+}
+
+// Query a single column of a list of object names.
+func (m *MysqlNativeDump) queryNames(query string, args ...any) ([]string, error) {
+	results, err := m.queryColumns(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(results))
+	for _, result := range results {
+		for _, value := range result {
+			names = append(names, value.String)
+		}
+	}
+
+	return names, nil
+}
+
+// A view can not be created before the views it depends on. Like mysqldump, we create a placeholder view
+// with the same columns after all tables, then replace it with the real view at the end of the dump.
+func (m *MysqlNativeDump) writeViewPlaceholder(buf *bufio.Writer, view string) error {
+	columns, err := m.queryColumns("SHOW COLUMNS FROM " + m.qualify(view))
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+
+	if !m.options.isEnabled(skipAddDropTable) {
+		sb.WriteString("DROP TABLE IF EXISTS " + sqlutil.QuoteIdentifier(view) + ";\n")
+		sb.WriteString("/*!50001 DROP VIEW IF EXISTS " + sqlutil.QuoteIdentifier(view) + "*/;\n")
+	}
+
+	sb.WriteString("/*!50001 CREATE VIEW " + sqlutil.QuoteIdentifier(view) + " AS SELECT ")
+
+	for i, column := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		sb.WriteString("1 AS " + sqlutil.QuoteIdentifier(column["Field"].String))
+	}
+
+	sb.WriteString("*/;\n\n")
+
+	_, err = buf.WriteString(sb.String())
+	return err
+}
+
+func (m *MysqlNativeDump) writeViewStructure(buf *bufio.Writer, view string) error {
+	result, err := m.showCreate("SHOW CREATE VIEW "+m.qualify(view), "Create View")
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("/*!50001 DROP VIEW IF EXISTS " + sqlutil.QuoteIdentifier(view) + "*/;\n")
+	sb.WriteString("/*!50001 SET @saved_cs_client = @@character_set_client */;\n")
+	sb.WriteString("/*!50001 SET @saved_cs_results = @@character_set_results */;\n")
+	sb.WriteString("/*!50001 SET @saved_col_connection = @@collation_connection */;\n")
+	sb.WriteString("/*!50001 SET character_set_client = " + result["character_set_client"].String + " */;\n")
+	sb.WriteString("/*!50001 SET character_set_results = " + result["character_set_client"].String + " */;\n")
+	sb.WriteString("/*!50001 SET collation_connection = " + result["collation_connection"].String + " */;\n")
+	sb.WriteString(result["Create View"].String + ";\n")
+	sb.WriteString("/*!50001 SET character_set_client = @saved_cs_client */;\n")
+	sb.WriteString("/*!50001 SET character_set_results = @saved_cs_results */;\n")
+	sb.WriteString("/*!50001 SET collation_connection = @saved_col_connection */;\n\n")
+
+	_, err = buf.WriteString(sb.String())
+	return err
+}
+
+// Write a trigger, routine or event definition.
+// The body may contain semicolons, so it is wrapped in a DELIMITER block with the session settings it was created with.
+func writeStoredProgram(sb *strings.Builder, definition map[string]sql.NullString, createColumn string) {
+	sb.WriteString("/*!50003 SET @saved_cs_client = @@character_set_client */ ;\n")
+	sb.WriteString("/*!50003 SET @saved_cs_results = @@character_set_results */ ;\n")
+	sb.WriteString("/*!50003 SET @saved_col_connection = @@collation_connection */ ;\n")
+	sb.WriteString("/*!50003 SET character_set_client = " + definition["character_set_client"].String + " */ ;\n")
+	sb.WriteString("/*!50003 SET character_set_results = " + definition["character_set_client"].String + " */ ;\n")
+	sb.WriteString("/*!50003 SET collation_connection = " + definition["collation_connection"].String + " */ ;\n")
+	sb.WriteString("/*!50003 SET @saved_sql_mode = @@sql_mode */ ;\n")
+	sb.WriteString("/*!50003 SET sql_mode = '" + definition["sql_mode"].String + "' */ ;\n")
+
+	timeZone, hasTimeZone := definition["time_zone"]
+	if hasTimeZone {
+		sb.WriteString("/*!50106 SET @saved_time_zone = @@time_zone */ ;\n")
+		sb.WriteString("/*!50106 SET time_zone = '" + timeZone.String + "' */ ;\n")
+	}
+
+	sb.WriteString("DELIMITER ;;\n")
+	sb.WriteString(definition[createColumn].String + " ;;\n")
+	sb.WriteString("DELIMITER ;\n")
+
+	if hasTimeZone {
+		sb.WriteString("/*!50106 SET time_zone = @saved_time_zone */ ;\n")
+	}
+
+	sb.WriteString("/*!50003 SET sql_mode = @saved_sql_mode */ ;\n")
+	sb.WriteString("/*!50003 SET character_set_client = @saved_cs_client */ ;\n")
+	sb.WriteString("/*!50003 SET character_set_results = @saved_cs_results */ ;\n")
+	sb.WriteString("/*!50003 SET collation_connection = @saved_col_connection */ ;\n\n")
+}
+
+// Write triggers of a table in their action order. They are written after the table content,
+// so restoring the data does not fire them.
+func (m *MysqlNativeDump) writeTriggers(buf *bufio.Writer, table string) error {
+	names, err := m.queryNames(
+		"SELECT TRIGGER_NAME FROM information_schema.TRIGGERS WHERE EVENT_OBJECT_SCHEMA = ? AND EVENT_OBJECT_TABLE = ? ORDER BY ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORDER",
+		m.database,
+		table,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+
+	for _, name := range names {
+		definition, err := m.showCreate("SHOW CREATE TRIGGER "+m.qualify(name), "SQL Original Statement")
+		if err != nil {
+			return err
+		}
+
+		sb.WriteString("/*!50032 DROP TRIGGER IF EXISTS " + sqlutil.QuoteIdentifier(name) + " */;\n")
+		writeStoredProgram(&sb, definition, "SQL Original Statement")
+	}
+
+	_, err = buf.WriteString(sb.String())
+	return err
+}
+
+// Write stored procedures and functions.
+func (m *MysqlNativeDump) writeRoutines(buf *bufio.Writer) error {
+	results, err := m.queryColumns(
+		"SELECT ROUTINE_NAME, ROUTINE_TYPE FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ? ORDER BY ROUTINE_TYPE, ROUTINE_NAME",
+		m.database,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+
+	for _, result := range results {
+		name := result["ROUTINE_NAME"].String
+		routineType := result["ROUTINE_TYPE"].String
+
+		var createColumn string
+		switch routineType {
+		case "PROCEDURE":
+			createColumn = "Create Procedure"
+		case "FUNCTION":
+			createColumn = "Create Function"
+		default:
+			return fmt.Errorf("unsupported routine type: %s, routine: %s", routineType, name)
+		}
+
+		definition, err := m.showCreate(fmt.Sprintf("SHOW CREATE %s %s", routineType, m.qualify(name)), createColumn)
+		if err != nil {
+			return err
+		}
+
+		sb.WriteString(fmt.Sprintf("/*!50003 DROP %s IF EXISTS %s */;\n", routineType, sqlutil.QuoteIdentifier(name)))
+		writeStoredProgram(&sb, definition, createColumn)
+	}
+
+	_, err = buf.WriteString(sb.String())
+	return err
+}
+
+// Write scheduled events.
+func (m *MysqlNativeDump) writeEvents(buf *bufio.Writer) error {
+	names, err := m.queryNames("SELECT EVENT_NAME FROM information_schema.EVENTS WHERE EVENT_SCHEMA = ? ORDER BY EVENT_NAME", m.database)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+
+	for _, name := range names {
+		definition, err := m.showCreate("SHOW CREATE EVENT "+m.qualify(name), "Create Event")
+		if err != nil {
+			return err
+		}
+
+		sb.WriteString("/*!50106 DROP EVENT IF EXISTS " + sqlutil.QuoteIdentifier(name) + " */;\n")
+		writeStoredProgram(&sb, definition, "Create Event")
+	}
+
+	_, err = buf.WriteString(sb.String())
+	return err
+}
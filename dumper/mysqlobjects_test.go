@@ -0,0 +1,195 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/liweiyi88/onedump/config"
+)
+
+func TestWriteViewPlaceholder(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+
+	rows := mock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
+		AddRow("id", "int", "NO", "", "0", "").
+		AddRow("name", "varchar(255)", "YES", "", nil, "")
+
+	mock.ExpectQuery("SHOW COLUMNS FROM `dump_test`.`user_view`").WillReturnRows(rows)
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+
+	err := mysql.writeViewPlaceholder(buf, "user_view")
+	assert.Nil(err)
+	assert.Nil(buf.Flush())
+
+	expected := "DROP TABLE IF EXISTS `user_view`;\n" +
+		"/*!50001 DROP VIEW IF EXISTS `user_view`*/;\n" +
+		"/*!50001 CREATE VIEW `user_view` AS SELECT 1 AS `id`, 1 AS `name`*/;\n\n"
+
+	assert.Equal(expected, b.String())
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestWriteViewStructure(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+
+	mock.ExpectQuery("SHOW CREATE VIEW `dump_test`.`user_view`").WillReturnError(errors.New("db err"))
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+
+	err := mysql.writeViewStructure(buf, "user_view")
+	assert.NotNil(err)
+
+	rows := mock.NewRows([]string{"View", "Create View", "character_set_client", "collation_connection"}).
+		AddRow("user_view", "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `user_view` AS select `users`.`id` AS `id` from `users`", "utf8mb4", "utf8mb4_0900_ai_ci")
+
+	mock.ExpectQuery("SHOW CREATE VIEW `dump_test`.`user_view`").WillReturnRows(rows)
+
+	err = mysql.writeViewStructure(buf, "user_view")
+	assert.Nil(err)
+	assert.Nil(buf.Flush())
+
+	expected := "/*!50001 DROP VIEW IF EXISTS `user_view`*/;\n" +
+		"/*!50001 SET @saved_cs_client = @@character_set_client */;\n" +
+		"/*!50001 SET @saved_cs_results = @@character_set_results */;\n" +
+		"/*!50001 SET @saved_col_connection = @@collation_connection */;\n" +
+		"/*!50001 SET character_set_client = utf8mb4 */;\n" +
+		"/*!50001 SET character_set_results = utf8mb4 */;\n" +
+		"/*!50001 SET collation_connection = utf8mb4_0900_ai_ci */;\n" +
+		"CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `user_view` AS select `users`.`id` AS `id` from `users`;\n" +
+		"/*!50001 SET character_set_client = @saved_cs_client */;\n" +
+		"/*!50001 SET character_set_results = @saved_cs_results */;\n" +
+		"/*!50001 SET collation_connection = @saved_col_connection */;\n\n"
+
+	assert.Equal(expected, b.String())
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestWriteTriggers(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+
+	const listQuery = "SELECT TRIGGER_NAME FROM information_schema.TRIGGERS WHERE EVENT_OBJECT_SCHEMA = ? AND EVENT_OBJECT_TABLE = ? ORDER BY ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORDER"
+
+	mock.ExpectQuery(listQuery).
+		WithArgs("dump_test", "users").
+		WillReturnRows(mock.NewRows([]string{"TRIGGER_NAME"}).AddRow("before_insert"))
+
+	mock.ExpectQuery("SHOW CREATE TRIGGER `dump_test`.`before_insert`").
+		WillReturnRows(mock.NewRows([]string{"Trigger", "sql_mode", "SQL Original Statement", "character_set_client", "collation_connection", "Database Collation", "Created"}).
+			AddRow("before_insert", "STRICT_TRANS_TABLES", "CREATE DEFINER=`root`@`%` TRIGGER `before_insert` BEFORE INSERT ON `users` FOR EACH ROW BEGIN SET NEW.name = UPPER(NEW.name); END", "utf8mb4", "utf8mb4_0900_ai_ci", "utf8mb4_0900_ai_ci", "2024-08-09 00:00:00.00"))
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+
+	err := mysql.writeTriggers(buf, "users")
+	assert.Nil(err)
+	assert.Nil(buf.Flush())
+
+	expected := "/*!50032 DROP TRIGGER IF EXISTS `before_insert` */;\n" +
+		"/*!50003 SET @saved_cs_client = @@character_set_client */ ;\n" +
+		"/*!50003 SET @saved_cs_results = @@character_set_results */ ;\n" +
+		"/*!50003 SET @saved_col_connection = @@collation_connection */ ;\n" +
+		"/*!50003 SET character_set_client = utf8mb4 */ ;\n" +
+		"/*!50003 SET character_set_results = utf8mb4 */ ;\n" +
+		"/*!50003 SET collation_connection = utf8mb4_0900_ai_ci */ ;\n" +
+		"/*!50003 SET @saved_sql_mode = @@sql_mode */ ;\n" +
+		"/*!50003 SET sql_mode = 'STRICT_TRANS_TABLES' */ ;\n" +
+		"DELIMITER ;;\n" +
+		"CREATE DEFINER=`root`@`%` TRIGGER `before_insert` BEFORE INSERT ON `users` FOR EACH ROW BEGIN SET NEW.name = UPPER(NEW.name); END ;;\n" +
+		"DELIMITER ;\n" +
+		"/*!50003 SET sql_mode = @saved_sql_mode */ ;\n" +
+		"/*!50003 SET character_set_client = @saved_cs_client */ ;\n" +
+		"/*!50003 SET character_set_results = @saved_cs_results */ ;\n" +
+		"/*!50003 SET collation_connection = @saved_col_connection */ ;\n\n"
+
+	assert.Equal(expected, b.String())
+
+	mock.ExpectQuery(listQuery).
+		WithArgs("dump_test", "users").
+		WillReturnRows(mock.NewRows([]string{"TRIGGER_NAME"}).AddRow("before_insert"))
+
+	mock.ExpectQuery("SHOW CREATE TRIGGER `dump_test`.`before_insert`").
+		WillReturnRows(mock.NewRows([]string{"Trigger", "sql_mode", "SQL Original Statement", "character_set_client", "collation_connection", "Database Collation", "Created"}).
+			AddRow("before_insert", "", nil, "utf8mb4", "utf8mb4_0900_ai_ci", "utf8mb4_0900_ai_ci", nil))
+
+	err = mysql.writeTriggers(buf, "users")
+	assert.EqualError(err, "SQL Original Statement is empty from query SHOW CREATE TRIGGER `dump_test`.`before_insert`, check if the user has enough privileges")
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestWriteRoutines(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+
+	mock.ExpectQuery("SELECT ROUTINE_NAME, ROUTINE_TYPE FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ? ORDER BY ROUTINE_TYPE, ROUTINE_NAME").
+		WithArgs("dump_test").
+		WillReturnRows(mock.NewRows([]string{"ROUTINE_NAME", "ROUTINE_TYPE"}).AddRow("add_one", "FUNCTION").AddRow("cleanup", "PROCEDURE"))
+
+	mock.ExpectQuery("SHOW CREATE FUNCTION `dump_test`.`add_one`").
+		WillReturnRows(mock.NewRows([]string{"Function", "sql_mode", "Create Function", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow("add_one", "", "CREATE FUNCTION `add_one`(i INT) RETURNS int DETERMINISTIC RETURN i + 1", "utf8mb4", "utf8mb4_0900_ai_ci", "utf8mb4_0900_ai_ci"))
+
+	mock.ExpectQuery("SHOW CREATE PROCEDURE `dump_test`.`cleanup`").
+		WillReturnRows(mock.NewRows([]string{"Procedure", "sql_mode", "Create Procedure", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow("cleanup", "", "CREATE PROCEDURE `cleanup`() BEGIN DELETE FROM users; END", "utf8mb4", "utf8mb4_0900_ai_ci", "utf8mb4_0900_ai_ci"))
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+
+	err := mysql.writeRoutines(buf)
+	assert.Nil(err)
+	assert.Nil(buf.Flush())
+
+	output := b.String()
+	assert.Contains(output, "/*!50003 DROP FUNCTION IF EXISTS `add_one` */;\n")
+	assert.Contains(output, "CREATE FUNCTION `add_one`(i INT) RETURNS int DETERMINISTIC RETURN i + 1 ;;\n")
+	assert.Contains(output, "/*!50003 DROP PROCEDURE IF EXISTS `cleanup` */;\n")
+	assert.Contains(output, "CREATE PROCEDURE `cleanup`() BEGIN DELETE FROM users; END ;;\n")
+	assert.Less(bytes.Index(b.Bytes(), []byte("add_one")), bytes.Index(b.Bytes(), []byte("cleanup")))
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestWriteEvents(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+
+	mock.ExpectQuery("SELECT EVENT_NAME FROM information_schema.EVENTS WHERE EVENT_SCHEMA = ? ORDER BY EVENT_NAME").
+		WithArgs("dump_test").
+		WillReturnRows(mock.NewRows([]string{"EVENT_NAME"}).AddRow("purge_sessions"))
+
+	mock.ExpectQuery("SHOW CREATE EVENT `dump_test`.`purge_sessions`").
+		WillReturnRows(mock.NewRows([]string{"Event", "sql_mode", "time_zone", "Create Event", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow("purge_sessions", "", "SYSTEM", "CREATE EVENT `purge_sessions` ON SCHEDULE EVERY 1 DAY DO DELETE FROM sessions", "utf8mb4", "utf8mb4_0900_ai_ci", "utf8mb4_0900_ai_ci"))
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+
+	err := mysql.writeEvents(buf)
+	assert.Nil(err)
+	assert.Nil(buf.Flush())
+
+	output := b.String()
+	assert.Contains(output, "/*!50106 DROP EVENT IF EXISTS `purge_sessions` */;\n")
+	assert.Contains(output, "/*!50106 SET time_zone = 'SYSTEM' */ ;\nDELIMITER ;;\n")
+	assert.Contains(output, "CREATE EVENT `purge_sessions` ON SCHEDULE EVERY 1 DAY DO DELETE FROM sessions ;;\nDELIMITER ;\n/*!50106 SET time_zone = @saved_time_zone */ ;\n")
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestIsTriggersEnabled(t *testing.T) {
+	assert, _, _ := initTest(t)
+
+	mysql, err := NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn))
+	assert.Nil(err)
+	assert.True(mysql.isTriggersEnabled())
+
+	mysql, err = NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--skip-triggers")))
+	assert.Nil(err)
+	assert.False(mysql.isTriggersEnabled())
+}
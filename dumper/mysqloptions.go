@@ -0,0 +1,70 @@
+package dumper
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+const (
+	noData             = "--no-data"        // dump table structures only
+	noCreateInfo       = "--no-create-info" // dump table contents only
+	insertIgnore       = "--insert-ignore"
+	replace            = "--replace"
+	completeInsert     = "--complete-insert" // enabled by default, unlike mysqldump
+	skipCompleteInsert = "--skip-complete-insert"
+)
+
+// Options supported by the native MySQL dumper.
+var mysqlNativeOptions = []string{
+	skipAddDropTable, skipAddLocks, netBufferLength, rowsPerInsert,
+	singleTransaction, sourceData, masterData,
+	routines, events, triggers, skipTriggers,
+	parallel, chunkSize, databases, allDatabases,
+	noData, noCreateInfo, insertIgnore, replace, completeInsert, skipCompleteInsert,
+}
+
+// mysqldump options that are accepted for compatibility, the native dumper always behaves like them.
+var mysqlNativeNoopOptions = []string{
+	"--opt", "--quick", "-q", "--extended-insert", "-e", "--add-drop-table", "--add-locks", "--disable-keys", "--set-charset", "--hex-blob",
+}
+
+// Fail on options that are not supported, so a job does not silently dump something else than what it asks for.
+func validateMysqlNativeOptions(options Options) error {
+	for _, option := range slices.Sorted(maps.Keys(options)) {
+		if !slices.Contains(mysqlNativeOptions, option) && !slices.Contains(mysqlNativeNoopOptions, option) {
+			return fmt.Errorf("%s option is not supported by the native MySQL dumper, use the mysqldump driver instead", option)
+		}
+	}
+
+	conflicts := [][2]string{
+		{insertIgnore, replace},
+		{completeInsert, skipCompleteInsert},
+		{triggers, skipTriggers},
+	}
+
+	for _, conflict := range conflicts {
+		if options.isEnabled(conflict[0]) && options.isEnabled(conflict[1]) {
+			return fmt.Errorf("%s and %s options can not be used together", conflict[0], conflict[1])
+		}
+	}
+
+	return nil
+}
+
+// The statement used to insert rows, e.g. INSERT IGNORE with --insert-ignore.
+func (m *MysqlNativeDump) insertStatement() string {
+	switch {
+	case m.options.isEnabled(replace):
+		return "REPLACE"
+	case m.options.isEnabled(insertIgnore):
+		return "INSERT IGNORE"
+	default:
+		return "INSERT"
+	}
+}
+
+// Check if the INSERT statements list the column names.
+func (m *MysqlNativeDump) isCompleteInsert() bool {
+	return !m.options.isEnabled(skipCompleteInsert)
+}
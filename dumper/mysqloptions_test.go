@@ -0,0 +1,121 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/config"
+)
+
+func TestValidateMysqlNativeOptions(t *testing.T) {
+	assert, _, _ := initTest(t)
+
+	tests := []struct {
+		options []string
+		err     string
+	}{
+		{[]string{"--skip-add-locks", "--net-buffer-length=1024", "--no-data", "--replace", "--quick", "--triggers"}, ""},
+		{[]string{"--opt", "-q", "--extended-insert", "--hex-blob"}, ""},
+		{[]string{"--no-create-info", "--insert-ignore", "--skip-complete-insert"}, ""},
+		{[]string{"--skip-comments"}, "--skip-comments option is not supported by the native MySQL dumper, use the mysqldump driver instead"},
+		{[]string{"--where=id > 1", "--compact"}, "--compact option is not supported by the native MySQL dumper, use the mysqldump driver instead"},
+		{[]string{"--insert-ignore", "--replace"}, "--insert-ignore and --replace options can not be used together"},
+		{[]string{"--complete-insert", "--skip-complete-insert"}, "--complete-insert and --skip-complete-insert options can not be used together"},
+		{[]string{"--triggers", "--skip-triggers"}, "--triggers and --skip-triggers options can not be used together"},
+	}
+
+	for _, tt := range tests {
+		err := validateMysqlNativeOptions(newOptions(tt.options...))
+		if tt.err == "" {
+			assert.Nil(err, tt.options)
+		} else {
+			assert.EqualError(err, tt.err, tt.options)
+		}
+	}
+
+	_, err := NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--lock-all-tables")))
+	assert.EqualError(err, "--lock-all-tables option is not supported by the native MySQL dumper, use the mysqldump driver instead")
+}
+
+func TestInsertStatementOptions(t *testing.T) {
+	tests := []struct {
+		options []string
+		expect  string
+	}{
+		{nil, "INSERT INTO `onedump` (`id`) VALUES (1);\n"},
+		{[]string{"--complete-insert"}, "INSERT INTO `onedump` (`id`) VALUES (1);\n"},
+		{[]string{"--skip-complete-insert"}, "INSERT INTO `onedump` VALUES (1);\n"},
+		{[]string{"--insert-ignore"}, "INSERT IGNORE INTO `onedump` (`id`) VALUES (1);\n"},
+		{[]string{"--replace", "--skip-complete-insert"}, "REPLACE INTO `onedump` VALUES (1);\n"},
+	}
+
+	for _, tt := range tests {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+		mysql.options = newOptions(tt.options...)
+
+		expectTableContent(mock, "onedump", 1)
+
+		var b bytes.Buffer
+		buf := bufio.NewWriter(&b)
+		assert.Nil(mysql.writeTableContent(buf, "onedump"))
+		assert.Nil(buf.Flush())
+		assert.Contains(b.String(), tt.expect, tt.options)
+	}
+}
+
+func expectDumpDatabaseTables(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SHOW FULL TABLES FROM `dump_test`").WillReturnRows(
+		mock.NewRows([]string{"Tables_in_dump_test", "Table_type"}).
+			AddRow("onedump", "BASE TABLE").
+			AddRow("user_view", "VIEW"),
+	)
+}
+
+func TestDumpDatabaseNoData(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+	mysql.options = newOptions("--no-data", "--skip-triggers")
+
+	expectDumpDatabaseTables(mock)
+	mock.ExpectQuery("SHOW CREATE TABLE `dump_test`.`onedump`").WillReturnRows(
+		mock.NewRows([]string{"Table", "Create Table"}).AddRow("onedump", "CREATE TABLE `onedump` (`id` int)"),
+	)
+	mock.ExpectQuery("SHOW COLUMNS FROM `dump_test`.`user_view`").WillReturnRows(
+		mock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "int", "YES", "", nil, ""),
+	)
+	mock.ExpectQuery("SHOW CREATE VIEW `dump_test`.`user_view`").WillReturnRows(
+		mock.NewRows([]string{"View", "Create View", "character_set_client", "collation_connection"}).
+			AddRow("user_view", "CREATE VIEW `user_view` AS select `id` from `onedump`", "utf8mb4", "utf8mb4_general_ci"),
+	)
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+	assert.Nil(mysql.dumpDatabase(buf, []queryer{db}))
+	assert.Nil(buf.Flush())
+
+	assert.Contains(b.String(), "CREATE TABLE `onedump`")
+	assert.NotContains(b.String(), "INSERT INTO")
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestDumpDatabaseNoCreateInfo(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+	mysql.options = newOptions("--no-create-info", "--skip-triggers")
+
+	expectDumpDatabaseTables(mock)
+	expectTableContent(mock, "onedump", 1)
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+	assert.Nil(mysql.dumpDatabase(buf, []queryer{db}))
+	assert.Nil(buf.Flush())
+
+	assert.NotContains(b.String(), "CREATE")
+	assert.NotContains(b.String(), "DROP TABLE")
+	assert.Contains(b.String(), "INSERT INTO `onedump` (`id`) VALUES (1);\n")
+	assert.Nil(mock.ExpectationsWereMet())
+}
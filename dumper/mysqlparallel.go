@@ -0,0 +1,161 @@
+package dumper
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/liweiyi88/onedump/fileutil"
+)
+
+// The number of connections to dump table contents concurrently, 1 by default.
+const parallel = "--parallel"
+
+// Open extra connections for parallel dump workers, the dump connection itself works as the first worker.
+func openWorkerConns(db *sql.DB, n int) ([]*sql.Conn, error) {
+	conns := make([]*sql.Conn, 0, n)
+
+	for range n {
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			closeWorkerConns(conns)
+			return nil, fmt.Errorf("fail to get database connection for parallel dump, error: %v", err)
+		}
+
+		conns = append(conns, conn)
+	}
+
+	return conns, nil
+}
+
+func closeWorkerConns(conns []*sql.Conn) {
+	for _, conn := range conns {
+		if err := conn.Close(); err != nil {
+			slog.Error("failed to close db connection", slog.Any("error", err))
+		}
+	}
+}
+
+type tableContentResult struct {
+	file *os.File
+	err  error
+}
+
+// Dump table contents concurrently, one table per worker at a time. Each table is written to a temp file,
+// then copied to the buffer in table order, so the output is identical to a serial dump.
+func (m *MysqlNativeDump) writeTableContentsParallel(buf *bufio.Writer, tables []string, workers []queryer) error {
+	results := make([]chan tableContentResult, len(tables))
+	for i := range results {
+		results[i] = make(chan tableContentResult, 1)
+	}
+
+	jobs := make(chan int)
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+
+	for _, db := range workers {
+		worker := *m
+		worker.db = db
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				file, err := worker.writeTableContentToFile(tables[i])
+				results[i] <- tableContentResult{file: file, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+
+		for i := range tables {
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var err error
+	for i, table := range tables {
+		result := <-results[i]
+		if result.err != nil {
+			err = fmt.Errorf("failed to write table content, table: %s, error: %v", table, result.err)
+			break
+		}
+
+		err = copyTableContent(buf, result.file)
+		removeTempFile(result.file)
+
+		if err != nil {
+			err = fmt.Errorf("failed to copy table content, table: %s, error: %v", table, err)
+			break
+		}
+	}
+
+	close(done)
+	wg.Wait()
+
+	// Clean up the tables finished by other workers after a failure.
+	for _, result := range results {
+		select {
+		case r := <-result:
+			if r.file != nil {
+				removeTempFile(r.file)
+			}
+		default:
+		}
+	}
+
+	return err
+}
+
+func (m *MysqlNativeDump) writeTableContentToFile(table string) (*os.File, error) {
+	file, err := os.CreateTemp(fileutil.WorkDir(), ".onedump-table-*")
+	if err != nil {
+		return nil, fmt.Errorf("fail to create temp file, error: %v", err)
+	}
+
+	buf := bufio.NewWriter(file)
+
+	if err := m.writeTableContent(buf, table); err != nil {
+		removeTempFile(file)
+		return nil, err
+	}
+
+	if err := buf.Flush(); err != nil {
+		removeTempFile(file)
+		return nil, fmt.Errorf("fail to write temp file %s, error: %v", file.Name(), err)
+	}
+
+	return file, nil
+}
+
+func copyTableContent(buf *bufio.Writer, file *os.File) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(buf, file)
+	return err
+}
+
+func removeTempFile(file *os.File) {
+	if err := file.Close(); err != nil {
+		slog.Error("fail to close temp file", slog.Any("error", err), slog.String("filename", file.Name()))
+	}
+
+	if err := os.Remove(file.Name()); err != nil {
+		slog.Error("fail to remove temp file", slog.Any("error", err), slog.String("filename", file.Name()))
+	}
+}
@@ -0,0 +1,101 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/config"
+	"github.com/liweiyi88/onedump/fileutil"
+)
+
+func TestNewMysqlNativeDumpParallel(t *testing.T) {
+	assert, _, _ := initTest(t)
+
+	mysql, err := NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn))
+	assert.Nil(err)
+	assert.Equal(1, mysql.parallel)
+	assert.False(mysql.isSnapshotEnabled())
+
+	// The workers must share one consistent snapshot, so --parallel implies --single-transaction.
+	mysql, err = NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--parallel=4")))
+	assert.Nil(err)
+	assert.Equal(4, mysql.parallel)
+	assert.True(mysql.isSnapshotEnabled())
+
+	_, err = NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--parallel=0")))
+	assert.EqualError(err, "--parallel should be greater than 0, got 0")
+}
+
+func expectTableContent(mock sqlmock.Sqlmock, table string, values ...int) {
+	rows := mock.NewRowsWithColumnDefinition(sqlmock.NewColumn("id").OfType("INT", 1))
+	for _, value := range values {
+		rows.AddRow(value)
+	}
+
+	mock.ExpectQuery("SELECT * FROM `dump_test`.`" + table + "`;").WillReturnRows(rows)
+}
+
+func tempTableFiles(t *testing.T) []string {
+	files, err := filepath.Glob(filepath.Join(fileutil.WorkDir(), ".onedump-table-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return files
+}
+
+func TestWriteTableContentsParallel(t *testing.T) {
+	t.Run("it should write table contents in table order", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mock.MatchExpectationsInOrder(false)
+		mysql := createTestMysqlNativeDump(db)
+
+		tables := []string{"a", "b", "c", "d", "e"}
+		for i, table := range tables {
+			expectTableContent(mock, table, i+1)
+		}
+
+		var serial bytes.Buffer
+		serialBuf := bufio.NewWriter(&serial)
+		for _, table := range tables {
+			assert.Nil(mysql.writeTableContent(serialBuf, table))
+		}
+		assert.Nil(serialBuf.Flush())
+
+		for i, table := range tables {
+			expectTableContent(mock, table, i+1)
+		}
+
+		var b bytes.Buffer
+		buf := bufio.NewWriter(&b)
+
+		err := mysql.writeTableContentsParallel(buf, tables, []queryer{db, db, db})
+		assert.Nil(err)
+		assert.Nil(buf.Flush())
+
+		assert.Equal(serial.String(), b.String())
+		assert.Nil(mock.ExpectationsWereMet())
+		assert.Empty(tempTableFiles(t))
+	})
+
+	t.Run("it should return the error of a failed table and clean up temp files", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mock.MatchExpectationsInOrder(false)
+		mysql := createTestMysqlNativeDump(db)
+
+		expectTableContent(mock, "a", 1)
+		mock.ExpectQuery("SELECT * FROM `dump_test`.`b`;").WillReturnError(errors.New("db error"))
+		expectTableContent(mock, "c", 3)
+
+		var b bytes.Buffer
+		buf := bufio.NewWriter(&b)
+
+		err := mysql.writeTableContentsParallel(buf, []string{"a", "b", "c"}, []queryer{db, db})
+		assert.ErrorContains(err, "failed to write table content, table: b")
+		assert.Empty(tempTableFiles(t))
+	})
+}
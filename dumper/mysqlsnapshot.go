@@ -0,0 +1,214 @@
+package dumper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/liweiyi88/onedump/binlog"
+)
+
+const (
+	singleTransaction = "--single-transaction"
+	sourceData        = "--source-data"
+	masterData        = "--master-data" // deprecated alias of --source-data since MySQL 8.0.26
+)
+
+const (
+	flushTablesQuery        = "FLUSH /*!40101 LOCAL */ TABLES"
+	flushTablesLockQuery    = "FLUSH TABLES WITH READ LOCK"
+	unlockTablesQuery       = "UNLOCK TABLES"
+	repeatableReadQuery     = "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"
+	consistentSnapshotQuery = "START TRANSACTION /*!40100 WITH CONSISTENT SNAPSHOT */"
+)
+
+var ErrBinlogDisabled = errors.New("binary logging is not enabled on the server")
+
+// The binlog coordinates of a consistent snapshot.
+type binlogCoordinates struct {
+	version      string // MySQL server version
+	file         string // e.g. binlog.000001
+	position     uint64
+	gtidExecuted string
+}
+
+// Parse the value of --source-data (or --master-data).
+// Like mysqldump, 1 writes a CHANGE REPLICATION SOURCE TO statement and 2 writes it as a comment.
+func parseSourceData(options Options) (string, error) {
+	value, ok := options[sourceData]
+	if !ok {
+		value, ok = options[masterData]
+	}
+
+	if !ok {
+		return "", nil
+	}
+
+	switch value {
+	case "":
+		return "1", nil
+	case "1", "2":
+		return value, nil
+	default:
+		return "", fmt.Errorf("invalid value of %s option: %q, expect 1 or 2", sourceData, value)
+	}
+}
+
+// --source-data, --parallel and the table stats imply --single-transaction,
+// the binlog coordinates, all the workers and the stats must match one snapshot.
+func (m *MysqlNativeDump) isSnapshotEnabled() bool {
+	return m.options.isEnabled(singleTransaction) || m.sourceData != "" || m.parallel > 1 || m.stats != nil
+}
+
+// Start a transaction with consistent snapshot on the dump connection and the worker connections of parallel dumps.
+// It briefly holds a global read lock, so the binlog coordinates and all snapshots match exactly.
+func (m *MysqlNativeDump) startSnapshot(workers ...queryer) (*binlogCoordinates, error) {
+	ctx := context.Background()
+
+	for _, query := range []string{flushTablesQuery, flushTablesLockQuery} {
+		if _, err := m.db.ExecContext(ctx, query); err != nil {
+			return nil, fmt.Errorf("fail to run query %s, error: %v", query, err)
+		}
+	}
+
+	locked := true
+
+	defer func() {
+		if !locked {
+			return
+		}
+
+		if _, err := m.db.ExecContext(ctx, unlockTablesQuery); err != nil {
+			slog.Error("fail to unlock tables", slog.Any("error", err))
+		}
+	}()
+
+	for _, db := range append([]queryer{m.db}, workers...) {
+		for _, query := range []string{repeatableReadQuery, consistentSnapshotQuery} {
+			if _, err := db.ExecContext(ctx, query); err != nil {
+				return nil, fmt.Errorf("fail to run query %s, error: %v", query, err)
+			}
+		}
+	}
+
+	// The binlog coordinates are only required by --source-data, otherwise the dump goes on without them.
+	coordinates, err := m.getBinlogCoordinates()
+	if err != nil {
+		if m.sourceData != "" {
+			return nil, err
+		}
+
+		if errors.Is(err, ErrBinlogDisabled) {
+			slog.Debug("binary logging is disabled, skip writing binlog coordinates")
+		} else {
+			slog.Warn("fail to get binlog coordinates, skip writing them", slog.Any("error", err))
+		}
+	}
+
+	if _, err := m.db.ExecContext(ctx, unlockTablesQuery); err != nil {
+		return nil, fmt.Errorf("fail to run query %s, error: %v", unlockTablesQuery, err)
+	}
+
+	locked = false
+	slog.Debug("consistent snapshot started", slog.Any("coordinates", coordinates))
+
+	return coordinates, nil
+}
+
+// Get the current binlog file, position and executed GTID set.
+func (m *MysqlNativeDump) getBinlogCoordinates() (*binlogCoordinates, error) {
+	ctx := context.Background()
+
+	var version string
+	if err := m.db.QueryRowContext(ctx, binlog.VersionQuery).Scan(&version); err != nil {
+		return nil, fmt.Errorf("fail to query MySQL version, error: %v", err)
+	}
+
+	query := binlog.BinlogStatusQuery(version)
+
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("fail to run query %s, error: %v", query, err)
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("fail to close database rows", slog.Any("error", err), slog.Any("query", query))
+		}
+	}()
+
+	// MariaDB does not have the Executed_Gtid_Set column, so scan columns by their names.
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("fail to get columns of query %s, error: %v", query, err)
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("fail to read rows of query %s, error: %v", query, err)
+		}
+
+		return nil, ErrBinlogDisabled
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("fail to scan database rows, query: %s, error: %v", query, err)
+	}
+
+	coordinates := &binlogCoordinates{version: version}
+
+	for i, column := range columns {
+		switch column {
+		case "File":
+			coordinates.file = values[i].String
+		case "Position":
+			position, err := strconv.ParseUint(values[i].String, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid binlog position: %s, error: %v", values[i].String, err)
+			}
+
+			coordinates.position = position
+		case "Executed_Gtid_Set":
+			// Multiple GTID sets are separated by a comma and a new line.
+			coordinates.gtidExecuted = strings.ReplaceAll(values[i].String, "\n", "")
+		}
+	}
+
+	return coordinates, nil
+}
+
+// Write the binlog coordinates in the same format as mysqldump --source-data,
+// so binlog.ParseBinlogFilePosition can find the point-in-time recovery start position.
+func (m *MysqlNativeDump) binlogCoordinatesComment(coordinates *binlogCoordinates) string {
+	var sb strings.Builder
+
+	sb.WriteString("--\n")
+	sb.WriteString("-- Position to start replication or point-in-time recovery from\n")
+	sb.WriteString("--\n\n")
+
+	if m.sourceData != "1" {
+		sb.WriteString("-- ")
+	}
+
+	sb.WriteString(binlog.ChangeReplicationSourceStatement(coordinates.version, coordinates.file, coordinates.position))
+	sb.WriteString("\n\n")
+
+	if coordinates.gtidExecuted != "" {
+		sb.WriteString("--\n")
+		sb.WriteString("-- GTID state at the beginning of the backup\n")
+		sb.WriteString("--\n\n")
+		sb.WriteString("-- SET @@GLOBAL.GTID_PURGED='" + coordinates.gtidExecuted + "';\n\n")
+	}
+
+	return sb.String()
+}
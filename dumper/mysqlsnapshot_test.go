@@ -0,0 +1,205 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/binlog"
+	"github.com/liweiyi88/onedump/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSourceData(t *testing.T) {
+	tests := []struct {
+		options []string
+		want    string
+		wantErr bool
+	}{
+		{options: []string{}, want: ""},
+		{options: []string{"--source-data"}, want: "1"},
+		{options: []string{"--source-data=2"}, want: "2"},
+		{options: []string{"--master-data=1"}, want: "1"},
+		{options: []string{"--source-data=3"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.options, " "), func(t *testing.T) {
+			value, err := parseSourceData(newOptions(tt.options...))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, value)
+		})
+	}
+}
+
+func expectSnapshotQueries(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(flushTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(flushTablesLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(repeatableReadQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(consistentSnapshotQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(binlog.VersionQuery).WillReturnRows(sqlmock.NewRows([]string{"mysql_version"}).AddRow("8.4.5"))
+}
+
+func TestStartSnapshot(t *testing.T) {
+	t.Run("it should capture binlog coordinates while holding the read lock", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+
+		expectSnapshotQueries(mock)
+		rows := sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}).
+			AddRow("binlog.000003", 1638, "", "", "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5,\n4E11FA47-71CA-11E1-9E33-C80AA9429562:1-3")
+		mock.ExpectQuery(binlog.ShowBinlogStatusQuery).WillReturnRows(rows)
+		mock.ExpectExec(unlockTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		coordinates, err := mysql.startSnapshot()
+		assert.NoError(err)
+		assert.NoError(mock.ExpectationsWereMet())
+
+		assert.Equal("binlog.000003", coordinates.file)
+		assert.Equal(uint64(1638), coordinates.position)
+		assert.Equal("3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5,4E11FA47-71CA-11E1-9E33-C80AA9429562:1-3", coordinates.gtidExecuted)
+	})
+
+	t.Run("it should skip coordinates when binary logging is disabled", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+
+		expectSnapshotQueries(mock)
+		mock.ExpectQuery(binlog.ShowBinlogStatusQuery).WillReturnRows(sqlmock.NewRows([]string{"File", "Position"}))
+		mock.ExpectExec(unlockTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		coordinates, err := mysql.startSnapshot()
+		assert.NoError(err)
+		assert.Nil(coordinates)
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should read binlog coordinates of MariaDB with SHOW MASTER STATUS", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+
+		mock.ExpectExec(flushTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(flushTablesLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(repeatableReadQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(consistentSnapshotQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(binlog.VersionQuery).WillReturnRows(sqlmock.NewRows([]string{"mysql_version"}).AddRow("11.4.2-MariaDB-log"))
+		mock.ExpectQuery(binlog.ShowMasterStatusQuery).WillReturnRows(sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB"}).AddRow("mysql-bin.000002", 328, "", ""))
+		mock.ExpectExec(unlockTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		coordinates, err := mysql.startSnapshot()
+		assert.NoError(err)
+		assert.Equal("mysql-bin.000002", coordinates.file)
+		assert.Equal(uint64(328), coordinates.position)
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should skip coordinates when they can not be read without --source-data", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+
+		expectSnapshotQueries(mock)
+		mock.ExpectQuery(binlog.ShowBinlogStatusQuery).WillReturnError(errors.New("access denied"))
+		mock.ExpectExec(unlockTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		coordinates, err := mysql.startSnapshot()
+		assert.NoError(err)
+		assert.Nil(coordinates)
+		assert.NoError(mock.ExpectationsWereMet())
+
+		mysql.sourceData = "1"
+
+		expectSnapshotQueries(mock)
+		mock.ExpectQuery(binlog.ShowBinlogStatusQuery).WillReturnError(errors.New("access denied"))
+		mock.ExpectExec(unlockTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err = mysql.startSnapshot()
+		assert.ErrorContains(err, "access denied")
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return error when binary logging is disabled but --source-data is enabled", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+		mysql.sourceData = "2"
+
+		expectSnapshotQueries(mock)
+		mock.ExpectQuery(binlog.ShowBinlogStatusQuery).WillReturnRows(sqlmock.NewRows([]string{"File", "Position"}))
+		mock.ExpectExec(unlockTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := mysql.startSnapshot()
+		assert.ErrorIs(err, ErrBinlogDisabled)
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should start the snapshot on worker connections before unlocking tables", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+
+		_, workerDB, workerMock := initTest(t)
+
+		mock.ExpectExec(flushTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(flushTablesLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(repeatableReadQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(consistentSnapshotQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		workerMock.ExpectExec(repeatableReadQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		workerMock.ExpectExec(consistentSnapshotQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(binlog.VersionQuery).WillReturnRows(sqlmock.NewRows([]string{"mysql_version"}).AddRow("8.4.5"))
+		mock.ExpectQuery(binlog.ShowBinlogStatusQuery).WillReturnRows(sqlmock.NewRows([]string{"File", "Position"}).AddRow("binlog.000003", 1638))
+		mock.ExpectExec(unlockTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		coordinates, err := mysql.startSnapshot(workerDB)
+		assert.NoError(err)
+		assert.Equal("binlog.000003", coordinates.file)
+		assert.NoError(mock.ExpectationsWereMet())
+		assert.NoError(workerMock.ExpectationsWereMet())
+	})
+
+	t.Run("it should unlock tables if the snapshot can not be started", func(t *testing.T) {
+		assert, db, mock := initTest(t)
+		mysql := createTestMysqlNativeDump(db)
+
+		mock.ExpectExec(flushTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(flushTablesLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(repeatableReadQuery).WillReturnError(errors.New("db error"))
+		mock.ExpectExec(unlockTablesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := mysql.startSnapshot()
+		assert.Error(err)
+		assert.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestWriteHeaderWithBinlogCoordinates(t *testing.T) {
+	assert, db, mock := initTest(t)
+
+	job := config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--single-transaction"))
+	mysql, err := NewMysqlNativeDump(job)
+	assert.NoError(err)
+	mysql.db = db
+
+	rows := mock.NewRows([]string{"variableName", "characterSet"}).AddRow("chartset", "utf8")
+	mock.ExpectQuery("SHOW VARIABLES LIKE 'character_set_results'").WillReturnRows(rows)
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+
+	coordinates := &binlogCoordinates{version: "8.0.42", file: "binlog.000003", position: 1638, gtidExecuted: "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5"}
+	assert.NoError(mysql.writeHeader(buf, coordinates))
+	assert.NoError(buf.Flush())
+
+	assert.Contains(b.String(), "-- CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='binlog.000003', SOURCE_LOG_POS=1638;\n")
+	assert.Contains(b.String(), "-- SET @@GLOBAL.GTID_PURGED='3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5';\n")
+
+	file, pos, err := binlog.ParseBinlogFilePosition(strings.NewReader(b.String()))
+	assert.NoError(err)
+	assert.Equal("binlog.000003", file)
+	assert.Equal(1638, pos)
+}
@@ -0,0 +1,129 @@
+package dumper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+// Row count and checksum of a table, they are compared with the restored table to verify a backup.
+type TableStat struct {
+	Rows     int64
+	Checksum sql.NullInt64 // the result of CHECKSUM TABLE, it is not collected for tables dumped with a WHERE predicate
+}
+
+// Stats of the dumped tables by table name.
+type TableStats map[string]TableStat
+
+// Dumpers that capture table stats at dump time for backup verification.
+type StatsDumper interface {
+	Dumper
+	EnableTableStats(checksum bool) error
+	TableStats() TableStats
+}
+
+// Collect table stats from the parallel workers.
+type tableStatsCollector struct {
+	checksum bool
+	mu       sync.Mutex
+	stats    TableStats
+}
+
+// Capture the row count and the checksum of every dumped table. It implies --single-transaction,
+// so the checksums are calculated in the same snapshot as the dump and match it.
+func (m *MysqlNativeDump) EnableTableStats(checksum bool) error {
+	if m.isMultiDatabase() {
+		return fmt.Errorf("table stats are not supported with %s or %s option", databases, allDatabases)
+	}
+
+	m.stats = &tableStatsCollector{checksum: checksum, stats: make(TableStats)}
+
+	return nil
+}
+
+// Get the stats of the tables in the last dump, the tables dumped without data are not included.
+func (m *MysqlNativeDump) TableStats() TableStats {
+	if m.stats == nil {
+		return nil
+	}
+
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+
+	stats := make(TableStats, len(m.stats.stats))
+	for table, stat := range m.stats.stats {
+		stats[table] = stat
+	}
+
+	return stats
+}
+
+// Close the INSERT statements of the table and record its stats.
+func (m *MysqlNativeDump) closeTableContent(w *insertWriter) error {
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	if m.stats == nil {
+		return nil
+	}
+
+	stat := TableStat{Rows: int64(w.totalRows)}
+
+	if _, hasWhere := m.tables.Where[w.table]; m.stats.checksum && !hasWhere {
+		checksum, err := checksumTable(m.db, m.database, w.table)
+		if err != nil {
+			return err
+		}
+
+		stat.Checksum = checksum
+	}
+
+	m.stats.mu.Lock()
+	m.stats.stats[w.table] = stat
+	m.stats.mu.Unlock()
+
+	return nil
+}
+
+func checksumTable(db queryer, database, table string) (sql.NullInt64, error) {
+	var name string
+	var checksum sql.NullInt64
+
+	row := db.QueryRowContext(context.Background(), "CHECKSUM TABLE "+sqlutil.QuoteIdentifier(database)+"."+sqlutil.QuoteIdentifier(table))
+	if err := row.Scan(&name, &checksum); err != nil {
+		return checksum, fmt.Errorf("failed to checksum table: %s, error: %v", table, err)
+	}
+
+	return checksum, nil
+}
+
+// Collect the stats of the tables in a database, e.g. a database restored from a dump.
+func CollectMysqlTableStats(db *sql.DB, database string, tables []string, checksum bool) (TableStats, error) {
+	stats := make(TableStats, len(tables))
+
+	for _, table := range tables {
+		var stat TableStat
+
+		row := db.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM "+sqlutil.QuoteIdentifier(database)+"."+sqlutil.QuoteIdentifier(table))
+		if err := row.Scan(&stat.Rows); err != nil {
+			return nil, fmt.Errorf("failed to count rows of table: %s, error: %v", table, err)
+		}
+
+		if checksum {
+			value, err := checksumTable(db, database, table)
+			if err != nil {
+				return nil, err
+			}
+
+			stat.Checksum = value
+		}
+
+		stats[table] = stat
+	}
+
+	return stats, nil
+}
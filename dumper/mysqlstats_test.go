@@ -0,0 +1,67 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/liweiyi88/onedump/config"
+)
+
+func TestEnableTableStats(t *testing.T) {
+	assert, db, _ := initTest(t)
+
+	mysql := createTestMysqlNativeDump(db)
+	assert.Nil(mysql.TableStats())
+	assert.False(mysql.isSnapshotEnabled())
+	assert.Nil(mysql.EnableTableStats(true))
+	assert.Empty(mysql.TableStats())
+	assert.True(mysql.isSnapshotEnabled())
+
+	multiDatabase, err := NewMysqlNativeDump(config.NewJob("test", "mysql", testDBDsn, config.WithDumpOptions("--databases=db1,db2")))
+	assert.Nil(err)
+	assert.EqualError(multiDatabase.EnableTableStats(true), "table stats are not supported with --databases or --all-databases option")
+}
+
+func TestWriteTableContentStats(t *testing.T) {
+	assert, db, mock := initTest(t)
+
+	mysql := createTestMysqlNativeDump(db)
+	mysql.tables = config.TableFilter{Where: map[string]string{"events": "id > 1"}}
+	assert.Nil(mysql.EnableTableStats(true))
+
+	expectTableContent(mock, "onedump", 1, 2)
+	mock.ExpectQuery("CHECKSUM TABLE `dump_test`.`onedump`").WillReturnRows(
+		mock.NewRows([]string{"Table", "Checksum"}).AddRow("dump_test.onedump", 1234),
+	)
+
+	// The checksum of a filtered table does not match its dump.
+	mock.ExpectQuery("SELECT * FROM `dump_test`.`events` WHERE id > 1;").WillReturnRows(
+		mock.NewRowsWithColumnDefinition(sqlmock.NewColumn("id").OfType("INT", 1)).AddRow(2),
+	)
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+	assert.Nil(mysql.writeTableContent(buf, "onedump"))
+	assert.Nil(mysql.writeTableContent(buf, "events"))
+
+	assert.Equal(TableStats{
+		"onedump": {Rows: 2, Checksum: sql.NullInt64{Int64: 1234, Valid: true}},
+		"events":  {Rows: 1},
+	}, mysql.TableStats())
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestCollectMysqlTableStats(t *testing.T) {
+	assert, db, mock := initTest(t)
+
+	mock.ExpectQuery("SELECT COUNT(*) FROM `scratch`.`users`").WillReturnRows(mock.NewRows([]string{"COUNT(*)"}).AddRow(3))
+	mock.ExpectQuery("CHECKSUM TABLE `scratch`.`users`").WillReturnRows(mock.NewRows([]string{"Table", "Checksum"}).AddRow("scratch.users", 42))
+
+	stats, err := CollectMysqlTableStats(db, "scratch", []string{"users"}, true)
+	assert.Nil(err)
+	assert.Equal(TableStats{"users": {Rows: 3, Checksum: sql.NullInt64{Int64: 42, Valid: true}}}, stats)
+	assert.Nil(mock.ExpectationsWereMet())
+}
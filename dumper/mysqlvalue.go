@@ -0,0 +1,232 @@
+package dumper
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const noBackslashEscapesMode = "NO_BACKSLASH_ESCAPES"
+
+// Write the value of a single column to the string builder as a MySQL literal.
+// Binary data is hex encoded, so it survives any character set of the restore connection.
+func writeValue(sb *strings.Builder, value any, typeName string, noBackslashEscapes bool) error {
+	if value == nil {
+		sb.WriteString("NULL")
+		return nil
+	}
+
+	switch typeName {
+	case "TINYINT",
+		"SMALLINT",
+		"MEDIUMINT",
+		"INT",
+		"INTEGER",
+		"BIGINT",
+		"UNSIGNED TINYINT",
+		"UNSIGNED SMALLINT",
+		"UNSIGNED MEDIUMINT",
+		"UNSIGNED INT",
+		"UNSIGNED BIGINT",
+		"FLOAT",
+		"DOUBLE",
+		"DECIMAL",
+		"DEC":
+		return writeNumber(sb, value, typeName)
+	case "DATE":
+		v, ok := value.([]uint8)
+
+		if !ok {
+			return fmt.Errorf("could not parse DATE type, expect []uint8, got %T", value)
+		}
+
+		writeString(sb, string(v), noBackslashEscapes)
+	case "DATETIME":
+		v, ok := value.([]byte)
+		if !ok {
+			return fmt.Errorf("could not parse DATETIME type, expect []byte, got %T", value)
+		}
+
+		writeString(sb, string(v), noBackslashEscapes)
+	case "TIMESTAMP":
+		v, ok := value.([]byte)
+		if !ok {
+			return fmt.Errorf("could not parse TIMESTAMP type, expect []byte, got %T", value)
+		}
+
+		writeString(sb, string(v), noBackslashEscapes)
+	case "TIME":
+		v, ok := value.([]byte)
+		if !ok {
+			return fmt.Errorf("could not parse TIME type, expect []byte, got %T", value)
+		}
+
+		writeString(sb, string(v), noBackslashEscapes)
+	case "YEAR":
+		switch v := value.(type) {
+		case int64:
+			sb.WriteString(fmt.Sprintf("'%d'", v))
+		case []byte:
+			writeString(sb, string(v), noBackslashEscapes)
+		default:
+			return fmt.Errorf("could not parse YEAR type, expect int64, got %T", value)
+		}
+	case "CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT", "ENUM", "SET":
+		v, ok := toBytes(value)
+		if !ok {
+			return fmt.Errorf("could not parse %s type, expect []byte or string, got %T", typeName, value)
+		}
+
+		writeString(sb, string(v), noBackslashEscapes)
+	case "BINARY":
+		// Keep the trailing NUL bytes, they are part of the value.
+		v, ok := value.([]uint8)
+		if !ok {
+			return fmt.Errorf("could not parse BINARY type, expect []uint8, got %T", value)
+		}
+
+		writeHex(sb, v)
+	case "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "GEOMETRY", "VECTOR":
+		// Spatial values are in the MySQL internal format (SRID + WKB), which can be inserted back as is.
+		// VECTOR values are float32 arrays in little endian.
+		v, ok := toBytes(value)
+		if !ok {
+			return fmt.Errorf("could not parse %s type, expect []byte, got %T", typeName, value)
+		}
+
+		writeHex(sb, v)
+	case "BIT":
+		v, ok := value.([]uint8)
+		if !ok {
+			return fmt.Errorf("cloud not parse BIT type, expect []uint8, got %T", value)
+		}
+
+		// BIT(M) is returned in big endian with (M+7)/8 bytes.
+		if len(v) == 0 || len(v) > 8 {
+			return fmt.Errorf("failed to parse BIT type, expected length between 1 and 8, got %d", len(v))
+		}
+
+		padded := make([]byte, 8)
+		copy(padded[8-len(v):], v)
+
+		sb.WriteString("b'" + strconv.FormatUint(binary.BigEndian.Uint64(padded), 2) + "'")
+	case "BOOL", "BOOLEAN":
+		if value.(bool) {
+			sb.WriteString("true")
+		} else {
+			sb.WriteString("false")
+		}
+	case "JSON":
+		v, ok := value.([]uint8)
+		if !ok {
+			return fmt.Errorf("cloud not parse JSON type, expect []unint8, got %T", value)
+		}
+
+		writeString(sb, string(v), noBackslashEscapes)
+	default:
+		return fmt.Errorf("unsupported database type: %s", typeName)
+	}
+
+	return nil
+}
+
+// Numbers are returned as []byte by the text protocol, or as Go numbers since go-sql-driver/mysql v1.8.
+func writeNumber(sb *strings.Builder, value any, typeName string) error {
+	switch v := value.(type) {
+	case []byte:
+		sb.Write(v)
+	case string:
+		sb.WriteString(v)
+	case int64:
+		sb.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		sb.WriteString(strconv.FormatUint(v, 10))
+	case int, int8, int16, int32, uint, uint8, uint16, uint32:
+		sb.WriteString(fmt.Sprintf("%d", v))
+	case float32:
+		// The shortest representation that reads back as the same float32.
+		sb.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	case float64:
+		sb.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	default:
+		return fmt.Errorf("could not parse %s type, expect a number, got %T", typeName, value)
+	}
+
+	return nil
+}
+
+func toBytes(value any) ([]byte, bool) {
+	switch v := value.(type) {
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	default:
+		return nil, false
+	}
+}
+
+// Write a quoted string literal. All the escaped characters are ASCII, so escaping byte by byte is safe for UTF-8.
+// When the server has NO_BACKSLASH_ESCAPES enabled, backslash is an ordinary character and only single quotes are escaped.
+func writeString(sb *strings.Builder, s string, noBackslashEscapes bool) {
+	sb.WriteByte('\'')
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if noBackslashEscapes {
+			if c == '\'' {
+				sb.WriteString("''")
+			} else {
+				sb.WriteByte(c)
+			}
+
+			continue
+		}
+
+		switch c {
+		case 0:
+			sb.WriteString(`\0`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\'':
+			sb.WriteString(`\'`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\032':
+			sb.WriteString(`\Z`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	sb.WriteByte('\'')
+}
+
+// Write binary data as a hex literal, e.g. 0x0A1B.
+func writeHex(sb *strings.Builder, v []byte) {
+	if len(v) == 0 {
+		sb.WriteString("''")
+		return
+	}
+
+	sb.WriteString("0x")
+	sb.WriteString(hex.EncodeToString(v))
+}
+
+// Check if the sql_mode contains NO_BACKSLASH_ESCAPES.
+func hasNoBackslashEscapes(sqlMode string) bool {
+	for mode := range strings.SplitSeq(sqlMode, ",") {
+		if strings.EqualFold(strings.TrimSpace(mode), noBackslashEscapesMode) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,271 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+func TestWriteValue(t *testing.T) {
+	tests := []struct {
+		name               string
+		value              any
+		typeName           string
+		noBackslashEscapes bool
+		want               string
+	}{
+		{"null", nil, "VARCHAR", false, "NULL"},
+		{"int bytes", []byte("-42"), "INT", false, "-42"},
+		{"int64", int64(-42), "BIGINT", false, "-42"},
+		{"max unsigned bigint", uint64(18446744073709551615), "UNSIGNED BIGINT", false, "18446744073709551615"},
+		{"float32", float32(0.1), "FLOAT", false, "0.1"},
+		{"float64", 0.1, "DOUBLE", false, "0.1"},
+		{"large float64", 1.7976931348623157e+308, "DOUBLE", false, "1.7976931348623157e+308"},
+		{"decimal", []byte("12345678901234567890.123456789"), "DECIMAL", false, "12345678901234567890.123456789"},
+		{"date", []byte("2024-08-09"), "DATE", false, "'2024-08-09'"},
+		{"year", int64(2024), "YEAR", false, "'2024'"},
+		{"year bytes", []byte("2024"), "YEAR", false, "'2024'"},
+		{"escaped text", []byte("a'b\"c\\d\x00e\nf\rg\x1ah"), "TEXT", false, `'a\'b\"c\\d\0e\nf\rg\Zh'`},
+		{"escaped text with no backslash escapes", []byte("a'b\"c\\d\x00e\nf"), "TEXT", true, "'a''b\"c\\d\x00e\nf'"},
+		{"utf8 text", "日本語 🐬", "VARCHAR", false, "'日本語 🐬'"},
+		{"enum", []byte("it's"), "ENUM", false, `'it\'s'`},
+		{"binary with trailing NUL", []byte{0x61, 0x00, 0x00}, "BINARY", false, "0x610000"},
+		{"varbinary", []byte{0x00, 0x27, 0x5c, 0xff}, "VARBINARY", false, "0x00275cff"},
+		{"empty blob", []byte{}, "BLOB", false, "''"},
+		{"geometry", []byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00}, "GEOMETRY", false, "0x000000000101000000"},
+		{"vector", []byte{0x00, 0x00, 0x80, 0x3f}, "VECTOR", false, "0x0000803f"},
+		{"bit(1)", []byte{1}, "BIT", false, "b'1'"},
+		{"bit(10)", []byte{0x02, 0x01}, "BIT", false, "b'1000000001'"},
+		{"bit(64)", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "BIT", false, "b'" + strings.Repeat("1", 64) + "'"},
+		{"bit zero", []byte{0x00}, "BIT", false, "b'0'"},
+		{"json", []byte(`{"a": "line\nbreak \"quoted\""}`), "JSON", false, `'{\"a\": \"line\\nbreak \\\"quoted\\\"\"}'`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := writeValue(&sb, test.value, test.typeName, test.noBackslashEscapes)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, sb.String())
+		})
+	}
+}
+
+func TestWriteValueErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	var sb strings.Builder
+	assert.EqualError(writeValue(&sb, []byte{}, "BIT", false), "failed to parse BIT type, expected length between 1 and 8, got 0")
+	assert.EqualError(writeValue(&sb, 1, "BLOB", false), "could not parse BLOB type, expect []byte, got int")
+	assert.EqualError(writeValue(&sb, 1, "TEXT", false), "could not parse TEXT type, expect []byte or string, got int")
+	assert.EqualError(writeValue(&sb, true, "INT", false), "could not parse INT type, expect a number, got bool")
+	assert.EqualError(writeValue(&sb, []byte{}, "UNKNOWN", false), "unsupported database type: UNKNOWN")
+}
+
+func TestHasNoBackslashEscapes(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(hasNoBackslashEscapes(""))
+	assert.False(hasNoBackslashEscapes("STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION"))
+	assert.True(hasNoBackslashEscapes("STRICT_TRANS_TABLES,NO_BACKSLASH_ESCAPES"))
+	assert.True(hasNoBackslashEscapes("no_backslash_escapes"))
+}
+
+func TestWriteHeaderWithNoBackslashEscapes(t *testing.T) {
+	assert, db, mock := initTest(t)
+	mysql := createTestMysqlNativeDump(db)
+	mysql.noBackslashEscapes = true
+
+	rows := mock.NewRows([]string{"Variable_name", "Value"}).AddRow("character_set_results", "utf8mb4")
+	mock.ExpectQuery("SHOW VARIABLES LIKE 'character_set_results'").WillReturnRows(rows)
+
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+
+	assert.Nil(mysql.writeHeader(buf, nil))
+	assert.Nil(buf.Flush())
+	assert.Contains(b.String(), "SQL_MODE='NO_AUTO_VALUE_ON_ZERO,NO_BACKSLASH_ESCAPES'")
+}
+
+// The round trip tests need a real MySQL server, e.g.
+// ONEDUMP_TEST_MYSQL_DSN="root:secret@tcp(127.0.0.1:3306)/dump_test" go test ./dumper -run RoundTrip
+func TestValueRoundTrip(t *testing.T) {
+	dsn := os.Getenv("ONEDUMP_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("ONEDUMP_TEST_MYSQL_DSN is not set")
+	}
+
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.MultiStatements = true
+
+	db, err := sql.Open("mysql", config.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	var version string
+	if err := db.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+
+	columns := []string{
+		"`id` INT NOT NULL PRIMARY KEY",
+		"`c_tinyint` TINYINT", "`c_utinyint` TINYINT UNSIGNED", "`c_bigint` BIGINT", "`c_ubigint` BIGINT UNSIGNED",
+		"`c_float` FLOAT", "`c_double` DOUBLE", "`c_decimal` DECIMAL(65,30)",
+		"`c_date` DATE", "`c_datetime` DATETIME(6)", "`c_timestamp` TIMESTAMP(6) NULL", "`c_time` TIME(6)", "`c_year` YEAR",
+		"`c_char` CHAR(10)", "`c_varchar` VARCHAR(255)", "`c_text` TEXT", "`c_latin1` VARCHAR(255) CHARACTER SET latin1",
+		"`c_enum` ENUM('a','it''s','\\\\')", "`c_set` SET('x','y','z')",
+		"`c_binary` BINARY(8)", "`c_varbinary` VARBINARY(255)", "`c_blob` LONGBLOB",
+		"`c_bit1` BIT(1)", "`c_bit10` BIT(10)", "`c_bit64` BIT(64)",
+		"`c_json` JSON",
+		"`c_geometry` GEOMETRY", "`c_point` POINT", "`c_polygon` POLYGON", "`c_collection` GEOMETRYCOLLECTION",
+	}
+
+	values := []string{
+		`1, -128, 255, -9223372036854775808, 18446744073709551615, 3.4028235e38, 2.2250738585072014e-308, 12345678901234567890123456789012345.123456789012345678901234567890,
+		'1000-01-01', '9999-12-31 23:59:59.999999', '2038-01-18 00:00:00.123456', '-838:59:59.000000', 2155,
+		'trailing  ', 'quote '' backslash \\ nul \0 newline \n cr \r ctrl-z \Z tab \t', '日本語 🐬 emoji', 'café',
+		'it''s', 'x,z',
+		0x00FF00FF00000000, 0x00275C22FF, 0x000102030405060708090A0B0C0D0E0F1A5C27,
+		b'1', b'1000000001', b'1111111111111111111111111111111111111111111111111111111111111111',
+		'{"a": "line\\nbreak \\"quoted\\"", "b": [1, 2.5, null, true], "c": "\\u00e9"}',
+		ST_GeomFromText('LINESTRING(0 0, 1 1, 2 2)'), ST_GeomFromText('POINT(1.5 -2.25)', 4326), ST_GeomFromText('POLYGON((0 0, 10 0, 10 10, 0 10, 0 0))'),
+		ST_GeomFromText('GEOMETRYCOLLECTION(POINT(1 1), LINESTRING(0 0, 1 1))')`,
+		`2, 0, 0, 0, 0, 0, 0, 0, '2024-02-29', '2024-02-29 00:00:00', NULL, '00:00:00', 2000, '', '', '', '', '\\', '', '', '', '', b'0', b'0', b'0', 'null', NULL, NULL, NULL, NULL`,
+		`3` + strings.Repeat(", NULL", len(columns)-1),
+	}
+
+	if strings.HasPrefix(version, "9.") {
+		columns = append(columns, "`c_vector` VECTOR(3)")
+		for i := range values {
+			if i == len(values)-1 {
+				values[i] += ", NULL"
+			} else {
+				values[i] += ", STRING_TO_VECTOR('[1.5, -0.1, 3.4028235e38]')"
+			}
+		}
+	}
+
+	setup := "DROP TABLE IF EXISTS `onedump_round_trip`;" +
+		"CREATE TABLE `onedump_round_trip` (" + strings.Join(columns, ", ") + ") DEFAULT CHARSET=utf8mb4;" +
+		"INSERT INTO `onedump_round_trip` VALUES (" + strings.Join(values, "), (") + ");"
+
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Exec("DROP TABLE IF EXISTS `onedump_round_trip`")
+
+	for _, noBackslashEscapes := range []bool{false, true} {
+		t.Run(fmt.Sprintf("no backslash escapes: %v", noBackslashEscapes), func(t *testing.T) {
+			assert := assert.New(t)
+			ctx := context.Background()
+
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer conn.Close()
+
+			if noBackslashEscapes {
+				_, err := conn.ExecContext(ctx, "SET SESSION sql_mode = CONCAT(@@SESSION.sql_mode, ',NO_BACKSLASH_ESCAPES')")
+				assert.NoError(err)
+			}
+
+			dumper := &MysqlNativeDump{
+				options:         newOptions(),
+				netBufferLength: DefaultNetBufferLength,
+				database:        config.DBName,
+				db:              conn,
+			}
+
+			sqlMode, err := dumper.getSqlMode()
+			assert.NoError(err)
+			dumper.noBackslashEscapes = hasNoBackslashEscapes(sqlMode)
+			assert.Equal(noBackslashEscapes, dumper.noBackslashEscapes)
+
+			before := selectRoundTripRows(t, conn)
+
+			var b bytes.Buffer
+			buf := bufio.NewWriter(&b)
+			assert.NoError(dumper.writeTableContent(buf, "onedump_round_trip"))
+			assert.NoError(buf.Flush())
+
+			_, err = conn.ExecContext(ctx, "TRUNCATE TABLE `onedump_round_trip`")
+			assert.NoError(err)
+
+			_, err = conn.ExecContext(ctx, b.String())
+			assert.NoError(err)
+
+			assert.Equal(before, selectRoundTripRows(t, conn))
+		})
+	}
+}
+
+// Select all values as hex, so the comparison is byte for byte.
+func selectRoundTripRows(t *testing.T, conn *sql.Conn) [][]sql.NullString {
+	ctx := context.Background()
+
+	rows, err := conn.QueryContext(ctx, "SELECT * FROM `onedump_round_trip` ORDER BY `id`")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows.Close()
+
+	hexColumns := make([]string, len(columns))
+	for i, column := range columns {
+		hexColumns[i] = "HEX(" + sqlutil.QuoteIdentifier(column) + ")"
+	}
+
+	rows, err = conn.QueryContext(ctx, "SELECT "+strings.Join(hexColumns, ", ")+" FROM `onedump_round_trip` ORDER BY `id`")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rows.Close()
+
+	var results [][]sql.NullString
+
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatal(err)
+		}
+
+		results = append(results, values)
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return results
+}
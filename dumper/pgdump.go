@@ -21,6 +21,7 @@ type PgDump struct {
 	credentialFiles []string
 	path            string
 	options         []string
+	tableOptions    []string // --table, --exclude-table and --exclude-table-data options translated from the table filter
 	viaSsh          bool
 	sshHost         string
 	sshUser         string
@@ -38,17 +39,47 @@ func NewPgDump(job *config.Job) (*PgDump, error) {
 		return nil, err
 	}
 
+	tableOptions, err := pgDumpTableArgs(job.Tables, job.ViaSsh())
+	if err != nil {
+		return nil, err
+	}
+
 	return &PgDump{
-		path:     "pg_dump",
-		options:  options,
-		viaSsh:   job.ViaSsh(),
-		sshHost:  job.SshHost,
-		sshUser:  job.SshUser,
-		sshKey:   job.SshKey,
-		DBConfig: NewDBConfig(config.Database, config.User, config.Password, config.Host, int(config.Port)),
+		path:         "pg_dump",
+		options:      options,
+		tableOptions: tableOptions,
+		viaSsh:       job.ViaSsh(),
+		sshHost:      job.SshHost,
+		sshUser:      job.SshUser,
+		sshKey:       job.SshKey,
+		DBConfig:     NewDBConfig(config.Database, config.User, config.Password, config.Host, int(config.Port)),
 	}, nil
 }
 
+// Translate the table filter to pg_dump options, pg_dump patterns support the same * and ? wildcards.
+func pgDumpTableArgs(filter config.TableFilter, viaSsh bool) ([]string, error) {
+	if len(filter.Where) > 0 {
+		return nil, errors.New("pg_dump does not support where predicates of tables")
+	}
+
+	var args []string
+	add := func(option string, patterns []string) {
+		for _, pattern := range patterns {
+			if viaSsh {
+				pattern = shellQuote(pattern)
+			}
+
+			args = append(args, option+"="+pattern)
+		}
+	}
+
+	add("--table", filter.Include)
+	add("--exclude-table", filter.Exclude)
+	add("--exclude-table-data", filter.SchemaOnly)
+
+	return args, nil
+}
+
 func (psql *PgDump) getDumpCommandArgs() []string {
 	args := []string{}
 
@@ -57,6 +88,7 @@ func (psql *PgDump) getDumpCommandArgs() []string {
 	args = append(args, "--username="+psql.Username)
 	args = append(args, "--dbname="+psql.DBName)
 	args = append(args, psql.options...)
+	args = append(args, psql.tableOptions...)
 
 	return args
 }
@@ -152,3 +152,27 @@ func TestClosePostgresql(t *testing.T) {
 	pgdump.credentialFiles = append(pgdump.credentialFiles, "wrong file")
 	assert.NotNil(pgdump.close())
 }
+
+func TestPgDumpTableArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	filter := config.TableFilter{
+		Include:    []string{"public.*"},
+		Exclude:    []string{"public.logs"},
+		SchemaOnly: []string{"public.audit_*"},
+	}
+
+	job := config.NewJob("test", "postgresql", testPsqlDBDsn, config.WithTables(filter))
+	pgdump, err := NewPgDump(job)
+	assert.Nil(err)
+
+	expect := "--host=localhost --port=5432 --username=julianli --dbname=mypsqldb --table=public.* --exclude-table=public.logs --exclude-table-data=public.audit_*"
+	assert.Equal(expect, strings.Join(pgdump.getDumpCommandArgs(), " "))
+
+	args, err := pgDumpTableArgs(filter, true)
+	assert.Nil(err)
+	assert.Equal([]string{"--table='public.*'", "--exclude-table='public.logs'", "--exclude-table-data='public.audit_*'"}, args)
+
+	_, err = NewPgDump(config.NewJob("test", "postgresql", testPsqlDBDsn, config.WithTables(config.TableFilter{Where: map[string]string{"events": "id > 1"}})))
+	assert.EqualError(err, "pg_dump does not support where predicates of tables")
+}
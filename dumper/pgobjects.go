@@ -0,0 +1,622 @@
+package dumper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Skip system schemas, and objects that are created by extensions as CREATE EXTENSION creates them.
+const pgUserSchemaCondition = `n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'`
+
+func pgNotExtensionMember(catalog, oid string) string {
+	return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d WHERE d.classid = '%s'::pg_catalog.regclass AND d.objid = %s AND d.deptype = 'e')", catalog, oid)
+}
+
+// The function depends on a table or a view, e.g. the BEGIN ATOMIC body references a table,
+// or the signature uses the row type of a table (or an array of it).
+const pgFunctionDependsOnRelation = `EXISTS (SELECT 1 FROM pg_catalog.pg_depend d ` +
+	`LEFT JOIN pg_catalog.pg_type t ON d.refclassid = 'pg_catalog.pg_type'::pg_catalog.regclass AND t.oid = d.refobjid ` +
+	`LEFT JOIN pg_catalog.pg_type et ON et.oid = t.typelem ` +
+	`WHERE d.classid = 'pg_catalog.pg_proc'::pg_catalog.regclass AND d.objid = p.oid AND d.deptype = 'n' ` +
+	`AND (d.refclassid = 'pg_catalog.pg_class'::pg_catalog.regclass OR t.typrelid <> 0 OR et.typrelid <> 0))`
+
+type pgColumn struct {
+	name       string
+	dataType   string // formatted type, e.g. character varying(255)
+	notNull    bool
+	expression string // the default value or the generation expression
+	identity   string // a (always), d (by default) or empty
+	generated  string // s (stored), v (virtual) or empty
+	collation  string // quoted collation if it is not the default of the type
+	sequence   string // the quoted sequence name of identity columns
+}
+
+type pgTable struct {
+	oid      uint32
+	schema   string
+	name     string
+	unlogged bool
+	columns  []pgColumn
+}
+
+type pgSequence struct {
+	schema        string
+	name          string
+	dataType      string
+	start         int64
+	increment     int64
+	min           int64
+	max           int64
+	cache         int64
+	cycle         bool
+	identity      bool // sequences of identity columns are created by CREATE TABLE
+	ownedBySchema string
+	ownedByTable  string // the table which owns the sequence, empty if it is not owned by a column
+	ownedByColumn string
+}
+
+type pgView struct {
+	oid          uint32
+	schema       string
+	name         string
+	materialized bool
+	populated    bool
+	definition   string
+}
+
+// Get the qualified name of the table which owns the sequence, it is quoted in the same way as the dumped tables.
+func (s pgSequence) owner() string {
+	if s.ownedByTable == "" {
+		return ""
+	}
+
+	return pgQualify(s.ownedBySchema, s.ownedByTable)
+}
+
+// Run a query that returns a single text column.
+func (p *PgNativeDump) queryStrings(query string, args ...any) ([]string, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("fail to run query %s, error: %v", query, err)
+	}
+
+	values, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("fail to read rows of query %s, error: %v", query, err)
+	}
+
+	return values, nil
+}
+
+func (p *PgNativeDump) writeSchemas(buf *bufio.Writer) error {
+	query := "SELECT n.nspname FROM pg_catalog.pg_namespace n WHERE " + pgUserSchemaCondition +
+		" AND " + pgNotExtensionMember("pg_catalog.pg_namespace", "n.oid") + " ORDER BY n.nspname"
+
+	schemas, err := p.queryStrings(query)
+	if err != nil {
+		return err
+	}
+
+	for _, schema := range schemas {
+		writePgComment(buf, schema, "SCHEMA", "")
+		buf.WriteString("CREATE SCHEMA IF NOT EXISTS " + pgx.Identifier{schema}.Sanitize() + ";\n\n")
+	}
+
+	return nil
+}
+
+func (p *PgNativeDump) writeExtensions(buf *bufio.Writer) error {
+	query := "SELECT e.extname, n.nspname FROM pg_catalog.pg_extension e " +
+		"JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace ORDER BY e.extname"
+
+	rows, err := p.db.Query(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("fail to query extensions, error: %v", err)
+	}
+
+	var name, schema string
+	_, err = pgx.ForEachRow(rows, []any{&name, &schema}, func() error {
+		writePgComment(buf, name, "EXTENSION", "")
+		_, err := buf.WriteString(fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s;\n\n",
+			pgx.Identifier{name}.Sanitize(), pgx.Identifier{schema}.Sanitize()))
+		return err
+	})
+
+	return err
+}
+
+func (p *PgNativeDump) writeEnumTypes(buf *bufio.Writer) error {
+	query := "SELECT n.nspname, t.typname, pg_catalog.array_agg(e.enumlabel::text ORDER BY e.enumsortorder) " +
+		"FROM pg_catalog.pg_type t " +
+		"JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace " +
+		"JOIN pg_catalog.pg_enum e ON e.enumtypid = t.oid " +
+		"WHERE " + pgUserSchemaCondition + " AND " + pgNotExtensionMember("pg_catalog.pg_type", "t.oid") +
+		" GROUP BY n.nspname, t.typname ORDER BY n.nspname, t.typname"
+
+	rows, err := p.db.Query(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("fail to query enum types, error: %v", err)
+	}
+
+	var schema, name string
+	var labels []string
+	_, err = pgx.ForEachRow(rows, []any{&schema, &name, &labels}, func() error {
+		quoted := make([]string, len(labels))
+		for i, label := range labels {
+			quoted[i] = pgQuoteLiteral(label)
+		}
+
+		writePgComment(buf, name, "TYPE", schema)
+		_, err := buf.WriteString(fmt.Sprintf("CREATE TYPE %s AS ENUM (\n    %s\n);\n\n", pgQualify(schema, name), strings.Join(quoted, ",\n    ")))
+		return err
+	})
+
+	return err
+}
+
+// Functions are created before tables, so they can be used by column defaults and check constraints.
+// Function bodies are not validated on restore as check_function_bodies is off, except BEGIN ATOMIC bodies,
+// so the functions that depend on tables are written after the tables are created instead.
+func (p *PgNativeDump) writeFunctions(buf *bufio.Writer, dependsOnRelation bool) error {
+	condition := pgFunctionDependsOnRelation
+	if !dependsOnRelation {
+		condition = "NOT " + condition
+	}
+
+	query := "SELECT n.nspname, p.proname, pg_catalog.pg_get_functiondef(p.oid) FROM pg_catalog.pg_proc p " +
+		"JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace " +
+		"WHERE " + pgUserSchemaCondition + " AND p.prokind IN ('f', 'p') AND " + pgNotExtensionMember("pg_catalog.pg_proc", "p.oid") +
+		" AND " + condition + " ORDER BY n.nspname, p.proname, p.oid"
+
+	rows, err := p.db.Query(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("fail to query functions, error: %v", err)
+	}
+
+	var schema, name, definition string
+	_, err = pgx.ForEachRow(rows, []any{&schema, &name, &definition}, func() error {
+		writePgComment(buf, name, "FUNCTION", schema)
+		_, err := buf.WriteString(strings.TrimRight(definition, "\n") + ";\n\n")
+		return err
+	})
+
+	return err
+}
+
+// Get the tables to dump, partitioned tables and table inheritance are not supported yet.
+func (p *PgNativeDump) getTables() ([]pgTable, error) {
+	query := "SELECT c.oid, n.nspname, c.relname, c.relkind::text, c.relpersistence::text, c.relispartition, " +
+		"EXISTS (SELECT 1 FROM pg_catalog.pg_inherits i WHERE i.inhrelid = c.oid OR i.inhparent = c.oid) FROM pg_catalog.pg_class c " +
+		"JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace " +
+		"WHERE c.relkind IN ('r', 'p') AND " + pgUserSchemaCondition + " AND " + pgNotExtensionMember("pg_catalog.pg_class", "c.oid") +
+		" ORDER BY n.nspname, c.relname"
+
+	rows, err := p.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("fail to query tables, error: %v", err)
+	}
+
+	var tables []pgTable
+	var table pgTable
+	var kind, persistence string
+	var isPartition, inherits bool
+
+	_, err = pgx.ForEachRow(rows, []any{&table.oid, &table.schema, &table.name, &kind, &persistence, &isPartition, &inherits}, func() error {
+		if !p.tables.Match(pgTableName(table.schema, table.name)) {
+			return nil
+		}
+
+		if kind == "p" || isPartition {
+			return fmt.Errorf("partitioned table %s is not supported by the native PostgreSQL dumper, use the pgdump driver or exclude it", pgQualify(table.schema, table.name))
+		}
+
+		// The child tables would be restored as standalone tables, so the parent would no longer see their rows.
+		if inherits {
+			return fmt.Errorf("table inheritance of table %s is not supported by the native PostgreSQL dumper, use the pgdump driver or exclude it", pgQualify(table.schema, table.name))
+		}
+
+		table.unlogged = persistence == "u"
+		tables = append(tables, table)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fail to read tables, error: %v", err)
+	}
+
+	for i := range tables {
+		tables[i].columns, err = p.getColumns(tables[i].oid)
+		if err != nil {
+			return nil, fmt.Errorf("fail to get columns of table: %s, error: %v", tables[i].name, err)
+		}
+	}
+
+	return tables, nil
+}
+
+func (p *PgNativeDump) getColumns(tableOid uint32) ([]pgColumn, error) {
+	query := "SELECT a.attname, pg_catalog.format_type(a.atttypid, a.atttypmod), a.attnotnull, " +
+		"COALESCE(pg_catalog.pg_get_expr(ad.adbin, ad.adrelid), ''), a.attidentity::text, a.attgenerated::text, " +
+		"CASE WHEN a.attcollation <> t.typcollation THEN pg_catalog.quote_ident(cn.nspname) || '.' || pg_catalog.quote_ident(co.collname) ELSE '' END, " +
+		"CASE WHEN a.attidentity <> '' THEN pg_catalog.pg_get_serial_sequence(a.attrelid::pg_catalog.regclass::text, a.attname) ELSE '' END " +
+		"FROM pg_catalog.pg_attribute a " +
+		"JOIN pg_catalog.pg_type t ON t.oid = a.atttypid " +
+		"LEFT JOIN pg_catalog.pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum " +
+		"LEFT JOIN pg_catalog.pg_collation co ON co.oid = a.attcollation " +
+		"LEFT JOIN pg_catalog.pg_namespace cn ON cn.oid = co.collnamespace " +
+		"WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum"
+
+	rows, err := p.db.Query(context.Background(), query, tableOid)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []pgColumn
+	var c pgColumn
+
+	_, err = pgx.ForEachRow(rows, []any{&c.name, &c.dataType, &c.notNull, &c.expression, &c.identity, &c.generated, &c.collation, &c.sequence}, func() error {
+		columns = append(columns, c)
+		return nil
+	})
+
+	return columns, err
+}
+
+func columnDefinition(column pgColumn) string {
+	var sb strings.Builder
+
+	sb.WriteString(pgx.Identifier{column.name}.Sanitize() + " " + column.dataType)
+
+	if column.collation != "" {
+		sb.WriteString(" COLLATE " + column.collation)
+	}
+
+	switch {
+	case column.generated == "s":
+		sb.WriteString(" GENERATED ALWAYS AS (" + column.expression + ") STORED")
+	case column.generated == "v":
+		sb.WriteString(" GENERATED ALWAYS AS (" + column.expression + ") VIRTUAL")
+	case column.expression != "":
+		sb.WriteString(" DEFAULT " + column.expression)
+	}
+
+	if column.identity != "" {
+		if column.identity == "a" {
+			sb.WriteString(" GENERATED ALWAYS AS IDENTITY")
+		} else {
+			sb.WriteString(" GENERATED BY DEFAULT AS IDENTITY")
+		}
+
+		// Keep the sequence name, so the sequence value can be restored.
+		if column.sequence != "" {
+			sb.WriteString(" (SEQUENCE NAME " + column.sequence + ")")
+		}
+	}
+
+	if column.notNull {
+		sb.WriteString(" NOT NULL")
+	}
+
+	return sb.String()
+}
+
+func createTableStatement(table pgTable) string {
+	var sb strings.Builder
+
+	sb.WriteString("CREATE ")
+	if table.unlogged {
+		sb.WriteString("UNLOGGED ")
+	}
+
+	sb.WriteString("TABLE " + pgQualify(table.schema, table.name) + " (")
+
+	for i, column := range table.columns {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+
+		sb.WriteString("\n    " + columnDefinition(column))
+	}
+
+	sb.WriteString("\n);\n\n")
+
+	return sb.String()
+}
+
+func (p *PgNativeDump) getSequences() ([]pgSequence, error) {
+	query := "SELECT n.nspname, c.relname, pg_catalog.format_type(s.seqtypid, NULL), s.seqstart, s.seqincrement, s.seqmin, s.seqmax, s.seqcache, s.seqcycle, " +
+		"COALESCE(d.deptype = 'i', false), " +
+		"COALESCE(tn.nspname, ''), COALESCE(t.relname, ''), COALESCE(a.attname, '') " +
+		"FROM pg_catalog.pg_sequence s " +
+		"JOIN pg_catalog.pg_class c ON c.oid = s.seqrelid " +
+		"JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace " +
+		"LEFT JOIN pg_catalog.pg_depend d ON d.classid = 'pg_catalog.pg_class'::pg_catalog.regclass AND d.objid = c.oid " +
+		"AND d.refclassid = 'pg_catalog.pg_class'::pg_catalog.regclass AND d.deptype IN ('a', 'i') " +
+		"LEFT JOIN pg_catalog.pg_class t ON t.oid = d.refobjid " +
+		"LEFT JOIN pg_catalog.pg_namespace tn ON tn.oid = t.relnamespace " +
+		"LEFT JOIN pg_catalog.pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid " +
+		"WHERE " + pgUserSchemaCondition + " AND " + pgNotExtensionMember("pg_catalog.pg_class", "c.oid") +
+		" ORDER BY n.nspname, c.relname"
+
+	rows, err := p.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("fail to query sequences, error: %v", err)
+	}
+
+	var sequences []pgSequence
+	var s pgSequence
+
+	_, err = pgx.ForEachRow(rows, []any{&s.schema, &s.name, &s.dataType, &s.start, &s.increment, &s.min, &s.max, &s.cache, &s.cycle, &s.identity, &s.ownedBySchema, &s.ownedByTable, &s.ownedByColumn}, func() error {
+		sequences = append(sequences, s)
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fail to read sequences, error: %v", err)
+	}
+
+	return sequences, nil
+}
+
+// Get the ALTER SEQUENCE ... OWNED BY statement of a standalone sequence, it is empty if the owner table is not dumped.
+func ownedByStatement(sequence pgSequence, dumped map[string]bool) string {
+	if sequence.identity || !dumped[sequence.owner()] {
+		return ""
+	}
+
+	return fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s;\n\n",
+		pgQualify(sequence.schema, sequence.name), sequence.owner(), pgx.Identifier{sequence.ownedByColumn}.Sanitize())
+}
+
+// Check if the value of the sequence should be dumped, the sequence of an identity column is only created with its table.
+func isSequenceValueDumped(sequence pgSequence, dumped map[string]bool) bool {
+	return !sequence.identity || dumped[sequence.owner()]
+}
+
+func createSequenceStatement(sequence pgSequence) string {
+	var sb strings.Builder
+
+	sb.WriteString("CREATE SEQUENCE " + pgQualify(sequence.schema, sequence.name) + "\n")
+	sb.WriteString("    AS " + sequence.dataType + "\n")
+	sb.WriteString("    START WITH " + strconv.FormatInt(sequence.start, 10) + "\n")
+	sb.WriteString("    INCREMENT BY " + strconv.FormatInt(sequence.increment, 10) + "\n")
+	sb.WriteString("    MINVALUE " + strconv.FormatInt(sequence.min, 10) + "\n")
+	sb.WriteString("    MAXVALUE " + strconv.FormatInt(sequence.max, 10) + "\n")
+	sb.WriteString("    CACHE " + strconv.FormatInt(sequence.cache, 10))
+
+	if sequence.cycle {
+		sb.WriteString("\n    CYCLE")
+	}
+
+	sb.WriteString(";\n\n")
+
+	return sb.String()
+}
+
+func (p *PgNativeDump) writeSequenceValue(buf *bufio.Writer, sequence pgSequence) error {
+	name := pgQualify(sequence.schema, sequence.name)
+
+	var lastValue int64
+	var isCalled bool
+
+	if err := p.db.QueryRow(context.Background(), "SELECT last_value, is_called FROM "+name).Scan(&lastValue, &isCalled); err != nil {
+		return err
+	}
+
+	_, err := buf.WriteString(fmt.Sprintf("SELECT pg_catalog.setval(%s, %d, %t);\n\n", pgQuoteLiteral(name), lastValue, isCalled))
+	return err
+}
+
+// Write primary keys, unique, check and exclusion constraints, or foreign keys only.
+func (p *PgNativeDump) writeConstraints(buf *bufio.Writer, table pgTable, foreignKeys bool) error {
+	types := "('p', 'u', 'c', 'x')"
+	if foreignKeys {
+		types = "('f')"
+	}
+
+	query := "SELECT co.conname, co.contype::text, pg_catalog.pg_get_constraintdef(co.oid) FROM pg_catalog.pg_constraint co " +
+		"WHERE co.conrelid = $1 AND co.contype IN " + types + " ORDER BY co.contype = 'c', co.conname"
+
+	rows, err := p.db.Query(context.Background(), query, table.oid)
+	if err != nil {
+		return err
+	}
+
+	var name, kind, definition string
+	_, err = pgx.ForEachRow(rows, []any{&name, &kind, &definition}, func() error {
+		objectType := "CONSTRAINT"
+		if kind == "f" {
+			objectType = "FK CONSTRAINT"
+		}
+
+		writePgComment(buf, table.name+" "+name, objectType, table.schema)
+		_, err := buf.WriteString(fmt.Sprintf("ALTER TABLE ONLY %s\n    ADD CONSTRAINT %s %s;\n\n",
+			pgQualify(table.schema, table.name), pgx.Identifier{name}.Sanitize(), definition))
+		return err
+	})
+
+	return err
+}
+
+// Write indexes that are not created by constraints.
+func (p *PgNativeDump) writeIndexes(buf *bufio.Writer, relationOid uint32) error {
+	query := "SELECT pg_catalog.pg_get_indexdef(i.indexrelid) FROM pg_catalog.pg_index i " +
+		"WHERE i.indrelid = $1 AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint co WHERE co.conindid = i.indexrelid AND co.contype IN ('p', 'u', 'x')) " +
+		"ORDER BY i.indexrelid"
+
+	definitions, err := p.queryStrings(query, relationOid)
+	if err != nil {
+		return err
+	}
+
+	for _, definition := range definitions {
+		if _, err := buf.WriteString(definition + ";\n\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write views and materialized views after the views they depend on.
+func (p *PgNativeDump) writeViews(buf *bufio.Writer) error {
+	views, err := p.getViews()
+	if err != nil {
+		return err
+	}
+
+	dependencies, err := p.getViewDependencies()
+	if err != nil {
+		return err
+	}
+
+	var materializedViews []uint32
+	for _, view := range sortPgViews(views, dependencies) {
+		if !view.materialized {
+			writePgComment(buf, view.name, "VIEW", view.schema)
+			if _, err := buf.WriteString(fmt.Sprintf("CREATE VIEW %s AS\n %s;\n\n", pgQualify(view.schema, view.name), view.definition)); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		withData := "WITH NO DATA"
+		if view.populated {
+			withData = "WITH DATA"
+		}
+
+		materializedViews = append(materializedViews, view.oid)
+
+		writePgComment(buf, view.name, "MATERIALIZED VIEW", view.schema)
+		if _, err := buf.WriteString(fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS\n %s\n  %s;\n\n", pgQualify(view.schema, view.name), view.definition, withData)); err != nil {
+			return err
+		}
+	}
+
+	for _, oid := range materializedViews {
+		if err := p.writeIndexes(buf, oid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *PgNativeDump) getViews() ([]pgView, error) {
+	query := "SELECT c.oid, n.nspname, c.relname, c.relkind::text, c.relispopulated, pg_catalog.pg_get_viewdef(c.oid) FROM pg_catalog.pg_class c " +
+		"JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace " +
+		"WHERE c.relkind IN ('v', 'm') AND " + pgUserSchemaCondition + " AND " + pgNotExtensionMember("pg_catalog.pg_class", "c.oid") +
+		" ORDER BY c.oid"
+
+	rows, err := p.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("fail to query views, error: %v", err)
+	}
+
+	var views []pgView
+	var view pgView
+	var kind string
+
+	_, err = pgx.ForEachRow(rows, []any{&view.oid, &view.schema, &view.name, &kind, &view.populated, &view.definition}, func() error {
+		if !p.tables.Match(pgTableName(view.schema, view.name)) {
+			return nil
+		}
+
+		view.materialized = kind == "m"
+		view.definition = strings.TrimSuffix(strings.TrimSpace(view.definition), ";")
+		views = append(views, view)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fail to read views, error: %v", err)
+	}
+
+	return views, nil
+}
+
+// Get the relations that each view references, by the rewrite rules of the views.
+func (p *PgNativeDump) getViewDependencies() (map[uint32][]uint32, error) {
+	query := "SELECT DISTINCT r.ev_class, d.refobjid FROM pg_catalog.pg_rewrite r " +
+		"JOIN pg_catalog.pg_depend d ON d.classid = 'pg_catalog.pg_rewrite'::pg_catalog.regclass AND d.objid = r.oid " +
+		"WHERE d.refclassid = 'pg_catalog.pg_class'::pg_catalog.regclass AND d.refobjid <> r.ev_class"
+
+	rows, err := p.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("fail to query view dependencies, error: %v", err)
+	}
+
+	dependencies := make(map[uint32][]uint32)
+	var view, relation uint32
+
+	_, err = pgx.ForEachRow(rows, []any{&view, &relation}, func() error {
+		dependencies[view] = append(dependencies[view], relation)
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fail to read view dependencies, error: %v", err)
+	}
+
+	return dependencies, nil
+}
+
+// Sort the views so every view comes after the views it depends on, otherwise they keep their order.
+// The order of creation is not enough, as CREATE OR REPLACE VIEW may make a view depend on a newer view.
+func sortPgViews(views []pgView, dependencies map[uint32][]uint32) []pgView {
+	byOid := make(map[uint32]pgView, len(views))
+	for _, view := range views {
+		byOid[view.oid] = view
+	}
+
+	sorted := make([]pgView, 0, len(views))
+	visited := make(map[uint32]bool, len(views))
+
+	var visit func(view pgView)
+	visit = func(view pgView) {
+		if visited[view.oid] {
+			return
+		}
+
+		visited[view.oid] = true
+
+		for _, oid := range dependencies[view.oid] {
+			if dependency, ok := byOid[oid]; ok {
+				visit(dependency)
+			}
+		}
+
+		sorted = append(sorted, view)
+	}
+
+	for _, view := range views {
+		visit(view)
+	}
+
+	return sorted
+}
+
+func (p *PgNativeDump) writeTriggers(buf *bufio.Writer, tableOid uint32) error {
+	query := "SELECT pg_catalog.pg_get_triggerdef(t.oid) FROM pg_catalog.pg_trigger t WHERE t.tgrelid = $1 AND NOT t.tgisinternal ORDER BY t.tgname"
+
+	definitions, err := p.queryStrings(query, tableOid)
+	if err != nil {
+		return err
+	}
+
+	for _, definition := range definitions {
+		if _, err := buf.WriteString(definition + ";\n\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -12,6 +12,12 @@ const (
 	AWS_SECRET_ACCESS_KEY = "AWS_SECRET_ACCESS_KEY"
 	AWS_SESSION_TOKEN     = "AWS_SESSION_TOKEN"
 	DATABASE_DSN          = "DATABASE_DSN"
+	DROPBOX_REFRESH_TOKEN = "DROPBOX_REFRESH_TOKEN"
+	DROPBOX_CLIENT_ID     = "DROPBOX_CLIENT_ID"
+	DROPBOX_CLIENT_SECRET = "DROPBOX_CLIENT_SECRET"
+	GDRIVE_EMAIL          = "GDRIVE_EMAIL"
+	GDRIVE_PRIVATE_KEY    = "GDRIVE_PRIVATE_KEY"
+	BINLOG_ENCRYPTION_KEY = "BINLOG_ENCRYPTION_KEY"
 )
 
 var ErrMissingEnv = errors.New("at least one env is required to resolve")
@@ -35,9 +41,24 @@ func EnsureRequiredVars(vars []string) error {
 	return errs
 }
 
+type DropboxCredentials struct {
+	RefreshToken string
+	ClientId     string
+	ClientSecret string
+}
+
+// The google cloud service account.
+type GDriveCredentials struct {
+	Email      string
+	PrivateKey string
+}
+
 type EnvResolver struct {
 	aws         bool
 	databaseDSN bool
+	dropbox     bool
+	gdrive      bool
+	binlogKey   bool
 }
 
 type resolverOption func(resolver *EnvResolver)
@@ -64,9 +85,30 @@ func WithDatabaseDSN() resolverOption {
 	}
 }
 
+func WithDropbox() resolverOption {
+	return func(resolver *EnvResolver) {
+		resolver.dropbox = true
+	}
+}
+
+func WithGDrive() resolverOption {
+	return func(resolver *EnvResolver) {
+		resolver.gdrive = true
+	}
+}
+
+func WithBinlogEncryptionKey() resolverOption {
+	return func(resolver *EnvResolver) {
+		resolver.binlogKey = true
+	}
+}
+
 type Values struct {
-	AWSCredentials AWSCredentials
-	DatabaseDSN    string
+	AWSCredentials      AWSCredentials
+	DropboxCredentials  DropboxCredentials
+	GDriveCredentials   GDriveCredentials
+	DatabaseDSN         string
+	BinlogEncryptionKey string
 }
 
 func (resolver *EnvResolver) Resolve() (Values, error) {
@@ -80,6 +122,18 @@ func (resolver *EnvResolver) Resolve() (Values, error) {
 		requiredVars = append(requiredVars, DATABASE_DSN)
 	}
 
+	if resolver.dropbox {
+		requiredVars = append(requiredVars, DROPBOX_REFRESH_TOKEN, DROPBOX_CLIENT_ID, DROPBOX_CLIENT_SECRET)
+	}
+
+	if resolver.gdrive {
+		requiredVars = append(requiredVars, GDRIVE_EMAIL, GDRIVE_PRIVATE_KEY)
+	}
+
+	if resolver.binlogKey {
+		requiredVars = append(requiredVars, BINLOG_ENCRYPTION_KEY)
+	}
+
 	if len(requiredVars) == 0 {
 		return Values{}, ErrMissingEnv
 	}
@@ -95,6 +149,16 @@ func (resolver *EnvResolver) Resolve() (Values, error) {
 			SessionToken:    os.Getenv(AWS_SESSION_TOKEN),
 			Region:          os.Getenv(AWS_REGION),
 		},
-		DatabaseDSN: os.Getenv(DATABASE_DSN),
+		DropboxCredentials: DropboxCredentials{
+			RefreshToken: os.Getenv(DROPBOX_REFRESH_TOKEN),
+			ClientId:     os.Getenv(DROPBOX_CLIENT_ID),
+			ClientSecret: os.Getenv(DROPBOX_CLIENT_SECRET),
+		},
+		GDriveCredentials: GDriveCredentials{
+			Email:      os.Getenv(GDRIVE_EMAIL),
+			PrivateKey: os.Getenv(GDRIVE_PRIVATE_KEY),
+		},
+		DatabaseDSN:         os.Getenv(DATABASE_DSN),
+		BinlogEncryptionKey: os.Getenv(BINLOG_ENCRYPTION_KEY),
 	}, nil
 }
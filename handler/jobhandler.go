@@ -54,21 +54,29 @@ func storageReadWriteCloser(count int, compress bool) ([]io.Reader, io.Writer, i
 	return prs, io.MultiWriter(pws...), config.NewMultiCloser(pcs)
 }
 
-// Save database dump to different storages.
-func (handler *JobHandler) save() error {
+// Save database dump to different storages, the dump is also copied for the verification if it is not nil.
+func (handler *JobHandler) save(verification *verification) error {
 	job := handler.Job
 	storages := handler.getStorages()
 
-	numberOfStorages := len(storages)
-
-	errCh := make(chan error, numberOfStorages+1)
-
 	dumper, err := handler.getDumper()
 
 	if err != nil {
 		return fmt.Errorf("could not get dumper: %v", err)
 	}
 
+	if verification != nil {
+		if err := verification.attach(dumper); err != nil {
+			return err
+		}
+
+		storages = append(storages, verification)
+	}
+
+	numberOfStorages := len(storages)
+
+	errCh := make(chan error, numberOfStorages+1)
+
 	if numberOfStorages > 0 {
 		// Use pipe to pass content from the database dump to different writer.
 		readers, writer, closer := storageReadWriteCloser(numberOfStorages, job.Gzip)
@@ -159,7 +167,13 @@ func (handler *JobHandler) getDumper() (dumper.Dumper, error) {
 	case "mysql":
 		return dumper.NewMysqlNativeDump(job)
 	case "postgresql":
-		return dumper.NewPgDump(job)
+		// The existing jobs with pg_dump options keep running pg_dump.
+		if !dumper.IsPgNativeDumpSupported(job.DumpOptions...) {
+			slog.Warn("the native PostgreSQL dumper does not support the dump options, fall back to pg_dump", slog.String("job", job.Name))
+			return dumper.NewPgDump(job)
+		}
+
+		return dumper.NewPgNativeDump(job)
 	case "mysqldump":
 		return dumper.NewMysqlDump(job)
 	case "pgdump":
@@ -181,9 +195,29 @@ func (handler *JobHandler) Do() *jobresult.JobResult {
 
 	result.JobName = handler.Job.Name
 
-	err := handler.save()
+	var verification *verification
+	if handler.Job.Verify != nil {
+		v, err := newVerification(handler.Job)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+
+		defer v.close()
+		verification = v
+	}
+
+	err := handler.save(verification)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to store dump file %v", err)
+		return result
+	}
+
+	if verification != nil {
+		result.VerifiedTables, err = verification.run()
+		if err != nil {
+			result.Error = fmt.Errorf("failed to verify dump file: %v", err)
+		}
 	}
 
 	return result
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/liweiyi88/onedump/config"
+	"github.com/liweiyi88/onedump/dumper"
+	"github.com/liweiyi88/onedump/fileutil"
+	"github.com/liweiyi88/onedump/storage"
+	"github.com/liweiyi88/onedump/verify"
+)
+
+// Keep a copy of the dump in a temp file while the job saves it to the storages,
+// then restore the copy into a scratch database to verify the backup.
+type verification struct {
+	job    *config.Job
+	file   *os.File
+	dumper dumper.StatsDumper
+}
+
+func newVerification(job *config.Job) (*verification, error) {
+	file, err := os.CreateTemp(fileutil.WorkDir(), ".onedump-verify-*")
+	if err != nil {
+		return nil, fmt.Errorf("fail to create temp file for verification, error: %v", err)
+	}
+
+	return &verification{job: job, file: file}, nil
+}
+
+// Capture the table stats at dump time, they are compared with the restored tables.
+func (v *verification) attach(d dumper.Dumper) error {
+	statsDumper, ok := d.(dumper.StatsDumper)
+	if !ok {
+		return fmt.Errorf("%s driver does not support backup verification", v.job.DBDriver)
+	}
+
+	if err := statsDumper.EnableTableStats(!v.job.Verify.SkipChecksum); err != nil {
+		return err
+	}
+
+	v.dumper = statsDumper
+
+	return nil
+}
+
+// Save the dump to the temp file, it is the same content as the other storages receive, e.g. gzipped.
+func (v *verification) Save(reader io.Reader, pathGenerator storage.PathGeneratorFunc) error {
+	if _, err := io.Copy(v.file, reader); err != nil {
+		return fmt.Errorf("fail to write temp file %s, error: %v", v.file.Name(), err)
+	}
+
+	return nil
+}
+
+// Restore the dump and compare it with the source, it returns the number of verified tables.
+func (v *verification) run() (int, error) {
+	if _, err := v.file.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("fail to read temp file %s, error: %v", v.file.Name(), err)
+	}
+
+	sourceConfig, err := mysql.ParseDSN(v.job.DBDsn)
+	if err != nil {
+		return 0, err
+	}
+
+	verifier := verify.NewMysqlVerifier(v.job.Verify.DSN, sourceConfig.DBName, !v.job.Verify.SkipChecksum)
+
+	return verifier.Verify(v.file, v.dumper.TableStats())
+}
+
+func (v *verification) close() {
+	if err := v.file.Close(); err != nil {
+		slog.Error("fail to close temp file", slog.Any("error", err), slog.String("filename", v.file.Name()))
+	}
+
+	if err := os.Remove(v.file.Name()); err != nil {
+		slog.Error("fail to remove temp file", slog.Any("error", err), slog.String("filename", v.file.Name()))
+	}
+}
@@ -6,9 +6,10 @@ import (
 )
 
 type JobResult struct {
-	Error   error
-	JobName string
-	Elapsed time.Duration
+	Error          error
+	JobName        string
+	Elapsed        time.Duration
+	VerifiedTables int // the number of tables verified by restoring the backup, 0 if the job does not verify it
 }
 
 func (result *JobResult) String() string {
@@ -16,6 +17,10 @@ func (result *JobResult) String() string {
 		return fmt.Sprintf("%s failed, it took %s with error: %v", result.JobName, result.Elapsed, result.Error)
 	}
 
+	if result.VerifiedTables > 0 {
+		return fmt.Sprintf("%s succeeded and %d tables were verified, it took %v", result.JobName, result.VerifiedTables, result.Elapsed)
+	}
+
 	return fmt.Sprintf("%s succeeded, it took %v", result.JobName, result.Elapsed)
 }
 
@@ -24,5 +29,9 @@ func (result *JobResult) ToSlackText() string {
 		return fmt.Sprintf(":x: `%s` failed, it took *%s* ```%v```", result.JobName, result.Elapsed, result.Error)
 	}
 
+	if result.VerifiedTables > 0 {
+		return fmt.Sprintf(":white_check_mark: `%s` succeeded and *%d* tables were verified, it took *%v*", result.JobName, result.VerifiedTables, result.Elapsed)
+	}
+
 	return fmt.Sprintf(":white_check_mark: `%s` succeeded, it took *%v*", result.JobName, result.Elapsed)
 }
@@ -39,3 +39,19 @@ func TestToSlackText(t *testing.T) {
 	expect = fmt.Sprintf(":white_check_mark: `%s` succeeded, it took *%v*", jr.JobName, jr.Elapsed)
 	assert.Equal(t, expect, jr.ToSlackText())
 }
+
+func TestVerifiedTables(t *testing.T) {
+	assert := assert.New(t)
+
+	jr := JobResult{
+		JobName:        "verify job",
+		Elapsed:        time.Second,
+		VerifiedTables: 3,
+	}
+
+	assert.Equal("verify job succeeded and 3 tables were verified, it took 1s", jr.String())
+	assert.Equal(":white_check_mark: `verify job` succeeded and *3* tables were verified, it took *1s*", jr.ToSlackText())
+
+	jr.Error = errors.New("table users has 1 rows, expected 2")
+	assert.Equal("verify job failed, it took 1s with error: table users has 1 rows, expected 2", jr.String())
+}
@@ -0,0 +1,107 @@
+package restore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type MysqlRestorer struct {
+	options
+	dsn string
+}
+
+func NewMysqlRestorer(dsn string, opts ...RestoreOption) *MysqlRestorer {
+	return &MysqlRestorer{
+		options: newOptions(opts...),
+		dsn:     dsn,
+	}
+}
+
+// Restore the dump to the database, the dump can be gzipped.
+func (restorer *MysqlRestorer) Restore(reader io.Reader) error {
+	if _, err := mysql.ParseDSN(restorer.dsn); err != nil {
+		return fmt.Errorf("invalid database dsn, error: %v", err)
+	}
+
+	db, err := sql.Open("mysql", restorer.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open database, error: %v", err)
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			slog.Error("failed to close database", slog.Any("error", err))
+		}
+	}()
+
+	ctx := context.Background()
+
+	// Session variables set by the dump (e.g. FOREIGN_KEY_CHECKS, SQL_MODE) must apply to all statements,
+	// so they have to be executed over the same connection.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database, error: %v", err)
+	}
+
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("failed to close database connection", slog.Any("error", err))
+		}
+	}()
+
+	return restorer.execute(ctx, conn, reader)
+}
+
+func (restorer *MysqlRestorer) execute(ctx context.Context, conn execer, reader io.Reader) error {
+	counter := &countingReader{reader: reader}
+
+	dump, err := Decompress(counter)
+	if err != nil {
+		return err
+	}
+
+	splitter := NewSplitter(dump)
+
+	start := time.Now()
+	lastReport := start
+	statements := 0
+
+	for {
+		statement, err := splitter.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read dump after %d statements, error: %v", statements, err)
+		}
+
+		if _, err := conn.ExecContext(ctx, restorer.rewriteQuery(statement.Query)); err != nil {
+			return fmt.Errorf("failed to execute statement at line %d: %s, error: %v", statement.Line, sqlutil.Truncate(statement.Query), err)
+		}
+
+		statements++
+
+		if time.Since(lastReport) >= restorer.progressInterval {
+			restorer.reportProgress(statements, counter.count)
+			lastReport = time.Now()
+		}
+	}
+
+	slog.Info("restore completed", slog.Int("statements", statements), slog.Int64("bytes", counter.count), slog.Duration("duration", time.Since(start)))
+
+	return nil
+}
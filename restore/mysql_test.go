@@ -0,0 +1,82 @@
+package restore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDump = `-- MySQL dump
+/*!40101 SET NAMES utf8mb4 */;
+CREATE TABLE ` + "`users`" + ` (
+  ` + "`id`" + ` int NOT NULL
+);
+INSERT INTO ` + "`users`" + ` VALUES (1),(2);
+`
+
+func expectTestDump(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("/*!40101 SET NAMES utf8mb4 */").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE `users` (\n  `id` int NOT NULL\n)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `users` VALUES (1),(2)").WillReturnResult(sqlmock.NewResult(0, 2))
+}
+
+func TestMysqlRestorerExecute(t *testing.T) {
+	assert := assert.New(t)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.Nil(err)
+	defer db.Close()
+
+	expectTestDump(mock)
+
+	restorer := NewMysqlRestorer("root@tcp(127.0.0.1:3306)/test")
+	assert.Nil(restorer.execute(context.Background(), db, strings.NewReader(testDump)))
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestMysqlRestorerExecuteGzipped(t *testing.T) {
+	assert := assert.New(t)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.Nil(err)
+	defer db.Close()
+
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	_, err = gzipWriter.Write([]byte(testDump))
+	assert.Nil(err)
+	assert.Nil(gzipWriter.Close())
+
+	expectTestDump(mock)
+
+	restorer := NewMysqlRestorer("root@tcp(127.0.0.1:3306)/test", WithSize(int64(buffer.Len())), WithProgressInterval(0))
+	assert.Nil(restorer.execute(context.Background(), db, &buffer))
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestMysqlRestorerExecuteError(t *testing.T) {
+	assert := assert.New(t)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.Nil(err)
+	defer db.Close()
+
+	mock.ExpectExec("/*!40101 SET NAMES utf8mb4 */").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE `users` (\n  `id` int NOT NULL\n)").WillReturnError(errors.New("table exists"))
+
+	restorer := NewMysqlRestorer("root@tcp(127.0.0.1:3306)/test")
+	err = restorer.execute(context.Background(), db, strings.NewReader(testDump))
+	assert.EqualError(err, "failed to execute statement at line 3: CREATE TABLE `users` (\n  `id` int NOT NULL\n), error: table exists")
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestMysqlRestorerInvalidDsn(t *testing.T) {
+	restorer := NewMysqlRestorer("invalid dsn")
+	assert.ErrorContains(t, restorer.Restore(strings.NewReader(testDump)), "invalid database dsn")
+}
@@ -0,0 +1,296 @@
+package restore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	copyFromStdinPattern = regexp.MustCompile(`(?is)^COPY\s.+\sFROM\s+stdin\b`)
+	dollarTagPattern     = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+	// pg_dump writes them to prevent psql from running meta-commands injected in the data, they are not needed here.
+	ignoredMetaCommands = []string{`\restrict`, `\unrestrict`}
+)
+
+// Split a plain SQL dump produced by pg_dump (or the native PostgreSQL dumper) into statements.
+// It understands comments (including nested block comments), quoted identifiers, standard and E” strings,
+// dollar-quoted strings and the data of COPY ... FROM stdin statements.
+type PgSplitter struct {
+	lineReader
+
+	state        splitState
+	escaped      bool
+	extended     bool   // E'' string, backslash escapes are only recognized in these strings
+	dollarTag    string // the tag of the current dollar-quoted string, e.g. $function$
+	commentDepth int
+
+	sb         strings.Builder
+	hasContent bool
+	startLine  int
+
+	copy *copyReader // the data of the last COPY statement
+}
+
+func NewPgSplitter(reader io.Reader) *PgSplitter {
+	return &PgSplitter{lineReader: newLineReader(reader)}
+}
+
+// Return the next statement without the semicolon, it returns io.EOF when there are no more statements.
+// If the statement is a COPY ... FROM stdin, the data has to be read by CopyData before the next statement,
+// otherwise it is skipped.
+func (s *PgSplitter) Next() (*Statement, error) {
+	if s.copy != nil {
+		if _, err := io.Copy(io.Discard, s.copy); err != nil {
+			return nil, err
+		}
+
+		s.copy = nil
+	}
+
+	for {
+		if len(s.pending) == 0 {
+			if s.eof {
+				return s.flush()
+			}
+
+			if err := s.readLine(); err != nil {
+				return nil, err
+			}
+
+			isMetaCommand, err := s.isMetaCommand()
+			if err != nil {
+				return nil, err
+			}
+
+			if isMetaCommand {
+				continue
+			}
+		}
+
+		if statement := s.split(); statement != nil {
+			if statement.Copy {
+				// The data starts from the next line.
+				s.pending = nil
+				s.copy = &copyReader{splitter: s, startLine: statement.Line}
+			}
+
+			return statement, nil
+		}
+	}
+}
+
+// Read the data of the last COPY ... FROM stdin statement in the text format, it ends before the \. line.
+func (s *PgSplitter) CopyData() io.Reader {
+	if s.copy == nil {
+		return bytes.NewReader(nil)
+	}
+
+	return s.copy
+}
+
+// psql meta-commands are only recognized at the beginning of a statement.
+func (s *PgSplitter) isMetaCommand() (bool, error) {
+	if s.state != stateNormal || s.hasContent {
+		return false, nil
+	}
+
+	line := strings.TrimSpace(string(s.pending))
+	if !strings.HasPrefix(line, `\`) {
+		return false, nil
+	}
+
+	command := strings.Fields(line)[0]
+	for _, ignored := range ignoredMetaCommands {
+		if command == ignored {
+			s.pending = nil
+			return true, nil
+		}
+	}
+
+	return false, fmt.Errorf("unsupported psql meta-command at line %d: %s", s.line, command)
+}
+
+func (s *PgSplitter) split() *Statement {
+	line := s.pending
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch s.state {
+		case stateSingleQuote:
+			if s.escaped {
+				s.escaped = false
+			} else if c == '\\' && s.extended {
+				s.escaped = true
+			} else if c == '\'' {
+				// A doubled quote is an escaped quote, it reopens the string on the next byte.
+				s.state = stateNormal
+			}
+		case stateDoubleQuote:
+			if c == '"' {
+				s.state = stateNormal
+			}
+		case stateDollarQuote:
+			if bytes.HasPrefix(line[i:], []byte(s.dollarTag)) {
+				s.sb.WriteString(s.dollarTag)
+				i += len(s.dollarTag) - 1
+				s.state = stateNormal
+				continue
+			}
+		case stateBlockComment:
+			switch {
+			case c == '/' && i+1 < len(line) && line[i+1] == '*':
+				s.commentDepth++
+				i++
+			case c == '*' && i+1 < len(line) && line[i+1] == '/':
+				s.commentDepth--
+				i++
+
+				if s.commentDepth == 0 {
+					s.state = stateNormal
+				}
+			}
+
+			continue
+		case stateNormal:
+			switch {
+			case c == ';':
+				s.pending = line[i+1:]
+
+				if statement := s.statement(); statement != nil {
+					return statement
+				}
+
+				return s.split()
+			case c == '-' && i+1 < len(line) && line[i+1] == '-':
+				if line[len(line)-1] == '\n' {
+					s.sb.WriteByte('\n')
+				}
+
+				s.pending = nil
+				return nil
+			case c == '/' && i+1 < len(line) && line[i+1] == '*':
+				s.state = stateBlockComment
+				s.commentDepth = 1
+				i++
+				continue
+			case c == '\'':
+				s.state = stateSingleQuote
+				s.extended = i > 0 && (line[i-1] == 'E' || line[i-1] == 'e') && (i == 1 || !isIdentifierChar(line[i-2]))
+			case c == '"':
+				s.state = stateDoubleQuote
+			case c == '$' && (i == 0 || !isIdentifierChar(line[i-1])):
+				if tag := dollarTagPattern.Find(line[i:]); tag != nil {
+					s.markContent()
+					s.dollarTag = string(tag)
+					s.state = stateDollarQuote
+					s.sb.Write(tag)
+					i += len(tag) - 1
+					continue
+				}
+			}
+
+			if !isSpace(c) {
+				s.markContent()
+			}
+		}
+
+		s.sb.WriteByte(c)
+	}
+
+	s.pending = nil
+	return nil
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (s *PgSplitter) markContent() {
+	if !s.hasContent {
+		s.hasContent = true
+		s.startLine = s.line
+	}
+}
+
+func (s *PgSplitter) statement() *Statement {
+	defer func() {
+		s.sb.Reset()
+		s.hasContent = false
+		s.startLine = 0
+	}()
+
+	if !s.hasContent {
+		return nil
+	}
+
+	query := strings.TrimSpace(s.sb.String())
+
+	return &Statement{
+		Query: query,
+		Line:  s.startLine,
+		Copy:  copyFromStdinPattern.MatchString(query),
+	}
+}
+
+// The last statement does not require a semicolon.
+func (s *PgSplitter) flush() (*Statement, error) {
+	if s.state != stateNormal && s.state != stateBlockComment && s.hasContent {
+		return nil, errors.New("unexpected end of file, a quoted string or identifier is not closed")
+	}
+
+	if statement := s.statement(); statement != nil {
+		if statement.Copy {
+			return nil, fmt.Errorf("unexpected end of file, no data for the COPY statement at line %d", statement.Line)
+		}
+
+		return statement, nil
+	}
+
+	return nil, io.EOF
+}
+
+// Read the COPY data line by line until the end-of-data marker.
+type copyReader struct {
+	splitter  *PgSplitter
+	startLine int
+	buf       []byte
+	done      bool
+}
+
+func (c *copyReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+
+		s := c.splitter
+		if s.eof {
+			return 0, fmt.Errorf("unexpected end of file, the data of the COPY statement at line %d does not end with \\.", c.startLine)
+		}
+
+		if err := s.readLine(); err != nil {
+			return 0, err
+		}
+
+		line := s.pending
+		s.pending = nil
+
+		if string(bytes.TrimRight(line, "\r\n")) == `\.` {
+			c.done = true
+			continue
+		}
+
+		c.buf = line
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+
+	return n, nil
+}
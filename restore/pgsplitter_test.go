@@ -0,0 +1,120 @@
+package restore
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPgSplitter(t *testing.T) {
+	tests := []struct {
+		name   string
+		dump   string
+		expect []string
+	}{
+		{
+			name:   "comments",
+			dump:   "--\n-- PostgreSQL database dump\n--\nSET statement_timeout = 0;\n/* outer /* nested; */ still comment; */ SELECT 1;--trailing\n",
+			expect: []string{"SET statement_timeout = 0", "SELECT 1"},
+		},
+		{
+			name:   "strings and identifiers",
+			dump:   "INSERT INTO \"semi;colon\" VALUES ('it''s; ok', 'C:\\', E'tab\\';\\t', e'\\\\');\nSELECT 2;",
+			expect: []string{"INSERT INTO \"semi;colon\" VALUES ('it''s; ok', 'C:\\', E'tab\\';\\t', e'\\\\')", "SELECT 2"},
+		},
+		{
+			name: "dollar quotes",
+			dump: "CREATE FUNCTION f() RETURNS trigger\n    LANGUAGE plpgsql\n    AS $_$\nBEGIN\n  RAISE NOTICE 'a;b $x$';\n  RETURN NEW;\nEND;\n$_$;\nSELECT $$;$$, $tag$ $$ ; $tag$;",
+			expect: []string{
+				"CREATE FUNCTION f() RETURNS trigger\n    LANGUAGE plpgsql\n    AS $_$\nBEGIN\n  RAISE NOTICE 'a;b $x$';\n  RETURN NEW;\nEND;\n$_$",
+				"SELECT $$;$$, $tag$ $$ ; $tag$",
+			},
+		},
+		{
+			name:   "ignored meta-commands",
+			dump:   "\\restrict abc\nSELECT 1;\n\\unrestrict abc\n",
+			expect: []string{"SELECT 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			splitter := NewPgSplitter(strings.NewReader(tt.dump))
+			queries := make([]string, 0)
+
+			for {
+				statement, err := splitter.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				assert.Nil(t, err)
+				assert.False(t, statement.Copy)
+				queries = append(queries, statement.Query)
+			}
+
+			assert.Equal(t, tt.expect, queries)
+		})
+	}
+}
+
+func TestPgSplitterCopy(t *testing.T) {
+	assert := assert.New(t)
+
+	dump := "COPY public.users (id, name) FROM stdin;\n1\tjohn; doe\n2\t\\N\n\\.\n\nCOPY public.empty (id) FROM stdin;\n\\.\nCOPY public.skipped (id) FROM stdin;\n3\n\\.\nSELECT 1;\n"
+	splitter := NewPgSplitter(strings.NewReader(dump))
+
+	statement, err := splitter.Next()
+	assert.Nil(err)
+	assert.True(statement.Copy)
+	assert.Equal("COPY public.users (id, name) FROM stdin", statement.Query)
+	assert.Equal(1, statement.Line)
+
+	data, err := io.ReadAll(splitter.CopyData())
+	assert.Nil(err)
+	assert.Equal("1\tjohn; doe\n2\t\\N\n", string(data))
+
+	statement, err = splitter.Next()
+	assert.Nil(err)
+	assert.True(statement.Copy)
+	assert.Equal(6, statement.Line)
+
+	data, err = io.ReadAll(splitter.CopyData())
+	assert.Nil(err)
+	assert.Empty(data)
+
+	// The data is skipped if it is not read.
+	statement, err = splitter.Next()
+	assert.Nil(err)
+	assert.True(statement.Copy)
+
+	statement, err = splitter.Next()
+	assert.Nil(err)
+	assert.False(statement.Copy)
+	assert.Equal("SELECT 1", statement.Query)
+	assert.Equal(11, statement.Line)
+
+	_, err = splitter.Next()
+	assert.ErrorIs(err, io.EOF)
+}
+
+func TestPgSplitterErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	splitter := NewPgSplitter(strings.NewReader("COPY users (id) FROM stdin;\n1\n2\n"))
+	_, err := splitter.Next()
+	assert.Nil(err)
+
+	_, err = io.ReadAll(splitter.CopyData())
+	assert.EqualError(err, "unexpected end of file, the data of the COPY statement at line 1 does not end with \\.")
+
+	splitter = NewPgSplitter(strings.NewReader("SELECT 1;\n\\connect other\n"))
+	_, err = splitter.Next()
+	assert.Nil(err)
+
+	_, err = splitter.Next()
+	assert.EqualError(err, "unsupported psql meta-command at line 2: \\connect")
+}
@@ -0,0 +1,188 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+// The database to connect to when creating the target database.
+const pgMaintenanceDatabase = "postgres"
+
+type pgExecer interface {
+	Exec(ctx context.Context, sql string) error
+	CopyFrom(ctx context.Context, reader io.Reader, sql string) (int64, error)
+}
+
+// Run statements with the simple query protocol, the same as psql.
+type pgConnExecer struct {
+	conn *pgconn.PgConn
+}
+
+func (e pgConnExecer) Exec(ctx context.Context, sql string) error {
+	_, err := e.conn.Exec(ctx, sql).ReadAll()
+	return err
+}
+
+func (e pgConnExecer) CopyFrom(ctx context.Context, reader io.Reader, sql string) (int64, error) {
+	tag, err := e.conn.CopyFrom(ctx, reader, sql)
+	return tag.RowsAffected(), err
+}
+
+// Restore plain SQL dumps without psql, the data of COPY ... FROM stdin statements is sent with the COPY protocol.
+type PgRestorer struct {
+	options
+	dsn string
+}
+
+func NewPgRestorer(dsn string, opts ...RestoreOption) *PgRestorer {
+	return &PgRestorer{
+		options: newOptions(opts...),
+		dsn:     dsn,
+	}
+}
+
+// Restore the dump to the database, the dump can be gzipped.
+func (restorer *PgRestorer) Restore(reader io.Reader) error {
+	config, err := pgconn.ParseConfig(restorer.dsn)
+	if err != nil {
+		return fmt.Errorf("invalid database dsn, error: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if restorer.createDatabase {
+		if err := createPgDatabase(ctx, config); err != nil {
+			return err
+		}
+	}
+
+	conn, err := pgconn.ConnectConfig(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database, error: %v", err)
+	}
+
+	defer func() {
+		if err := conn.Close(ctx); err != nil {
+			slog.Error("failed to close database connection", slog.Any("error", err))
+		}
+	}()
+
+	return restorer.execute(ctx, pgConnExecer{conn}, reader)
+}
+
+func createPgDatabase(ctx context.Context, config *pgconn.Config) error {
+	if config.Database == "" {
+		return errors.New("database name is required in the dsn to create the database")
+	}
+
+	maintenanceConfig := config.Copy()
+	maintenanceConfig.Database = pgMaintenanceDatabase
+
+	conn, err := pgconn.ConnectConfig(ctx, maintenanceConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s database, error: %v", pgMaintenanceDatabase, err)
+	}
+
+	defer func() {
+		if err := conn.Close(ctx); err != nil {
+			slog.Error("failed to close database connection", slog.Any("error", err))
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{config.Database}.Sanitize()).ReadAll(); err != nil {
+		return fmt.Errorf("failed to create database %s, error: %v", config.Database, err)
+	}
+
+	slog.Info("database created", slog.String("database", config.Database))
+
+	return nil
+}
+
+func (restorer *PgRestorer) execute(ctx context.Context, conn pgExecer, reader io.Reader) (err error) {
+	counter := &countingReader{reader: reader}
+
+	dump, err := Decompress(counter)
+	if err != nil {
+		return err
+	}
+
+	if restorer.singleTransaction {
+		if err := conn.Exec(ctx, "BEGIN"); err != nil {
+			return fmt.Errorf("failed to begin transaction, error: %v", err)
+		}
+
+		defer func() {
+			if err != nil {
+				if rollbackErr := conn.Exec(ctx, "ROLLBACK"); rollbackErr != nil {
+					slog.Error("failed to rollback transaction", slog.Any("error", rollbackErr))
+				}
+
+				return
+			}
+
+			if err = conn.Exec(ctx, "COMMIT"); err != nil {
+				err = fmt.Errorf("failed to commit transaction, error: %v", err)
+			}
+		}()
+	}
+
+	// A failing statement aborts the transaction, the rest of statements would fail anyway.
+	onErrorStop := restorer.onErrorStop || restorer.singleTransaction
+
+	splitter := NewPgSplitter(dump)
+
+	start := time.Now()
+	lastReport := start
+	statements, failures := 0, 0
+
+	for {
+		statement, err := splitter.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read dump after %d statements, error: %v", statements, err)
+		}
+
+		if statement.Copy {
+			_, err = conn.CopyFrom(ctx, splitter.CopyData(), restorer.rewriteQuery(statement.Query))
+		} else {
+			err = conn.Exec(ctx, restorer.rewriteQuery(statement.Query))
+		}
+
+		statements++
+
+		if err != nil {
+			err = fmt.Errorf("failed to execute statement at line %d: %s, error: %v", statement.Line, sqlutil.Truncate(statement.Query), err)
+			if onErrorStop {
+				return err
+			}
+
+			failures++
+			slog.Error("statement failed", slog.Any("error", err))
+		}
+
+		if time.Since(lastReport) >= restorer.progressInterval {
+			restorer.reportProgress(statements, counter.count)
+			lastReport = time.Now()
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d statements failed", failures, statements)
+	}
+
+	slog.Info("restore completed", slog.Int("statements", statements), slog.Int64("bytes", counter.count), slog.Duration("duration", time.Since(start)))
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package restore
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Decompress the dump if it is gzipped, the content is detected by the magic number rather than the file extension.
+func Decompress(reader io.Reader) (io.Reader, error) {
+	buf := bufio.NewReader(reader)
+
+	header, err := buf.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read dump header, error: %v", err)
+	}
+
+	if !bytes.Equal(header, gzipMagic) {
+		return buf, nil
+	}
+
+	gzipReader, err := gzip.NewReader(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a gzip reader, error: %v", err)
+	}
+
+	return gzipReader, nil
+}
+
+// Count the bytes read from the dump to report the progress.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+
+	return n, err
+}
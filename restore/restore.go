@@ -0,0 +1,100 @@
+package restore
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+const (
+	DefaultProgressInterval = 5 * time.Second
+)
+
+// Restore a SQL dump to a database, the dump can be gzipped.
+type Restorer interface {
+	Restore(reader io.Reader) error
+}
+
+type options struct {
+	size              int64 // the size of the dump in bytes, it is used to report the progress in percentage
+	progressInterval  time.Duration
+	singleTransaction bool // PostgreSQL only
+	onErrorStop       bool // PostgreSQL only, MySQL always stops at the first error
+	createDatabase    bool // PostgreSQL only
+	rewrite           func(query string) string
+}
+
+func newOptions(opts ...RestoreOption) options {
+	options := options{
+		progressInterval: DefaultProgressInterval,
+		onErrorStop:      true,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+type RestoreOption func(options *options)
+
+func WithSize(size int64) RestoreOption {
+	return func(options *options) {
+		options.size = size
+	}
+}
+
+func WithProgressInterval(interval time.Duration) RestoreOption {
+	return func(options *options) {
+		options.progressInterval = interval
+	}
+}
+
+// Run all statements in a single transaction, nothing is restored if any statement fails.
+func WithSingleTransaction(singleTransaction bool) RestoreOption {
+	return func(options *options) {
+		options.singleTransaction = singleTransaction
+	}
+}
+
+// Stop at the first failing statement, the same as psql with ON_ERROR_STOP.
+// Otherwise the failures are logged and returned after all statements are executed.
+func WithOnErrorStop(onErrorStop bool) RestoreOption {
+	return func(options *options) {
+		options.onErrorStop = onErrorStop
+	}
+}
+
+// Create the database in the DSN before restoring, it fails if the database already exists.
+func WithCreateDatabase(createDatabase bool) RestoreOption {
+	return func(options *options) {
+		options.createDatabase = createDatabase
+	}
+}
+
+// Rewrite statements before executing them, e.g. to restore views into another database.
+func WithRewrite(rewrite func(query string) string) RestoreOption {
+	return func(options *options) {
+		options.rewrite = rewrite
+	}
+}
+
+func (options options) rewriteQuery(query string) string {
+	if options.rewrite == nil {
+		return query
+	}
+
+	return options.rewrite(query)
+}
+
+func (options options) reportProgress(statements int, bytes int64) {
+	attrs := []any{slog.Int("statements", statements), slog.Int64("bytes", bytes)}
+
+	if options.size > 0 {
+		attrs = append(attrs, slog.String("progress", fmt.Sprintf("%.1f%%", float64(bytes)*100/float64(options.size))))
+	}
+
+	slog.Info("restoring", attrs...)
+}
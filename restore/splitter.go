@@ -0,0 +1,265 @@
+package restore
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const DefaultDelimiter = ";"
+
+// Match the sql_mode set by a dump, e.g. SQL_MODE='NO_AUTO_VALUE_ON_ZERO,NO_BACKSLASH_ESCAPES' or SQL_MODE=@OLD_SQL_MODE.
+var sqlModePattern = regexp.MustCompile(`(?i)\bSQL_MODE\s*=\s*('[^']*'|@\w+)`)
+
+type splitState int
+
+const (
+	stateNormal splitState = iota
+	stateSingleQuote
+	stateDoubleQuote
+	stateBacktick
+	stateBlockComment
+	stateDollarQuote // PostgreSQL only
+)
+
+// Read a dump line by line, the splitters consume the pending line byte by byte.
+type lineReader struct {
+	reader  *bufio.Reader
+	line    int
+	pending []byte // the rest of the current line that is not split yet
+	eof     bool
+}
+
+func newLineReader(reader io.Reader) lineReader {
+	return lineReader{reader: bufio.NewReaderSize(reader, 64*1024)}
+}
+
+func (l *lineReader) readLine() error {
+	line, err := l.reader.ReadBytes('\n')
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		l.eof = true
+	}
+
+	l.line++
+	l.pending = line
+
+	return nil
+}
+
+type Statement struct {
+	Query string
+	Line  int  // the line where the statement starts
+	Copy  bool // a PostgreSQL COPY ... FROM stdin statement, the data follows the statement
+}
+
+// Split a SQL dump into statements the same way as the mysql client.
+// It understands the DELIMITER command, comments, quoted strings and identifiers that span multiple lines.
+// Comments are dropped except conditional comments (/*! ... */) and optimizer hints (/*+ ... */).
+type Splitter struct {
+	lineReader
+	delimiter          string
+	noBackslashEscapes bool // backslash is an ordinary character in strings when the dump sets NO_BACKSLASH_ESCAPES
+
+	state       splitState
+	escaped     bool
+	keepComment bool // the block comment is executed by the server
+	sb          strings.Builder
+	hasContent  bool // the statement has something other than whitespaces and comments
+	startLine   int
+}
+
+func NewSplitter(reader io.Reader) *Splitter {
+	return &Splitter{
+		lineReader: newLineReader(reader),
+		delimiter:  DefaultDelimiter,
+	}
+}
+
+// Return the next statement without the delimiter, it returns io.EOF when there are no more statements.
+func (s *Splitter) Next() (*Statement, error) {
+	for {
+		if len(s.pending) == 0 {
+			if s.eof {
+				return s.flush()
+			}
+
+			if err := s.readLine(); err != nil {
+				return nil, err
+			}
+
+			if s.isDelimiterCommand() {
+				continue
+			}
+		}
+
+		if statement := s.split(); statement != nil {
+			return statement, nil
+		}
+	}
+}
+
+// The DELIMITER command is only recognized at the beginning of a statement.
+func (s *Splitter) isDelimiterCommand() bool {
+	if s.state != stateNormal || s.hasContent {
+		return false
+	}
+
+	fields := strings.Fields(string(bytes.TrimSpace(s.pending)))
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "DELIMITER") {
+		return false
+	}
+
+	s.delimiter = fields[1]
+	s.pending = nil
+	s.reset()
+
+	return true
+}
+
+// Consume the pending bytes, return a statement once the delimiter is found.
+func (s *Splitter) split() *Statement {
+	line := s.pending
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch s.state {
+		case stateSingleQuote, stateDoubleQuote, stateBacktick:
+			if s.escaped {
+				s.escaped = false
+			} else if c == '\\' && s.state != stateBacktick && !s.noBackslashEscapes {
+				s.escaped = true
+			} else if (c == '\'' && s.state == stateSingleQuote) || (c == '"' && s.state == stateDoubleQuote) || (c == '`' && s.state == stateBacktick) {
+				s.state = stateNormal
+			}
+		case stateBlockComment:
+			if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+				if s.keepComment {
+					s.sb.WriteString("*/")
+				}
+
+				i++
+				s.state = stateNormal
+				continue
+			}
+
+			if !s.keepComment {
+				continue
+			}
+		case stateNormal:
+			if bytes.HasPrefix(line[i:], []byte(s.delimiter)) {
+				s.pending = line[i+len(s.delimiter):]
+
+				if statement := s.statement(); statement != nil {
+					return statement
+				}
+
+				return s.split()
+			}
+
+			switch {
+			case c == '#' || (c == '-' && isLineComment(line[i:])):
+				if line[len(line)-1] == '\n' {
+					s.sb.WriteByte('\n')
+				}
+
+				s.pending = nil
+				return nil
+			case c == '/' && i+1 < len(line) && line[i+1] == '*':
+				s.state = stateBlockComment
+
+				// Conditional comments and optimizer hints are executed by the server, other comments are dropped.
+				s.keepComment = i+2 < len(line) && (line[i+2] == '!' || line[i+2] == '+')
+				if s.keepComment {
+					s.markContent()
+					s.sb.WriteString("/*")
+				}
+
+				i++
+				continue
+			case c == '\'':
+				s.state = stateSingleQuote
+			case c == '"':
+				s.state = stateDoubleQuote
+			case c == '`':
+				s.state = stateBacktick
+			}
+
+			if !isSpace(c) {
+				s.markContent()
+			}
+		}
+
+		s.sb.WriteByte(c)
+	}
+
+	s.pending = nil
+	return nil
+}
+
+// -- starts a comment only if it is followed by a whitespace or a control character.
+func isLineComment(b []byte) bool {
+	if len(b) < 2 || b[1] != '-' {
+		return false
+	}
+
+	return len(b) == 2 || b[2] <= ' '
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func (s *Splitter) markContent() {
+	if !s.hasContent {
+		s.hasContent = true
+		s.startLine = s.line
+	}
+}
+
+func (s *Splitter) reset() {
+	s.sb.Reset()
+	s.hasContent = false
+	s.startLine = 0
+}
+
+// Build the statement and reset the buffer, comments without any statement are skipped.
+func (s *Splitter) statement() *Statement {
+	defer s.reset()
+
+	if !s.hasContent {
+		return nil
+	}
+
+	statement := &Statement{
+		Query: strings.TrimSpace(s.sb.String()),
+		Line:  s.startLine,
+	}
+
+	if matches := sqlModePattern.FindAllStringSubmatch(statement.Query, -1); len(matches) > 0 {
+		sqlMode := matches[len(matches)-1][1]
+		s.noBackslashEscapes = strings.Contains(strings.ToUpper(sqlMode), "NO_BACKSLASH_ESCAPES")
+	}
+
+	return statement
+}
+
+// The last statement does not require a delimiter, the same as the mysql client.
+func (s *Splitter) flush() (*Statement, error) {
+	if s.state != stateNormal && s.state != stateBlockComment && s.hasContent {
+		return nil, errors.New("unexpected end of file, a quoted string or identifier is not closed")
+	}
+
+	if statement := s.statement(); statement != nil {
+		return statement, nil
+	}
+
+	return nil, io.EOF
+}
@@ -0,0 +1,135 @@
+package restore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func splitAll(t *testing.T, dump string) []*Statement {
+	t.Helper()
+
+	splitter := NewSplitter(strings.NewReader(dump))
+	statements := make([]*Statement, 0)
+
+	for {
+		statement, err := splitter.Next()
+		if errors.Is(err, io.EOF) {
+			return statements
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		statements = append(statements, statement)
+	}
+}
+
+func queries(statements []*Statement) []string {
+	queries := make([]string, 0, len(statements))
+	for _, statement := range statements {
+		queries = append(queries, statement.Query)
+	}
+
+	return queries
+}
+
+func TestSplitter(t *testing.T) {
+	tests := []struct {
+		name   string
+		dump   string
+		expect []string
+	}{
+		{
+			name:   "statements on the same line",
+			dump:   "SELECT 1; SELECT 2;\nSELECT 3",
+			expect: []string{"SELECT 1", "SELECT 2", "SELECT 3"},
+		},
+		{
+			name:   "line comments",
+			dump:   "-- MySQL dump\n# comment; with delimiter\nSELECT 1; -- trailing\nSELECT 2--1;\n--\n",
+			expect: []string{"SELECT 1", "SELECT 2--1"},
+		},
+		{
+			name:   "block and conditional comments",
+			dump:   "/* only a comment; */\n/*!40101 SET NAMES utf8mb4 */;\nSELECT /*+ MAX_EXECUTION_TIME(1) */ 1;",
+			expect: []string{"/*!40101 SET NAMES utf8mb4 */", "SELECT /*+ MAX_EXECUTION_TIME(1) */ 1"},
+		},
+		{
+			name:   "multi-line strings",
+			dump:   "INSERT INTO `t;1` VALUES ('a;\n-- not a comment\nb', \"c\\\";\", 'it''s; \\'ok\\'');\nSELECT 1;",
+			expect: []string{"INSERT INTO `t;1` VALUES ('a;\n-- not a comment\nb', \"c\\\";\", 'it''s; \\'ok\\'')", "SELECT 1"},
+		},
+		{
+			name: "delimiter",
+			dump: "DELIMITER ;;\nCREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN\n  SET NEW.b = 1;\nEND ;;\nDELIMITER ;\nSELECT 1;",
+			expect: []string{
+				"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN\n  SET NEW.b = 1;\nEND",
+				"SELECT 1",
+			},
+		},
+		{
+			name:   "no backslash escapes",
+			dump:   "SET SQL_MODE='NO_BACKSLASH_ESCAPES';\nINSERT INTO t VALUES ('C:\\');\nSET SQL_MODE=@OLD_SQL_MODE;\nINSERT INTO t VALUES ('\\';');",
+			expect: []string{"SET SQL_MODE='NO_BACKSLASH_ESCAPES'", "INSERT INTO t VALUES ('C:\\')", "SET SQL_MODE=@OLD_SQL_MODE", "INSERT INTO t VALUES ('\\';')"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, queries(splitAll(t, tt.dump)))
+		})
+	}
+}
+
+func TestSplitterLineNumber(t *testing.T) {
+	assert := assert.New(t)
+
+	statements := splitAll(t, "-- header\n\nCREATE TABLE t (\n  id int\n);\n\nINSERT INTO t VALUES (1);")
+
+	assert.Len(statements, 2)
+	assert.Equal(3, statements[0].Line)
+	assert.Equal(7, statements[1].Line)
+}
+
+func TestSplitterUnclosedString(t *testing.T) {
+	splitter := NewSplitter(strings.NewReader("SELECT 1;\nINSERT INTO t VALUES ('abc);\n"))
+
+	statement, err := splitter.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT 1", statement.Query)
+
+	_, err = splitter.Next()
+	assert.EqualError(t, err, "unexpected end of file, a quoted string or identifier is not closed")
+}
+
+func TestSplitterMysqlDump(t *testing.T) {
+	assert := assert.New(t)
+
+	file, err := os.Open("../testutils/mysqlrestore/init-db.sql")
+	assert.Nil(err)
+
+	defer file.Close()
+
+	splitter := NewSplitter(file)
+
+	count := 0
+	for {
+		statement, err := splitter.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		assert.Nil(err)
+		assert.NotEmpty(statement.Query)
+		assert.False(strings.HasPrefix(statement.Query, "--"), statement.Query)
+		count++
+	}
+
+	assert.Greater(count, 0)
+}
@@ -0,0 +1,20 @@
+package sqlutil
+
+import "strings"
+
+const maxStatementLength = 200 // the maximum number of characters of a statement in logs and error messages
+
+// Quote a MySQL identifier with backticks.
+func QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// Truncate a long statement for logs and error messages, it is cut on a character boundary.
+func Truncate(statement string) string {
+	runes := []rune(statement)
+	if len(runes) <= maxStatementLength {
+		return statement
+	}
+
+	return string(runes[:maxStatementLength]) + "..."
+}
@@ -0,0 +1,31 @@
+package sqlutil
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("`users`", QuoteIdentifier("users"))
+	assert.Equal("`my``table`", QuoteIdentifier("my`table"))
+}
+
+func TestTruncate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("SELECT 1", Truncate("SELECT 1"))
+
+	long := "INSERT INTO t VALUES " + strings.Repeat("(1),", 100)
+	assert.Equal(long[:maxStatementLength]+"...", Truncate(long))
+
+	// The multi-byte characters are not cut in the middle.
+	multiByte := "INSERT INTO t VALUES ('" + strings.Repeat("数据", 100) + "')"
+	truncated := Truncate(multiByte)
+	assert.True(utf8.ValidString(truncated))
+	assert.Equal(maxStatementLength+3, utf8.RuneCountInString(truncated))
+}
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/liweiyi88/onedump/storage"
@@ -18,6 +19,8 @@ var (
 	uploadSessionEndpoint       = "https://content.dropboxapi.com/2/files/upload_session/start"
 	uploadSessionAppendEndpoint = "https://content.dropboxapi.com/2/files/upload_session/append_v2"
 	uploadSessionFinishEndpoint = "https://content.dropboxapi.com/2/files/upload_session/finish"
+	downloadEndpoint            = "https://content.dropboxapi.com/2/files/download"
+	deleteEndpoint              = "https://api.dropboxapi.com/2/files/delete_v2"
 )
 
 const (
@@ -71,6 +74,14 @@ type uploadSessionAppendParam struct {
 	Cursor Cursor `json:"cursor"`
 }
 
+type downloadParam struct {
+	Path string `json:"path"`
+}
+
+type deleteParam struct {
+	Path string `json:"path"`
+}
+
 type uploadSessionResponse struct {
 	SessionId string `json:"session_id"`
 }
@@ -288,3 +299,96 @@ func (dropbox *Dropbox) sendRequest(client *http.Client, method string, url stri
 
 	return body, err
 }
+
+// Download a file as a stream, the caller should close it.
+func (dropbox *Dropbox) Open(path string) (io.ReadCloser, error) {
+	if path == "" {
+		path = dropbox.Path
+	}
+
+	if dropbox.accessToken == "" || dropbox.hasTokenExpired() {
+		if err := dropbox.getAccessToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest("POST", downloadEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	paramJson, err := json.Marshal(downloadParam{Path: path})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode param into json %v", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+dropbox.accessToken)
+	req.Header.Set("Dropbox-API-Arg", string(paramJson))
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send dropbox request %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		if err := response.Body.Close(); err != nil {
+			slog.Error("fail to close download response body", slog.Any("error", err))
+		}
+
+		return nil, fmt.Errorf("request %s is not successful, get status code: %d, body: %s", downloadEndpoint, response.StatusCode, string(body))
+	}
+
+	return response.Body, nil
+}
+
+// Delete a file from dropbox, it is not an error if the file does not exist.
+func (dropbox *Dropbox) Delete(pathGenerator storage.PathGeneratorFunc) error {
+	path := pathGenerator(dropbox.Path)
+
+	if dropbox.accessToken == "" || dropbox.hasTokenExpired() {
+		if err := dropbox.getAccessToken(); err != nil {
+			return err
+		}
+	}
+
+	paramJson, err := json.Marshal(deleteParam{Path: path})
+	if err != nil {
+		return fmt.Errorf("could not encode param into json %v", err)
+	}
+
+	req, err := http.NewRequest("POST", deleteEndpoint, bytes.NewReader(paramJson))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+dropbox.accessToken)
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send dropbox request %v", err)
+	}
+
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			slog.Error("fail to close delete response body", slog.Any("error", err))
+		}
+	}()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	// Dropbox returns 409 with a path_lookup/not_found error summary if the file does not exist.
+	if response.StatusCode == http.StatusConflict && strings.Contains(string(body), "not_found") {
+		return nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("request %s is not successful, get status code: %d, body: %s", deleteEndpoint, response.StatusCode, string(body))
+	}
+
+	return nil
+}
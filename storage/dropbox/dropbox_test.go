@@ -2,6 +2,7 @@ package dropbox
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -141,3 +142,98 @@ func TestUploadSessionFailure(t *testing.T) {
 	err := dropbox.Save(sr, storage.PathGenerator(true, true))
 	assert.NotNil(t, err)
 }
+
+func TestOpen(t *testing.T) {
+	assert := assert.New(t)
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "{\"access_token\":\"sl.BYBntuwSqTes9FsYOrJ68Hi_UvEDH5cZzqt3QSJ3fvVAz\",\"token_type\":\"bearer\",\"expires_in\":14400}")
+	})
+
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Dropbox-API-Arg") != `{"path":"/backup/dump.sql"}` {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, "path/not_found")
+			return
+		}
+
+		fmt.Fprint(w, "file content")
+	})
+
+	svr := httptest.NewServer(mux)
+	defer svr.Close()
+
+	originOauthTokenEndpoint := oauthTokenEndpoint
+	oauthTokenEndpoint = svr.URL + "/oauth2/token"
+
+	originDownloadEndpoint := downloadEndpoint
+	downloadEndpoint = svr.URL + "/download"
+
+	defer func() {
+		oauthTokenEndpoint = originOauthTokenEndpoint
+		downloadEndpoint = originDownloadEndpoint
+	}()
+
+	dropbox := &Dropbox{Path: "/backup/dump.sql"}
+
+	reader, err := dropbox.Open("")
+	assert.Nil(err)
+
+	content, err := io.ReadAll(reader)
+	assert.Nil(err)
+	assert.Equal("file content", string(content))
+	assert.Nil(reader.Close())
+
+	_, err = dropbox.Open("/backup/unknown.sql")
+	assert.ErrorContains(err, "get status code: 409, body: path/not_found")
+}
+
+func TestDelete(t *testing.T) {
+	assert := assert.New(t)
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "{\"access_token\":\"sl.BYBntuwSqTes9FsYOrJ68Hi_UvEDH5cZzqt3QSJ3fvVAz\",\"token_type\":\"bearer\",\"expires_in\":14400}")
+	})
+
+	mux.HandleFunc("/delete_v2", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		switch string(body) {
+		case `{"path":"/binlogs/mysql-bin.000001"}`:
+			fmt.Fprint(w, "{}")
+		case `{"path":"/binlogs/mysql-bin.000002"}`:
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"error_summary": "path_lookup/not_found/"}`)
+		default:
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"error_summary": "path_lookup/restricted_content/"}`)
+		}
+	})
+
+	svr := httptest.NewServer(mux)
+	defer svr.Close()
+
+	originOauthTokenEndpoint := oauthTokenEndpoint
+	oauthTokenEndpoint = svr.URL + "/oauth2/token"
+
+	originDeleteEndpoint := deleteEndpoint
+	deleteEndpoint = svr.URL + "/delete_v2"
+
+	defer func() {
+		oauthTokenEndpoint = originOauthTokenEndpoint
+		deleteEndpoint = originDeleteEndpoint
+	}()
+
+	dropbox := &Dropbox{Path: "/binlogs"}
+	pathGenerator := func(name string) storage.PathGeneratorFunc {
+		return func(folder string) string {
+			return folder + "/" + name
+		}
+	}
+
+	assert.Nil(dropbox.Delete(pathGenerator("mysql-bin.000001")))
+	assert.Nil(dropbox.Delete(pathGenerator("mysql-bin.000002")))
+	assert.ErrorContains(dropbox.Delete(pathGenerator("mysql-bin.000003")), "get status code: 409")
+}
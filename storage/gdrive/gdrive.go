@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 
 	"golang.org/x/oauth2/google"
 	"golang.org/x/oauth2/jwt"
@@ -22,7 +23,7 @@ type GDrive struct {
 	FolderId   string `yaml:"folderid"`
 }
 
-func (gdrive *GDrive) Save(reader io.Reader, pathGenerator storage.PathGeneratorFunc) error {
+func (gdrive *GDrive) createService() (*drive.Service, error) {
 	conf := &jwt.Config{
 		Email:      gdrive.Email,
 		PrivateKey: []byte(gdrive.PrivateKey),
@@ -36,7 +37,16 @@ func (gdrive *GDrive) Save(reader io.Reader, pathGenerator storage.PathGenerator
 
 	driveClient, err := drive.NewService(context.Background(), option.WithHTTPClient(client))
 	if err != nil {
-		return fmt.Errorf("could not create drive client error: %v", err)
+		return nil, fmt.Errorf("could not create drive client error: %v", err)
+	}
+
+	return driveClient, nil
+}
+
+func (gdrive *GDrive) Save(reader io.Reader, pathGenerator storage.PathGeneratorFunc) error {
+	driveClient, err := gdrive.createService()
+	if err != nil {
+		return err
 	}
 
 	path := pathGenerator(gdrive.FileName)
@@ -57,3 +67,64 @@ func (gdrive *GDrive) Save(reader io.Reader, pathGenerator storage.PathGenerator
 
 	return nil
 }
+
+// Search the files with the name in the folder.
+func (gdrive *GDrive) fileQuery(filename string) string {
+	query := fmt.Sprintf("name = '%s' and trashed = false", strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(filename))
+	if gdrive.FolderId != "" {
+		query += fmt.Sprintf(" and '%s' in parents", gdrive.FolderId)
+	}
+
+	return query
+}
+
+// Open the latest file with the name in the folder, google drive allows files with the same name.
+func (gdrive *GDrive) Open(filename string) (io.ReadCloser, error) {
+	if filename == "" {
+		filename = gdrive.FileName
+	}
+
+	driveClient, err := gdrive.createService()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := driveClient.Files.List().Q(gdrive.fileQuery(filename)).OrderBy("createdTime desc").PageSize(1).Fields("files(id)").Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search file %s in google drive: %v", filename, err)
+	}
+
+	if len(list.Files) == 0 {
+		return nil, fmt.Errorf("file %s is not found in google drive", filename)
+	}
+
+	response, err := driveClient.Files.Get(list.Files[0].Id).Download()
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file %s from google drive: %v", filename, err)
+	}
+
+	return response.Body, nil
+}
+
+// Delete all the files with the name in the folder, it is not an error if the file does not exist.
+func (gdrive *GDrive) Delete(pathGenerator storage.PathGeneratorFunc) error {
+	filename := pathGenerator(gdrive.FileName)
+
+	driveClient, err := gdrive.createService()
+	if err != nil {
+		return err
+	}
+
+	list, err := driveClient.Files.List().Q(gdrive.fileQuery(filename)).Fields("files(id)").Do()
+	if err != nil {
+		return fmt.Errorf("failed to search file %s in google drive: %v", filename, err)
+	}
+
+	for _, file := range list.Files {
+		if err := driveClient.Files.Delete(file.Id).Do(); err != nil {
+			return fmt.Errorf("failed to delete file %s from google drive: %v", filename, err)
+		}
+	}
+
+	return nil
+}
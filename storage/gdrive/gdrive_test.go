@@ -21,3 +21,21 @@ func TestSave(t *testing.T) {
 	err := gdrive.Save(reader, storage.PathGenerator(true, true))
 	assert.NotNil(t, err)
 }
+
+func TestFileQuery(t *testing.T) {
+	gdrive := &GDrive{FolderId: "13GbhhbpBeJmUIzm9lET63nXgWgdh3Tly"}
+	assert.Equal(t, `name = 'it\'s.sql' and trashed = false and '13GbhhbpBeJmUIzm9lET63nXgWgdh3Tly' in parents`, gdrive.fileQuery("it's.sql"))
+}
+
+func TestDelete(t *testing.T) {
+	gdrive := &GDrive{
+		Email:      "myaccount@onedump.iam.gserviceaccount.com",
+		FolderId:   "13GbhhbpBeJmUIzm9lET63nXgWgdh3Tly",
+		PrivateKey: "key",
+	}
+
+	err := gdrive.Delete(func(folder string) string {
+		return "mysql-bin.000001"
+	})
+	assert.NotNil(t, err)
+}
@@ -1,6 +1,7 @@
 package local
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -36,3 +37,27 @@ func (local *Local) Save(reader io.Reader, pathGenerator storage.PathGeneratorFu
 
 	return nil
 }
+
+func (local *Local) Open(filename string) (io.ReadCloser, error) {
+	if filename == "" {
+		filename = local.Path
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local file: %w", err)
+	}
+
+	return file, nil
+}
+
+// Delete the local file, it is not an error if the file does not exist.
+func (local *Local) Delete(pathGenerator storage.PathGeneratorFunc) error {
+	path := pathGenerator(local.Path)
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete local file: %w", err)
+	}
+
+	return nil
+}
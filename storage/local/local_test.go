@@ -1,6 +1,7 @@
 package local
 
 import (
+	"io"
 	"os"
 	"strings"
 	"testing"
@@ -25,3 +26,36 @@ func TestSave(t *testing.T) {
 	assert.Equal(t, expected, string(data))
 	defer os.Remove(filename)
 }
+
+func TestOpen(t *testing.T) {
+	filename := os.TempDir() + "/test-open.sql"
+	assert.Nil(t, os.WriteFile(filename, []byte("hello"), 0644))
+	defer os.Remove(filename)
+
+	local := &Local{Path: filename}
+
+	file, err := local.Open("")
+	assert.Nil(t, err)
+
+	data, err := io.ReadAll(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Nil(t, file.Close())
+
+	_, err = local.Open(os.TempDir() + "/not-exist.sql")
+	assert.NotNil(t, err)
+}
+
+func TestDelete(t *testing.T) {
+	filename := os.TempDir() + "/test-delete.sql"
+	assert.Nil(t, os.WriteFile(filename, []byte("hello"), 0644))
+
+	local := &Local{Path: filename}
+	pathGenerator := func(filename string) string { return filename }
+
+	assert.Nil(t, local.Delete(pathGenerator))
+	assert.NoFileExists(t, filename)
+
+	// It is deleted already.
+	assert.Nil(t, local.Delete(pathGenerator))
+}
@@ -184,3 +184,38 @@ func (s3 *S3) GetContent(ctx context.Context) ([]byte, error) {
 
 	return io.ReadAll(result.Body)
 }
+
+// Open a S3 object as a stream, the caller should close it.
+func (s3 *S3) Open(key string) (io.ReadCloser, error) {
+	if key == "" {
+		key = s3.Key
+	}
+
+	result, err := s3.createClient().GetObject(context.Background(), &s3Client.GetObjectInput{
+		Bucket: aws.String(s3.Bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fail to get S3 object, bucket: %s, key: %s, error: %w", s3.Bucket, key, err)
+	}
+
+	return result.Body, nil
+}
+
+func (s3 *S3) Delete(pathGenerator storage.PathGeneratorFunc) error {
+	key := pathGenerator(s3.Key)
+
+	_, err := s3.createClient().DeleteObject(context.Background(), &s3Client.DeleteObjectInput{
+		Bucket: aws.String(s3.Bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		return fmt.Errorf("fail to delete file from S3 bucket: %s, key: %s, error: %w", s3.Bucket, key, err)
+	}
+
+	slog.Debug("[s3] the file has been deleted from the S3 bucket", slog.Any("bucket", s3.Bucket), slog.Any("key", key))
+
+	return nil
+}
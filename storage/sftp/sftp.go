@@ -240,3 +240,71 @@ func (sf *Sftp) IsPathDir(path string) (bool, error) {
 
 	return destInfo.IsDir(), nil
 }
+
+// A remote file that closes the SFTP session and the SSH connection with it.
+type remoteFile struct {
+	*sftpdialer.File
+	client *sftpdialer.Client
+	conn   io.Closer
+}
+
+func (f *remoteFile) Close() error {
+	return errors.Join(f.File.Close(), f.client.Close(), f.conn.Close())
+}
+
+// Open a remote file via SFTP, the caller should close it.
+func (sf *Sftp) Open(path string) (io.ReadCloser, error) {
+	if path == "" {
+		path = sf.Path
+	}
+
+	conn, err := dialer.NewSsh(sf.SshHost, sf.SshKey, sf.SshUser).CreateSshClient()
+	if err != nil {
+		return nil, fmt.Errorf("[sftp] fail to create ssh connection, error: %v", err)
+	}
+
+	client, err := sftpdialer.NewClient(conn)
+	if err != nil {
+		return nil, errors.Join(err, conn.Close())
+	}
+
+	file, err := client.Open(path)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("[sftp] fail to open remote file %s, via SFTP, error: %v", path, err), client.Close(), conn.Close())
+	}
+
+	return &remoteFile{File: file, client: client, conn: conn}, nil
+}
+
+// Delete a remote file via SFTP, it is not an error if the file does not exist.
+func (sf *Sftp) Delete(pathGenerator storage.PathGeneratorFunc) error {
+	path := pathGenerator(sf.Path)
+
+	conn, err := dialer.NewSsh(sf.SshHost, sf.SshKey, sf.SshUser).CreateSshClient()
+	if err != nil {
+		return fmt.Errorf("[sftp] fail to create ssh connection, error: %v", err)
+	}
+
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("[sftp] fail to close ssh connection", slog.Any("error", err))
+		}
+	}()
+
+	client, err := sftpdialer.NewClient(conn)
+	if err != nil {
+		return fmt.Errorf("[sftp] fail to create sftp client, error: %v", err)
+	}
+
+	defer func() {
+		if err := client.Close(); err != nil {
+			slog.Error("[sftp] fail to close sftp client", slog.Any("error", err))
+		}
+	}()
+
+	if err := client.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("[sftp] fail to delete remote file %s, error: %v", path, err)
+	}
+
+	return nil
+}
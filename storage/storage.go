@@ -12,6 +12,18 @@ type Storage interface {
 	Save(reader io.Reader, pathGenerator PathGeneratorFunc) error
 }
 
+// Read a file back from the storage, e.g. to restore a dump.
+// The filename is the path (or key) in the storage, it falls back to the configured path if it is empty.
+type Opener interface {
+	Open(filename string) (io.ReadCloser, error)
+}
+
+// Delete a file from the storage, e.g. to expire the archived binlog files.
+// The path of the file is generated from the configured path, the same as Save.
+type Deleter interface {
+	Delete(pathGenerator PathGeneratorFunc) error
+}
+
 func PathGenerator(gzip bool, unique bool) PathGeneratorFunc {
 	return func(filename string) string {
 		return fileutil.EnsureFileName(filename, gzip, unique)
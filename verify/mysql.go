@@ -0,0 +1,183 @@
+package verify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/liweiyi88/onedump/dumper"
+	"github.com/liweiyi88/onedump/fileutil"
+	"github.com/liweiyi88/onedump/restore"
+	"github.com/liweiyi88/onedump/sqlutil"
+)
+
+// The scratch database is named with this prefix and a random suffix.
+const scratchDatabasePrefix = "onedump_verify_"
+
+var createViewPattern = regexp.MustCompile(`(?is)^CREATE\s.*?\bVIEW\b`)
+
+// Verify a MySQL backup by restoring it into a scratch database on the verification server,
+// then comparing the row counts and checksums of the restored tables with the ones captured at dump time.
+type MysqlVerifier struct {
+	dsn            string
+	sourceDatabase string
+	checksum       bool
+}
+
+func NewMysqlVerifier(dsn, sourceDatabase string, checksum bool) *MysqlVerifier {
+	return &MysqlVerifier{
+		dsn:            dsn,
+		sourceDatabase: sourceDatabase,
+		checksum:       checksum,
+	}
+}
+
+// Verify the dump and drop the scratch database, it returns the number of verified tables.
+func (v *MysqlVerifier) Verify(reader io.Reader, expected dumper.TableStats) (int, error) {
+	config, err := mysql.ParseDSN(v.dsn)
+	if err != nil {
+		return 0, fmt.Errorf("invalid verify dsn, error: %v", err)
+	}
+
+	database := scratchDatabasePrefix + strings.ToLower(fileutil.GenerateRandomName(8))
+
+	serverConfig := config.Clone()
+	serverConfig.DBName = ""
+
+	db, err := sql.Open("mysql", serverConfig.FormatDSN())
+	if err != nil {
+		return 0, fmt.Errorf("failed to open verification database, error: %v", err)
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			slog.Error("failed to close verification database", slog.Any("error", err))
+		}
+	}()
+
+	if _, err := db.ExecContext(context.Background(), "CREATE DATABASE "+sqlutil.QuoteIdentifier(database)); err != nil {
+		return 0, fmt.Errorf("failed to create scratch database %s, error: %v", database, err)
+	}
+
+	slog.Debug("scratch database created", slog.String("database", database))
+
+	defer func() {
+		if _, err := db.ExecContext(context.Background(), "DROP DATABASE "+sqlutil.QuoteIdentifier(database)); err != nil {
+			slog.Error("failed to drop scratch database", slog.String("database", database), slog.Any("error", err))
+		}
+	}()
+
+	restoreConfig := config.Clone()
+	restoreConfig.DBName = database
+
+	restorer := restore.NewMysqlRestorer(restoreConfig.FormatDSN(), restore.WithRewrite(rewriteViews(v.sourceDatabase, database)))
+	if err := restorer.Restore(reader); err != nil {
+		return 0, fmt.Errorf("failed to restore dump into scratch database, error: %v", err)
+	}
+
+	return v.compare(db, database, expected)
+}
+
+func (v *MysqlVerifier) compare(db *sql.DB, database string, expected dumper.TableStats) (int, error) {
+	restored, err := getTables(db, database)
+	if err != nil {
+		return 0, err
+	}
+
+	var tables []string
+	for table := range expected {
+		if slices.Contains(restored, table) {
+			tables = append(tables, table)
+		}
+	}
+
+	actual, err := dumper.CollectMysqlTableStats(db, database, tables, v.checksum)
+	if err != nil {
+		return 0, err
+	}
+
+	if mismatches := compareTableStats(expected, actual); len(mismatches) > 0 {
+		return 0, fmt.Errorf("the restored database does not match the source: %s", strings.Join(mismatches, "; "))
+	}
+
+	return len(expected), nil
+}
+
+// Get the base tables of the database.
+func getTables(db *sql.DB, database string) ([]string, error) {
+	rows, err := db.QueryContext(context.Background(), "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'", database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query restored tables, error: %v", err)
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", slog.Any("error", err))
+		}
+	}()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("failed to scan restored tables, error: %v", err)
+		}
+
+		tables = append(tables, table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get restored tables, error: %v", err)
+	}
+
+	return tables, nil
+}
+
+// Compare the restored tables with the source, checksums are compared only if both of them are available.
+func compareTableStats(expected, actual dumper.TableStats) []string {
+	var mismatches []string
+
+	for _, table := range slices.Sorted(maps.Keys(expected)) {
+		want := expected[table]
+
+		got, ok := actual[table]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("table %s is missing", table))
+			continue
+		}
+
+		if got.Rows != want.Rows {
+			mismatches = append(mismatches, fmt.Sprintf("table %s has %d rows, expected %d", table, got.Rows, want.Rows))
+			continue
+		}
+
+		if got.Checksum.Valid && want.Checksum.Valid && got.Checksum.Int64 != want.Checksum.Int64 {
+			mismatches = append(mismatches, fmt.Sprintf("table %s has checksum %d, expected %d", table, got.Checksum.Int64, want.Checksum.Int64))
+		}
+	}
+
+	return mismatches
+}
+
+// SHOW CREATE VIEW qualifies the tables with the source database, they have to point to the scratch database,
+// as the source database may not exist on the verification server.
+func rewriteViews(sourceDatabase, database string) func(query string) string {
+	source := sqlutil.QuoteIdentifier(sourceDatabase) + "."
+	target := sqlutil.QuoteIdentifier(database) + "."
+
+	return func(query string) string {
+		if !createViewPattern.MatchString(query) {
+			return query
+		}
+
+		return strings.ReplaceAll(query, source, target)
+	}
+}
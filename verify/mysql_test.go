@@ -0,0 +1,98 @@
+package verify
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liweiyi88/onedump/dumper"
+)
+
+func TestCompareTableStats(t *testing.T) {
+	assert := assert.New(t)
+
+	checksum := func(value int64) sql.NullInt64 {
+		return sql.NullInt64{Int64: value, Valid: true}
+	}
+
+	expected := dumper.TableStats{
+		"users":    {Rows: 2, Checksum: checksum(100)},
+		"orders":   {Rows: 5, Checksum: checksum(200)},
+		"events":   {Rows: 1},
+		"sessions": {Rows: 3},
+		"comments": {Rows: 4, Checksum: checksum(300)},
+	}
+
+	actual := dumper.TableStats{
+		"users":    {Rows: 2, Checksum: checksum(100)},
+		"orders":   {Rows: 4, Checksum: checksum(200)},
+		"events":   {Rows: 1, Checksum: checksum(400)},
+		"comments": {Rows: 4, Checksum: checksum(301)},
+	}
+
+	assert.Equal([]string{
+		"table comments has checksum 301, expected 300",
+		"table orders has 4 rows, expected 5",
+		"table sessions is missing",
+	}, compareTableStats(expected, actual))
+
+	assert.Empty(compareTableStats(expected, expected))
+}
+
+func TestRewriteViews(t *testing.T) {
+	assert := assert.New(t)
+	rewrite := rewriteViews("shop", "onedump_verify_abc")
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{
+			"CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v_users` AS select `shop`.`users`.`id` AS `id` from `shop`.`users`",
+			"CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v_users` AS select `onedump_verify_abc`.`users`.`id` AS `id` from `onedump_verify_abc`.`users`",
+		},
+		{
+			"/*!50001 CREATE VIEW `v` AS select `shop`.`t`.`id` AS `id` from `shop`.`t` */",
+			"/*!50001 CREATE VIEW `v` AS select `shop`.`t`.`id` AS `id` from `shop`.`t` */",
+		},
+		{
+			"INSERT INTO `t` VALUES ('`shop`.`users`')",
+			"INSERT INTO `t` VALUES ('`shop`.`users`')",
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(tt.expected, rewrite(tt.query))
+	}
+}
+
+// The verification test needs a real MySQL server, e.g.
+// ONEDUMP_TEST_MYSQL_DSN="root:secret@tcp(127.0.0.1:3306)/dump_test" go test ./verify -run Server
+func TestMysqlVerifierServer(t *testing.T) {
+	dsn := os.Getenv("ONEDUMP_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("ONEDUMP_TEST_MYSQL_DSN is not set")
+	}
+
+	assert := assert.New(t)
+
+	config, err := mysql.ParseDSN(dsn)
+	assert.Nil(err)
+
+	dump := "CREATE TABLE `users` (`id` INT PRIMARY KEY, `name` VARCHAR(255));\nINSERT INTO `users` VALUES (1,'john'),(2,'jane');\n"
+
+	stats := dumper.TableStats{"users": {Rows: 2}}
+	verifier := NewMysqlVerifier(dsn, config.DBName, false)
+
+	verified, err := verifier.Verify(strings.NewReader(dump), stats)
+	assert.Nil(err)
+	assert.Equal(1, verified)
+
+	stats["users"] = dumper.TableStat{Rows: 3}
+	_, err = verifier.Verify(strings.NewReader(dump), stats)
+	assert.EqualError(err, "the restored database does not match the source: table users has 2 rows, expected 3")
+}